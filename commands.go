@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"goDDD1/models"
+	"goDDD1/services"
+	"log"
+	"os"
+	"strings"
+)
+
+// runCommand 执行命令行子命令，例如：go run . reconcile
+func runCommand(args []string) {
+	switch args[0] {
+	case "reconcile":
+		// 执行一次钱包对账并输出差异数量
+		batchNo, count, err := services.NewReconcileService().RunReconciliation()
+		if err != nil {
+			log.Fatalf("钱包对账失败: %v", err)
+		}
+		fmt.Printf("钱包对账完成，批次号：%s，差异数量：%d\n", batchNo, count)
+	case "migrate-categories":
+		// 将商品的Tag迁移为分类，可重复执行
+		categories, links, err := services.NewCategoryService().MigrateTagCategories()
+		if err != nil {
+			log.Fatalf("迁移商品分类失败: %v", err)
+		}
+		fmt.Printf("商品分类迁移完成，新建分类：%d，新建商品关联：%d\n", categories, links)
+	case "export-stores":
+		// 导出全部商品：export-stores [csv|json] [输出文件]，不指定输出文件时输出到标准输出
+		format := models.StoreFileCSV
+		if len(args) > 1 {
+			format = models.StoreFileFormat(args[1])
+		}
+		data, err := services.NewStoreImportService().Export(format)
+		if err != nil {
+			log.Fatalf("导出商品失败: %v", err)
+		}
+		if len(args) > 2 {
+			if err := os.WriteFile(args[2], data, 0644); err != nil {
+				log.Fatalf("写入文件失败: %v", err)
+			}
+			fmt.Printf("商品导出完成：%s\n", args[2])
+			return
+		}
+		os.Stdout.Write(data)
+	case "import-stores":
+		// 导入商品：import-stores <文件> [--apply]，默认只预览差异，加--apply才写入
+		if len(args) < 2 {
+			log.Fatalf("用法: import-stores <文件.csv|文件.json> [--apply]")
+		}
+		format := models.StoreFileFormatOf(args[1])
+		if format == "" {
+			log.Fatalf("无法识别文件格式，文件扩展名应为.csv或.json")
+		}
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			log.Fatalf("读取文件失败: %v", err)
+		}
+		apply := len(args) > 2 && args[2] == "--apply"
+		// 命令行导入没有操作人，版本历史中记录为0
+		result, err := services.NewStoreImportService().Import(data, format, !apply, 0)
+		if err != nil {
+			log.Fatalf("导入商品失败: %v", err)
+		}
+		for _, row := range result.Rows {
+			switch row.Status {
+			case models.StoreImportInvalid:
+				fmt.Printf("第%d行 %s 校验未通过：%s\n", row.Row, row.Name, strings.Join(row.Errors, "；"))
+			case models.StoreImportUpdated:
+				for _, change := range row.Changes {
+					fmt.Printf("第%d行 %s 修改 %s：%q -> %q\n", row.Row, row.Name, change.Field, change.Old, change.New)
+				}
+			case models.StoreImportCreated:
+				fmt.Printf("第%d行 %s 新建\n", row.Row, row.Name)
+			}
+		}
+		fmt.Printf("新建：%d，修改：%d，未变化：%d，校验未通过：%d\n", result.Created, result.Updated, result.Unchanged, result.Invalid)
+		switch {
+		case result.Applied:
+			fmt.Println("导入完成")
+		case apply:
+			log.Fatalf("存在校验未通过的行，未导入任何数据")
+		default:
+			fmt.Println("预览完成，确认无误后加--apply参数导入")
+		}
+	default:
+		log.Fatalf("未知命令: %s", args[0])
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// AdminConfig 管理员配置
+type AdminConfig struct {
+	UIDs []uint // 拥有管理员权限的用户UID，为空时所有管理员接口都不可用
+}
+
+// GetAdminConfig 从环境变量读取管理员配置
+func GetAdminConfig() *AdminConfig {
+	uids := make([]uint, 0)
+	for _, item := range strings.Split(getEnv("ADMIN_UIDS", ""), ",") {
+		uid, err := strconv.ParseUint(strings.TrimSpace(item), 10, 64)
+		if err == nil && uid > 0 {
+			uids = append(uids, uint(uid))
+		}
+	}
+
+	return &AdminConfig{
+		UIDs: uids,
+	}
+}
+
+// IsAdmin 判断用户是否为管理员
+func (c *AdminConfig) IsAdmin(uid uint) bool {
+	for _, item := range c.UIDs {
+		if item == uid {
+			return true
+		}
+	}
+	return false
+}
@@ -66,6 +66,54 @@ func InitDB() *gorm.DB {
 	return db
 }
 
+// EnsureNonNegativeConstraints 为钱包余额和商品库存添加非负CHECK约束（需要MySQL 8.0.16及以上版本）
+func EnsureNonNegativeConstraints(db *gorm.DB) {
+	constraints := []struct {
+		table string
+		name  string
+		check string
+	}{
+		{"user_wallets", "chk_user_wallets_num_non_negative", "num >= 0"},
+		{"stores", "chk_stores_stock_non_negative", "stock >= 0"},
+	}
+
+	for _, constraint := range constraints {
+		var count int
+		if err := db.Raw("SELECT COUNT(*) FROM information_schema.TABLE_CONSTRAINTS WHERE CONSTRAINT_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = ?",
+			constraint.table, constraint.name).Row().Scan(&count); err != nil {
+			log.Printf("查询约束%s失败: %v", constraint.name, err)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+
+		sql := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s)", constraint.table, constraint.name, constraint.check)
+		if err := db.Exec(sql).Error; err != nil {
+			// 已存在负数数据时约束无法添加，需要先通过对账修正
+			log.Printf("添加约束%s失败: %v", constraint.name, err)
+		}
+	}
+}
+
+// EnsureStoreSearchIndexes 创建商品搜索使用的组合索引，索引已存在时跳过
+func EnsureStoreSearchIndexes(db *gorm.DB) {
+	indexes := []struct {
+		name    string
+		columns []string
+	}{
+		{"idx_stores_search_price", []string{"status", "price", "id"}},
+		{"idx_stores_search_sales", []string{"status", "sales_count", "id"}},
+		{"idx_stores_search_tag_price", []string{"tag", "status", "price", "id"}},
+	}
+
+	for _, index := range indexes {
+		if err := db.Table("stores").AddIndex(index.name, index.columns...).Error; err != nil {
+			log.Printf("添加索引%s失败: %v", index.name, err)
+		}
+	}
+}
+
 // CloseDB 关闭数据库连接
 func CloseDB() {
 	if Database != nil {
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"time"
+)
+
+// FlashSaleConfig 秒杀配置
+type FlashSaleConfig struct {
+	Workers        int           // 写入订单的后台协程数量，0表示不启动
+	ResultTTL      time.Duration // 秒杀结果在Redis中的保留时间
+	SettleDelay    time.Duration // 活动结束后等待队列处理完毕再结算剩余库存的时间
+	SettleInterval time.Duration // 结算任务执行间隔，0表示不启动
+}
+
+// GetFlashSaleConfig 从环境变量读取秒杀配置
+func GetFlashSaleConfig() *FlashSaleConfig {
+	return &FlashSaleConfig{
+		Workers:        getEnvAsInt("FLASH_SALE_WORKERS", 4),
+		ResultTTL:      time.Duration(getEnvAsInt("FLASH_SALE_RESULT_TTL_MINUTES", 60)) * time.Minute,
+		SettleDelay:    time.Duration(getEnvAsInt("FLASH_SALE_SETTLE_DELAY_MINUTES", 10)) * time.Minute,
+		SettleInterval: time.Duration(getEnvAsInt("FLASH_SALE_SETTLE_INTERVAL_MINUTES", 5)) * time.Minute,
+	}
+}
@@ -0,0 +1,15 @@
+package config
+
+// GiftConfig 赠送礼物配置
+type GiftConfig struct {
+	AutoAccept bool // 是否自动接受礼物，关闭时需要接收方手动接受
+	DailyCount int  // 每个玩家每日最多赠送次数，0表示不限
+}
+
+// GetGiftConfig 从环境变量读取赠送礼物配置
+func GetGiftConfig() *GiftConfig {
+	return &GiftConfig{
+		AutoAccept: getEnv("GIFT_AUTO_ACCEPT", "false") == "true",
+		DailyCount: getEnvAsInt("GIFT_DAILY_COUNT", 10),
+	}
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"time"
+)
+
+// JobConfig 后台定时任务配置
+type JobConfig struct {
+	ReconcileInterval      time.Duration // 钱包对账间隔，0表示不启动
+	WalletExpireInterval   time.Duration // 过期货币扣除间隔，0表示不启动
+	RechargeRefundInterval time.Duration // 充值渠道退款重试间隔，0表示不启动
+}
+
+// GetJobConfig 从环境变量读取定时任务配置
+func GetJobConfig() *JobConfig {
+	return &JobConfig{
+		ReconcileInterval:      time.Duration(getEnvAsInt("RECONCILE_INTERVAL_MINUTES", 1440)) * time.Minute,
+		WalletExpireInterval:   time.Duration(getEnvAsInt("WALLET_EXPIRE_INTERVAL_MINUTES", 10)) * time.Minute,
+		RechargeRefundInterval: time.Duration(getEnvAsInt("RECHARGE_REFUND_INTERVAL_MINUTES", 5)) * time.Minute,
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// MediaConfig 商品图片存储配置
+type MediaConfig struct {
+	LocalDir      string        // 本地存储根目录
+	PublicPath    string        // 图片访问路径前缀
+	MaxUploadSize int64         // 单张图片最大字节数
+	MaxPixels     int           // 单张图片最大像素数，防止解码超大图片耗尽内存
+	ThumbnailSize int           // 缩略图最长边像素
+	CacheMaxAge   time.Duration // 图片响应的浏览器缓存时间
+}
+
+// GetMediaConfig 从环境变量读取商品图片存储配置
+func GetMediaConfig() *MediaConfig {
+	return &MediaConfig{
+		LocalDir:      getEnv("MEDIA_LOCAL_DIR", "uploads"),
+		PublicPath:    "/" + strings.Trim(getEnv("MEDIA_PUBLIC_PATH", "/media"), "/"),
+		MaxUploadSize: int64(getEnvAsInt("MEDIA_MAX_UPLOAD_MB", 5)) << 20,
+		MaxPixels:     getEnvAsInt("MEDIA_MAX_PIXELS", 25000000),
+		ThumbnailSize: getEnvAsInt("MEDIA_THUMBNAIL_SIZE", 256),
+		CacheMaxAge:   time.Duration(getEnvAsInt("MEDIA_CACHE_MAX_AGE_DAYS", 30)) * 24 * time.Hour,
+	}
+}
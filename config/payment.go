@@ -0,0 +1,28 @@
+package config
+
+import "errors"
+
+// mockSecretPlaceholder 示例配置中的模拟支付密钥，不能实际使用
+const mockSecretPlaceholder = "mock-secret-change-in-production"
+
+// PaymentConfig 支付配置
+type PaymentConfig struct {
+	MockEnabled bool   // 是否启用本地模拟支付，只用于本地联调
+	MockSecret  string // 模拟支付回调签名密钥
+}
+
+// GetPaymentConfig 从环境变量读取支付配置
+func GetPaymentConfig() *PaymentConfig {
+	return &PaymentConfig{
+		MockEnabled: getEnv("PAYMENT_MOCK_ENABLED", "false") == "true",
+		MockSecret:  getEnv("PAYMENT_MOCK_SECRET", ""),
+	}
+}
+
+// Validate 校验支付配置，启用模拟支付时必须设置自己的签名密钥，否则任何人都可以伪造支付回调
+func (c *PaymentConfig) Validate() error {
+	if c.MockEnabled && (c.MockSecret == "" || c.MockSecret == mockSecretPlaceholder) {
+		return errors.New("启用模拟支付时必须设置PAYMENT_MOCK_SECRET，且不能使用示例密钥")
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package config
+
+// 退款导致经验不足当前等级时的处理策略
+const (
+	RefundLevelKeep      = "keep"      // 保留当前等级，只扣减经验值
+	RefundLevelDowngrade = "downgrade" // 按扣减后的经验值重新计算等级
+)
+
+// RefundConfig 购买退款配置
+type RefundConfig struct {
+	LevelPolicy string // 经验不足当前等级时的处理策略
+}
+
+// GetRefundConfig 从环境变量读取退款配置
+func GetRefundConfig() *RefundConfig {
+	policy := getEnv("REFUND_LEVEL_POLICY", RefundLevelKeep)
+	if policy != RefundLevelDowngrade {
+		policy = RefundLevelKeep
+	}
+	return &RefundConfig{
+		LevelPolicy: policy,
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"time"
+)
+
+// RestockConfig 自动补货配置
+type RestockConfig struct {
+	Interval  time.Duration // 补货任务执行间隔，0表示不启动
+	BatchSize int           // 每次执行最多处理的补货策略数量
+}
+
+// GetRestockConfig 从环境变量读取自动补货配置
+func GetRestockConfig() *RestockConfig {
+	return &RestockConfig{
+		Interval:  time.Duration(getEnvAsInt("RESTOCK_INTERVAL_MINUTES", 1)) * time.Minute,
+		BatchSize: getEnvAsInt("RESTOCK_BATCH_SIZE", 100),
+	}
+}
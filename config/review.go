@@ -0,0 +1,15 @@
+package config
+
+// ReviewConfig 商品评价配置
+type ReviewConfig struct {
+	RequireModeration bool // 新评价和修改后的评价是否需要审核后才展示
+	HideReportCount   int  // 被举报达到该次数时自动隐藏，0表示不自动隐藏
+}
+
+// GetReviewConfig 从环境变量读取商品评价配置
+func GetReviewConfig() *ReviewConfig {
+	return &ReviewConfig{
+		RequireModeration: getEnv("REVIEW_REQUIRE_MODERATION", "false") == "true",
+		HideReportCount:   getEnvAsInt("REVIEW_HIDE_REPORT_COUNT", 5),
+	}
+}
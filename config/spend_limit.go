@@ -0,0 +1,19 @@
+package config
+
+import (
+	"time"
+)
+
+// SpendLimitConfig 消费限额配置
+type SpendLimitConfig struct {
+	CoolingOff      time.Duration // 放宽自我限额的冷静期
+	DefaultTimezone string        // 玩家未设置时区时使用的默认时区
+}
+
+// GetSpendLimitConfig 从环境变量读取消费限额配置
+func GetSpendLimitConfig() *SpendLimitConfig {
+	return &SpendLimitConfig{
+		CoolingOff:      time.Duration(getEnvAsInt("SPEND_LIMIT_COOLING_OFF_HOURS", 72)) * time.Hour,
+		DefaultTimezone: getEnv("SPEND_LIMIT_DEFAULT_TIMEZONE", "Asia/Shanghai"),
+	}
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"time"
+)
+
+// StoreConfig 商城配置
+type StoreConfig struct {
+	CatalogCacheTTL  time.Duration // 商品列表缓存时间，0表示不缓存
+	ScheduleInterval time.Duration // 可售时间检查任务执行间隔，0表示不启动
+	PublishInterval  time.Duration // 定时发布草稿任务执行间隔，0表示不启动
+}
+
+// GetStoreConfig 从环境变量读取商城配置
+func GetStoreConfig() *StoreConfig {
+	return &StoreConfig{
+		CatalogCacheTTL:  time.Duration(getEnvAsInt("STORE_CATALOG_CACHE_SECONDS", 60)) * time.Second,
+		ScheduleInterval: time.Duration(getEnvAsInt("STORE_SCHEDULE_INTERVAL_MINUTES", 1)) * time.Minute,
+		PublishInterval:  time.Duration(getEnvAsInt("STORE_PUBLISH_INTERVAL_MINUTES", 1)) * time.Minute,
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"strings"
+)
+
+// TransferConfig 玩家转账配置
+type TransferConfig struct {
+	Currencies     []string // 允许转账的货币类型
+	MaxPerTransfer int64    // 单笔转账上限
+	DailyLimit     int64    // 每日累计转出上限
+	DailyCount     int      // 每日转出次数上限
+	MinAccountDays int      // 转出方最低注册天数
+	MinLevel       uint     // 转出方最低等级
+	FeePercent     int64    // 手续费百分比，0表示不收取
+	SystemUID      uint     // 收取手续费的系统账户UID
+}
+
+// GetTransferConfig 从环境变量读取转账配置
+func GetTransferConfig() *TransferConfig {
+	currencies := make([]string, 0)
+	for _, currency := range strings.Split(getEnv("TRANSFER_CURRENCIES", "coin"), ",") {
+		if currency = strings.TrimSpace(currency); currency != "" {
+			currencies = append(currencies, currency)
+		}
+	}
+
+	return &TransferConfig{
+		Currencies:     currencies,
+		MaxPerTransfer: int64(getEnvAsInt("TRANSFER_MAX_PER_TRANSFER", 10000)),
+		DailyLimit:     int64(getEnvAsInt("TRANSFER_DAILY_LIMIT", 50000)),
+		DailyCount:     getEnvAsInt("TRANSFER_DAILY_COUNT", 20),
+		MinAccountDays: getEnvAsInt("TRANSFER_MIN_ACCOUNT_DAYS", 7),
+		MinLevel:       uint(getEnvAsInt("TRANSFER_MIN_LEVEL", 3)),
+		FeePercent:     int64(getEnvAsInt("TRANSFER_FEE_PERCENT", 0)),
+		SystemUID:      uint(getEnvAsInt("TRANSFER_SYSTEM_UID", 9999)),
+	}
+}
+
+// IsTradable 判断货币类型是否允许转账
+func (c *TransferConfig) IsTradable(currency string) bool {
+	for _, item := range c.Currencies {
+		if item == currency {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"goDDD1/services"
+	"goDDD1/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CartController 购物车控制器
+type CartController struct {
+	cartService services.CartService
+}
+
+// NewCartController 创建购物车控制器实例
+func NewCartController() *CartController {
+	return &CartController{
+		cartService: services.NewCartService(),
+	}
+}
+
+// cartItemRequest 购物车商品请求
+type cartItemRequest struct {
+	StoreID  uint  `json:"store_id" binding:"required"`
+	Quantity int64 `json:"quantity"`
+}
+
+// GetCart 查看当前登录用户的购物车
+func (c *CartController) GetCart(ctx *gin.Context) {
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	cart, err := c.cartService.GetCart(uid)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"cart": cart,
+	})
+}
+
+// AddItem 添加商品到购物车
+func (c *CartController) AddItem(ctx *gin.Context) {
+	var request cartItemRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if request.Quantity <= 0 {
+		utils.ResClientError(ctx, "quantity必须大于0")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	cart, err := c.cartService.AddItem(uid, request.StoreID, request.Quantity)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "添加成功", gin.H{
+		"cart": cart,
+	})
+}
+
+// UpdateItem 修改购物车中商品的数量，quantity为0时移除
+func (c *CartController) UpdateItem(ctx *gin.Context) {
+	var request cartItemRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if request.Quantity < 0 {
+		utils.ResClientError(ctx, "quantity不能为负数")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	cart, err := c.cartService.UpdateItem(uid, request.StoreID, request.Quantity)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "修改成功", gin.H{
+		"cart": cart,
+	})
+}
+
+// RemoveItem 从购物车移除商品
+func (c *CartController) RemoveItem(ctx *gin.Context) {
+	var request struct {
+		StoreID uint `json:"store_id" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	cart, err := c.cartService.RemoveItem(uid, request.StoreID)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "移除成功", gin.H{
+		"cart": cart,
+	})
+}
+
+// ClearCart 清空购物车
+func (c *CartController) ClearCart(ctx *gin.Context) {
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	if err := c.cartService.ClearCart(uid); err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "清空成功", nil)
+}
+
+// Checkout 结算购物车中的全部商品，生成一个订单，可选使用一张优惠券
+func (c *CartController) Checkout(ctx *gin.Context) {
+	var request struct {
+		CouponID uint `json:"coupon_id"`
+	}
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			utils.ResClientError(ctx, "JSON数据格式错误")
+			return
+		}
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	order, err := c.cartService.Checkout(uid, request.CouponID)
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "结算成功", gin.H{
+		"order": order,
+	})
+}
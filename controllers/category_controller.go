@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"errors"
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CategoryController 商品分类控制器
+type CategoryController struct {
+	categoryService services.CategoryService
+	storeController *StoreController
+}
+
+// NewCategoryController 创建商品分类控制器实例
+func NewCategoryController() *CategoryController {
+	return &CategoryController{
+		categoryService: services.NewCategoryService(),
+		storeController: NewStoreController(),
+	}
+}
+
+// GetTree 获取启用的分类树及每个分类下的商品数量
+func (c *CategoryController) GetTree(ctx *gin.Context) {
+	tree, err := c.categoryService.GetCategoryTree(true)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"categories": tree,
+	})
+}
+
+// GetStores 分页获取分类及其子分类下的商品 ?category_id=1&page=1&page_size=10
+func (c *CategoryController) GetStores(ctx *gin.Context) {
+	categoryID, err := strconv.ParseUint(ctx.Query("category_id"), 10, 32)
+	if err != nil || categoryID == 0 {
+		utils.ResClientError(ctx, "无效的category_id")
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	stores, total, err := c.categoryService.GetCategoryStores(uint(categoryID), page, pageSize)
+	if err != nil {
+		if errors.Is(err, services.ErrCategoryNotFound) {
+			utils.ResClientError(ctx, err.Error())
+			return
+		}
+		utils.ResServerError(ctx, err)
+		return
+	}
+	c.storeController.fillStoreDetails(ctx, stores...)
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"stores":   stores,
+	})
+}
+
+// ListAll 管理员获取包含停用分类的完整分类树
+func (c *CategoryController) ListAll(ctx *gin.Context) {
+	tree, err := c.categoryService.GetCategoryTree(false)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"categories": tree,
+	})
+}
+
+// CreateCategory 管理员创建分类
+func (c *CategoryController) CreateCategory(ctx *gin.Context) {
+	var category models.Category
+	if err := ctx.ShouldBindJSON(&category); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if err := validateCategory(&category); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	if err := c.categoryService.CreateCategory(&category); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "创建成功", gin.H{
+		"category": category,
+	})
+}
+
+// UpdateCategory 管理员更新分类，可修改父分类
+func (c *CategoryController) UpdateCategory(ctx *gin.Context) {
+	var category models.Category
+	if err := ctx.ShouldBindJSON(&category); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if category.ID == 0 {
+		utils.ResClientError(ctx, "id不能为空")
+		return
+	}
+	if err := validateCategory(&category); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	updated, err := c.categoryService.UpdateCategory(&category)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "更新成功", gin.H{
+		"category": updated,
+	})
+}
+
+// DeleteCategory 管理员删除分类
+func (c *CategoryController) DeleteCategory(ctx *gin.Context) {
+	var request struct {
+		ID uint `json:"id" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if err := c.categoryService.DeleteCategory(request.ID); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "删除成功", nil)
+}
+
+// SetStoreCategories 管理员设置商品所属的分类
+func (c *CategoryController) SetStoreCategories(ctx *gin.Context) {
+	var request struct {
+		StoreID     uint   `json:"store_id" binding:"required"`
+		CategoryIDs []uint `json:"category_ids"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if err := c.categoryService.SetStoreCategories(request.StoreID, request.CategoryIDs); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "设置成功", gin.H{
+		"store_id":     request.StoreID,
+		"category_ids": request.CategoryIDs,
+	})
+}
+
+// validateCategory 校验分类名称、编码和状态
+func validateCategory(category *models.Category) error {
+	category.Code = strings.TrimSpace(category.Code)
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Code == "" || category.Name == "" {
+		return errors.New("分类编码和名称不能为空")
+	}
+	if len(category.Code) > 20 || strings.ContainsAny(category.Code, ", ") {
+		return errors.New("分类编码不能超过20个字符且不能包含逗号和空格")
+	}
+	if category.Status != 0 && category.Status != 1 {
+		return errors.New("status必须是0或1")
+	}
+	return nil
+}
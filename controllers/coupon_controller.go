@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CouponController 优惠券控制器
+type CouponController struct {
+	couponService services.CouponService
+}
+
+// NewCouponController 创建优惠券控制器实例
+func NewCouponController() *CouponController {
+	return &CouponController{
+		couponService: services.NewCouponService(),
+	}
+}
+
+// ClaimCoupon 当前登录用户使用兑换码领取优惠券
+func (c *CouponController) ClaimCoupon(ctx *gin.Context) {
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	coupon, err := c.couponService.ClaimCoupon(uid, request.Code)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "领取成功", gin.H{
+		"coupon": coupon,
+	})
+}
+
+// GetMyCoupons 获取当前登录用户的优惠券 ?status=unused&page=1&page_size=10
+func (c *CouponController) GetMyCoupons(ctx *gin.Context) {
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	coupons, total, err := c.couponService.GetUserCoupons(uid, models.UserCouponStatus(ctx.Query("status")), page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"coupons":  coupons,
+	})
+}
+
+// CreateCampaign 管理员创建优惠券活动
+func (c *CouponController) CreateCampaign(ctx *gin.Context) {
+	var campaign models.CouponCampaign
+	if err := ctx.ShouldBindJSON(&campaign); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if err := c.couponService.CreateCampaign(&campaign); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "创建成功", gin.H{
+		"campaign": campaign,
+	})
+}
+
+// ListCampaigns 管理员查询优惠券活动 ?page=1&page_size=10
+func (c *CouponController) ListCampaigns(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	campaigns, total, err := c.couponService.ListCampaigns(page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+		"campaigns": campaigns,
+	})
+}
+
+// GenerateCodes 管理员为优惠券活动生成兑换码
+func (c *CouponController) GenerateCodes(ctx *gin.Context) {
+	var request struct {
+		CampaignID uint   `json:"campaign_id" binding:"required"`
+		Count      int    `json:"count"` // 一次性兑换码的生成数量
+		Code       string `json:"code"`  // 公共兑换码，为空时随机生成
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	codes, err := c.couponService.GenerateCodes(request.CampaignID, request.Count, request.Code)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "生成成功", gin.H{
+		"total": len(codes),
+		"codes": codes,
+	})
+}
+
+// ListCodes 管理员查询优惠券活动的兑换码 ?campaign_id=1&page=1&page_size=10
+func (c *CouponController) ListCodes(ctx *gin.Context) {
+	campaignID, err := strconv.ParseUint(ctx.Query("campaign_id"), 10, 32)
+	if err != nil || campaignID == 0 {
+		utils.ResClientError(ctx, "campaign_id格式错误")
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	codes, total, err := c.couponService.ListCodes(uint(campaignID), page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"codes":    codes,
+	})
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"net"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-sql-driver/mysql"
+)
+
+// serviceErrorCodes 业务错误与响应错误码的对应关系
+var serviceErrorCodes = []struct {
+	err  error
+	code string
+}{
+	{services.ErrInsufficientFunds, utils.CodeInsufficientFunds},
+	{services.ErrInsufficientStock, utils.CodeInsufficientStock},
+	{services.ErrConcurrentModification, utils.CodeConcurrentModification},
+	{services.ErrItemsConsumed, utils.CodeItemsConsumed},
+	{services.ErrSpendLimitExceeded, utils.CodeSpendLimitExceeded},
+	{services.ErrPurchaseLimitReached, utils.CodePurchaseLimitReached},
+	{services.ErrCouponNotApplicable, utils.CodeCouponNotApplicable},
+	{services.ErrLevelRequired, utils.CodeLevelRequired},
+	{services.ErrVipRequired, utils.CodeVipRequired},
+	{services.ErrPrerequisiteRequired, utils.CodePrerequisiteRequired},
+}
+
+// resServiceError 将服务层返回的错误转换为对应错误码的响应。
+// 数据库、缓存等基础设施错误按服务器错误处理，其余未定义的错误是业务校验失败，按客户端错误处理
+func resServiceError(ctx *gin.Context, err error) {
+	for _, item := range serviceErrorCodes {
+		if errors.Is(err, item.err) {
+			utils.ResCodeError(ctx, item.code, err.Error())
+			return
+		}
+	}
+	if isInternalError(err) {
+		utils.ResServerError(ctx, err)
+		return
+	}
+	utils.ResClientError(ctx, err.Error())
+}
+
+// isInternalError 判断错误是否来自数据库、Redis或网络等基础设施
+func isInternalError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	var redisErr redis.Error
+	var netErr net.Error
+	return errors.As(err, &mysqlErr) ||
+		errors.As(err, &redisErr) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, sql.ErrTxDone) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FlashSaleController 秒杀控制器
+type FlashSaleController struct {
+	flashSaleService services.FlashSaleService
+}
+
+// NewFlashSaleController 创建秒杀控制器实例
+func NewFlashSaleController() *FlashSaleController {
+	return &FlashSaleController{
+		flashSaleService: services.NewFlashSaleService(),
+	}
+}
+
+// ListOpenSales 获取进行中和即将开始的秒杀活动
+func (c *FlashSaleController) ListOpenSales(ctx *gin.Context) {
+	sales, err := c.flashSaleService.ListFlashSales(true)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"sales": sales,
+	})
+}
+
+// Purchase 参与秒杀，返回请求号，下单结果通过GetResult轮询
+func (c *FlashSaleController) Purchase(ctx *gin.Context) {
+	var request struct {
+		SaleID   uint  `json:"sale_id" binding:"required"`
+		Quantity int64 `json:"quantity"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+	if request.Quantity == 0 {
+		request.Quantity = 1
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	result, err := c.flashSaleService.Purchase(uid, request.SaleID, request.Quantity)
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "抢购成功，正在生成订单", gin.H{
+		"result": result,
+	})
+}
+
+// GetResult 查询秒杀结果 ?request_no=
+func (c *FlashSaleController) GetResult(ctx *gin.Context) {
+	requestNo := ctx.Query("request_no")
+	if requestNo == "" {
+		utils.ResClientError(ctx, "request_no不能为空")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	result, err := c.flashSaleService.GetResult(uid, requestNo)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"result": result,
+	})
+}
+
+// CreateSale 管理员创建秒杀活动
+func (c *FlashSaleController) CreateSale(ctx *gin.Context) {
+	var sale models.FlashSale
+	if err := ctx.ShouldBindJSON(&sale); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if err := c.flashSaleService.CreateFlashSale(&sale); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "创建成功", gin.H{
+		"sale": sale,
+	})
+}
+
+// CancelSale 管理员取消秒杀活动
+func (c *FlashSaleController) CancelSale(ctx *gin.Context) {
+	var request struct {
+		SaleID uint `json:"sale_id" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if err := c.flashSaleService.CancelFlashSale(request.SaleID); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "取消成功", nil)
+}
+
+// ListSales 管理员查询全部秒杀活动
+func (c *FlashSaleController) ListSales(ctx *gin.Context) {
+	sales, err := c.flashSaleService.ListFlashSales(false)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"sales": sales,
+	})
+}
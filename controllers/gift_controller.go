@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GiftController 礼物控制器
+type GiftController struct {
+	giftService  services.GiftService
+	storeService services.StoreService
+}
+
+// NewGiftController 创建礼物控制器实例
+func NewGiftController() *GiftController {
+	return &GiftController{
+		giftService:  services.NewGiftService(),
+		storeService: services.NewStoreService(),
+	}
+}
+
+// giftHandleRequest 接受或拒绝礼物请求
+type giftHandleRequest struct {
+	GiftNo string `json:"gift_no" binding:"required"`
+}
+
+// BuyGift 当前登录用户购买商品赠送给其他玩家
+func (c *GiftController) BuyGift(ctx *gin.Context) {
+	var request struct {
+		StoreID     uint   `json:"store_id" binding:"required"`
+		Num         uint   `json:"num" binding:"required"`
+		CouponID    uint   `json:"coupon_id"`
+		RecipientID uint   `json:"recipient_id" binding:"required"`
+		Message     string `json:"message"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	order, err := c.storeService.BuyGift(uid, request.StoreID, request.Num, request.CouponID, &services.GiftRequest{
+		RecipientID: request.RecipientID,
+		Message:     request.Message,
+	})
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "赠送成功", gin.H{
+		"order": order,
+		"gift":  order.Gift,
+	})
+}
+
+// GetMyGifts 获取当前登录用户送出或收到的礼物 ?box=received|sent&page=1&page_size=10
+func (c *GiftController) GetMyGifts(ctx *gin.Context) {
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	box := models.GiftBox(ctx.DefaultQuery("box", string(models.GiftBoxReceived)))
+	if box != models.GiftBoxReceived && box != models.GiftBoxSent {
+		utils.ResClientError(ctx, "box必须是received或sent")
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	gifts, total, err := c.giftService.GetUserGifts(uid, box, page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"gifts":    gifts,
+	})
+}
+
+// AcceptGift 接受礼物
+func (c *GiftController) AcceptGift(ctx *gin.Context) {
+	var request giftHandleRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	gift, err := c.giftService.AcceptGift(uid, request.GiftNo)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "已接受礼物", gin.H{
+		"gift": gift,
+	})
+}
+
+// DeclineGift 拒绝礼物
+func (c *GiftController) DeclineGift(ctx *gin.Context) {
+	var request giftHandleRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	gift, err := c.giftService.DeclineGift(uid, request.GiftNo)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "已拒绝礼物", gin.H{
+		"gift": gift,
+	})
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead 上传请求中除图片外的表单数据允许的大小
+const multipartOverhead = 1 << 20
+
+// MediaController 商品图片控制器
+type MediaController struct {
+	mediaService services.MediaService
+}
+
+// NewMediaController 创建商品图片控制器实例
+func NewMediaController() *MediaController {
+	return &MediaController{
+		mediaService: services.NewMediaService(),
+	}
+}
+
+// UploadImage 上传商品图片（multipart表单字段file），同时传store_id时直接设置为该商品的图片
+func (c *MediaController) UploadImage(ctx *gin.Context) {
+	maxSize := config.GetMediaConfig().MaxUploadSize
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize+multipartOverhead)
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.ResClientError(ctx, fmt.Sprintf("图片大小不能超过%dMB", maxSize>>20))
+			return
+		}
+		utils.ResClientError(ctx, "请上传图片文件file")
+		return
+	}
+	if fileHeader.Size > maxSize {
+		utils.ResClientError(ctx, fmt.Sprintf("图片大小不能超过%dMB", maxSize>>20))
+		return
+	}
+
+	var storeID uint
+	if value := ctx.PostForm("store_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || id == 0 {
+			utils.ResClientError(ctx, "store_id格式错误")
+			return
+		}
+		storeID = uint(id)
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	media, err := c.mediaService.UploadImage(uid, data)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	result := gin.H{"media": media}
+	if storeID != 0 {
+		store, err := c.mediaService.SetStoreImage(storeID, media.ID)
+		if err != nil {
+			utils.ResClientError(ctx, err.Error())
+			return
+		}
+		result["store"] = store.ToStoreDTO()
+	}
+
+	utils.ResSuccess(ctx, "上传成功", result)
+}
+
+// SetStoreImage 设置商品图片，media_id为0表示移除图片
+func (c *MediaController) SetStoreImage(ctx *gin.Context) {
+	var request struct {
+		StoreID uint `json:"store_id" binding:"required"`
+		MediaID uint `json:"media_id"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	store, err := c.mediaService.SetStoreImage(request.StoreID, request.MediaID)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "商品图片设置成功", gin.H{
+		"store": store.ToStoreDTO(),
+	})
+}
+
+// Serve 读取图片文件，图片以内容校验和命名不会变化，响应允许浏览器和CDN长期缓存
+func (c *MediaController) Serve(ctx *gin.Context) {
+	key := strings.TrimPrefix(ctx.Param("filepath"), "/")
+
+	object, err := c.mediaService.Open(key)
+	if err != nil {
+		if errors.Is(err, services.ErrMediaNotFound) {
+			ctx.Status(http.StatusNotFound)
+			return
+		}
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	defer object.Body.Close()
+
+	maxAge := int(config.GetMediaConfig().CacheMaxAge.Seconds())
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", maxAge))
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(ctx.Writer, ctx.Request, key, object.ModTime, object.Body)
+}
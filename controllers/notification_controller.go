@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationController 站内通知控制器
+type NotificationController struct {
+	notificationService services.NotificationService
+}
+
+// NewNotificationController 创建站内通知控制器实例
+func NewNotificationController() *NotificationController {
+	return &NotificationController{
+		notificationService: services.NewNotificationService(),
+	}
+}
+
+// GetMyNotifications 获取当前登录用户的通知 ?unread=1&page=1&page_size=10
+func (c *NotificationController) GetMyNotifications(ctx *gin.Context) {
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	notifications, total, unread, err := c.notificationService.GetUserNotifications(uid, ctx.Query("unread") == "1", page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":         total,
+		"page":          page,
+		"pageSize":      pageSize,
+		"unread":        unread,
+		"notifications": notifications,
+	})
+}
+
+// MarkRead 将通知标记为已读，ids为空时标记全部
+func (c *NotificationController) MarkRead(ctx *gin.Context) {
+	var request struct {
+		IDs []uint `json:"ids"`
+	}
+
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			utils.ResClientError(ctx, "JSON数据格式错误")
+			return
+		}
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	count, err := c.notificationService.MarkRead(uid, request.IDs)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "标记成功", gin.H{
+		"count": count,
+	})
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrderController 商城订单控制器
+type OrderController struct {
+	orderService services.OrderService
+}
+
+// NewOrderController 创建商城订单控制器实例
+func NewOrderController() *OrderController {
+	return &OrderController{
+		orderService: services.NewOrderService(),
+	}
+}
+
+// GetMyOrders 分页获取当前登录用户的购买记录
+func (c *OrderController) GetMyOrders(ctx *gin.Context) {
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	orders, total, err := c.orderService.GetUserOrders(uid, page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"orders":   orders,
+	})
+}
+
+// GetMyOrder 获取当前登录用户的订单详情
+func (c *OrderController) GetMyOrder(ctx *gin.Context) {
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	order, err := c.orderService.GetOrderByNo(ctx.Param("order_no"))
+	if err != nil || order.UserID != uid {
+		utils.ResClientError(ctx, "订单不存在")
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"order": order,
+	})
+}
+
+// GetOrder 管理员根据订单号查询订单详情
+func (c *OrderController) GetOrder(ctx *gin.Context) {
+	order, err := c.orderService.GetOrderByNo(ctx.Param("order_no"))
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"order": order,
+	})
+}
+
+// SearchOrders 管理员查询订单 ?order_no=&user_id=&store_id=&status=&start=2006-01-02&end=2006-01-02&page=1&page_size=10
+func (c *OrderController) SearchOrders(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	filter := &services.OrderFilter{
+		OrderNo: ctx.Query("order_no"),
+		Status:  models.OrderStatus(ctx.Query("status")),
+	}
+
+	if userIDStr := ctx.Query("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseUint(userIDStr, 10, 32)
+		if err != nil {
+			utils.ResClientError(ctx, "无效的user_id")
+			return
+		}
+		filter.UserID = uint(userID)
+	}
+
+	if storeIDStr := ctx.Query("store_id"); storeIDStr != "" {
+		storeID, err := strconv.ParseUint(storeIDStr, 10, 32)
+		if err != nil {
+			utils.ResClientError(ctx, "无效的store_id")
+			return
+		}
+		filter.StoreID = uint(storeID)
+	}
+
+	if start := ctx.Query("start"); start != "" {
+		startTime, err := time.ParseInLocation("2006-01-02", start, time.Local)
+		if err != nil {
+			utils.ResClientError(ctx, "start格式应为2006-01-02")
+			return
+		}
+		filter.StartTime = &startTime
+	}
+
+	if end := ctx.Query("end"); end != "" {
+		endTime, err := time.ParseInLocation("2006-01-02", end, time.Local)
+		if err != nil {
+			utils.ResClientError(ctx, "end格式应为2006-01-02")
+			return
+		}
+		// 包含结束日期当天
+		endTime = endTime.AddDate(0, 0, 1)
+		filter.EndTime = &endTime
+	}
+
+	orders, total, err := c.orderService.SearchOrders(filter, page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"orders":   orders,
+	})
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PricingController 价格规则管理控制器
+type PricingController struct {
+	comboDiscountService services.ComboDiscountService
+}
+
+// NewPricingController 创建价格规则管理控制器实例
+func NewPricingController() *PricingController {
+	return &PricingController{
+		comboDiscountService: services.NewComboDiscountService(),
+	}
+}
+
+// CreateCombo 管理员创建组合优惠
+func (c *PricingController) CreateCombo(ctx *gin.Context) {
+	var combo models.ComboDiscount
+	if err := ctx.ShouldBindJSON(&combo); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if err := c.comboDiscountService.CreateCombo(&combo); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "创建成功", gin.H{
+		"combo": combo,
+	})
+}
+
+// UpdateCombo 管理员更新组合优惠
+func (c *PricingController) UpdateCombo(ctx *gin.Context) {
+	var combo models.ComboDiscount
+	if err := ctx.ShouldBindJSON(&combo); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if combo.ID == 0 {
+		utils.ResClientError(ctx, "id不能为空")
+		return
+	}
+
+	if err := c.comboDiscountService.UpdateCombo(&combo); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "更新成功", gin.H{
+		"combo": combo,
+	})
+}
+
+// ListCombos 管理员查询组合优惠 ?active=1只返回启用的
+func (c *PricingController) ListCombos(ctx *gin.Context) {
+	combos, err := c.comboDiscountService.ListCombos(ctx.Query("active") == "1")
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"combos": combos,
+	})
+}
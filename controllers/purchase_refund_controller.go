@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PurchaseRefundController 商城购买退款控制器
+type PurchaseRefundController struct {
+	purchaseRefundService services.PurchaseRefundService
+}
+
+// NewPurchaseRefundController 创建商城购买退款控制器实例
+func NewPurchaseRefundController() *PurchaseRefundController {
+	return &PurchaseRefundController{
+		purchaseRefundService: services.NewPurchaseRefundService(),
+	}
+}
+
+// RefundPurchase 管理员对一笔购买进行退款
+func (c *PurchaseRefundController) RefundPurchase(ctx *gin.Context) {
+	var request struct {
+		FlowID       uint   `json:"flow_id" binding:"required"`
+		Quantity     int64  `json:"quantity"`
+		AllowPartial bool   `json:"allow_partial"`
+		Reason       string `json:"reason" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if request.Quantity < 0 {
+		utils.ResClientError(ctx, "quantity不能为负数")
+		return
+	}
+
+	if len(request.Reason) > 200 {
+		utils.ResClientError(ctx, "退款原因过长")
+		return
+	}
+
+	operatorID, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	refund, err := c.purchaseRefundService.RefundPurchase(&services.PurchaseRefundRequest{
+		FlowID:       request.FlowID,
+		Quantity:     request.Quantity,
+		AllowPartial: request.AllowPartial,
+		Reason:       request.Reason,
+		OperatorID:   operatorID,
+	})
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "退款成功", gin.H{
+		"refund": refund,
+	})
+}
+
+// ListRefunds 查询退款记录 ?user_id=&flow_id=&page=1&page_size=10
+func (c *PurchaseRefundController) ListRefunds(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	userID, _ := strconv.ParseUint(ctx.DefaultQuery("user_id", "0"), 10, 32)
+	flowID, _ := strconv.ParseUint(ctx.DefaultQuery("flow_id", "0"), 10, 32)
+
+	refunds, total, err := c.purchaseRefundService.ListRefunds(uint(userID), uint(flowID), page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"refunds":  refunds,
+	})
+}
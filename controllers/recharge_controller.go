@@ -0,0 +1,289 @@
+package controllers
+
+import (
+	"errors"
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RechargeController 充值控制器
+type RechargeController struct {
+	rechargeService services.RechargeService
+}
+
+// NewRechargeController 创建充值控制器实例
+func NewRechargeController() *RechargeController {
+	return &RechargeController{
+		rechargeService: services.NewRechargeService(),
+	}
+}
+
+// ListProducts 获取上架中的充值商品
+func (c *RechargeController) ListProducts(ctx *gin.Context) {
+	products, err := c.rechargeService.ListProducts(true)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"products": products,
+	})
+}
+
+// CreateOrder 创建充值订单
+func (c *RechargeController) CreateOrder(ctx *gin.Context) {
+	var request struct {
+		ProductID uint   `json:"product_id" binding:"required"`
+		Provider  string `json:"provider" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	order, intent, err := c.rechargeService.CreateOrder(uid, request.ProductID, request.Provider)
+	if err != nil {
+		if errors.Is(err, services.ErrSpendLimitExceeded) {
+			utils.ResCodeError(ctx, utils.CodeSpendLimitExceeded, err.Error())
+			return
+		}
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "创建订单成功", gin.H{
+		"order":   order,
+		"payment": intent,
+	})
+}
+
+// GetMyOrders 获取当前登录用户的充值订单
+func (c *RechargeController) GetMyOrders(ctx *gin.Context) {
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	orders, total, err := c.rechargeService.GetUserOrders(uid, page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"orders":   orders,
+	})
+}
+
+// MockPay 管理员模拟订单支付成功，用于本地联调
+func (c *RechargeController) MockPay(ctx *gin.Context) {
+	var request struct {
+		OrderNo string `json:"order_no" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	order, err := c.rechargeService.MockPay(request.OrderNo)
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "支付成功", gin.H{
+		"order": order,
+	})
+}
+
+// PaymentCallback 支付渠道异步回调，签名校验由对应渠道完成
+func (c *RechargeController) PaymentCallback(ctx *gin.Context) {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		utils.ResClientError(ctx, "读取回调数据失败")
+		return
+	}
+
+	order, err := c.rechargeService.HandleCallback(ctx.Param("provider"), ctx.Request.Header, body)
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "回调处理成功", gin.H{
+		"order_no": order.OrderNo,
+		"status":   order.Status,
+	})
+}
+
+// CreateProduct 管理员创建充值商品
+func (c *RechargeController) CreateProduct(ctx *gin.Context) {
+	var product models.RechargeProduct
+	if err := ctx.ShouldBindJSON(&product); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if product.Name == "" || product.Price <= 0 || product.Diamonds <= 0 {
+		utils.ResClientError(ctx, "商品名称、价格和钻石数量不能为空")
+		return
+	}
+	if product.FirstBonus < 0 {
+		utils.ResClientError(ctx, "首充赠送不能为负数")
+		return
+	}
+
+	product.ID = 0
+	if err := c.rechargeService.CreateProduct(&product); err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "创建成功", gin.H{
+		"product": product,
+	})
+}
+
+// UpdateProduct 管理员更新充值商品
+func (c *RechargeController) UpdateProduct(ctx *gin.Context) {
+	var product models.RechargeProduct
+	if err := ctx.ShouldBindJSON(&product); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if product.ID == 0 {
+		utils.ResClientError(ctx, "id不能为空")
+		return
+	}
+	if product.Name == "" || product.Price <= 0 || product.Diamonds <= 0 {
+		utils.ResClientError(ctx, "商品名称、价格和钻石数量不能为空")
+		return
+	}
+	if product.FirstBonus < 0 {
+		utils.ResClientError(ctx, "首充赠送不能为负数")
+		return
+	}
+
+	if err := c.rechargeService.UpdateProduct(&product); err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "更新成功", gin.H{
+		"product": product,
+	})
+}
+
+// SearchOrders 管理员查询充值订单 ?order_no=&user_id=&status=&provider=&start=2006-01-02&end=2006-01-02&page=1&page_size=10
+func (c *RechargeController) SearchOrders(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	filter := &services.RechargeOrderFilter{
+		OrderNo:  ctx.Query("order_no"),
+		Status:   models.RechargeOrderStatus(ctx.Query("status")),
+		Provider: ctx.Query("provider"),
+	}
+
+	if userIDStr := ctx.Query("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseUint(userIDStr, 10, 32)
+		if err != nil {
+			utils.ResClientError(ctx, "无效的user_id")
+			return
+		}
+		filter.UserID = uint(userID)
+	}
+
+	if start := ctx.Query("start"); start != "" {
+		startTime, err := time.ParseInLocation("2006-01-02", start, time.Local)
+		if err != nil {
+			utils.ResClientError(ctx, "start格式应为2006-01-02")
+			return
+		}
+		filter.StartTime = &startTime
+	}
+
+	if end := ctx.Query("end"); end != "" {
+		endTime, err := time.ParseInLocation("2006-01-02", end, time.Local)
+		if err != nil {
+			utils.ResClientError(ctx, "end格式应为2006-01-02")
+			return
+		}
+		// 包含结束日期当天
+		endTime = endTime.AddDate(0, 0, 1)
+		filter.EndTime = &endTime
+	}
+
+	orders, total, err := c.rechargeService.SearchOrders(filter, page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"orders":   orders,
+	})
+}
+
+// RefundOrder 管理员对充值订单退款
+func (c *RechargeController) RefundOrder(ctx *gin.Context) {
+	var request struct {
+		OrderNo string `json:"order_no" binding:"required"`
+		Reason  string `json:"reason" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	order, err := c.rechargeService.RefundOrder(request.OrderNo, request.Reason)
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "退款成功", gin.H{
+		"order": order,
+	})
+}
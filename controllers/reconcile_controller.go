@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReconcileController 钱包对账控制器
+type ReconcileController struct {
+	reconcileService services.ReconcileService
+}
+
+// NewReconcileController 创建钱包对账控制器实例
+func NewReconcileController() *ReconcileController {
+	return &ReconcileController{
+		reconcileService: services.NewReconcileService(),
+	}
+}
+
+// RunReconciliation 手动触发一次钱包对账
+func (c *ReconcileController) RunReconciliation(ctx *gin.Context) {
+	batchNo, count, err := c.reconcileService.RunReconciliation()
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "对账完成", gin.H{
+		"batch_no":    batchNo,
+		"drift_count": count,
+	})
+}
+
+// ListDrifts 查询对账差异报告 ?status=open&batch_no=&page=1&page_size=10
+func (c *ReconcileController) ListDrifts(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	status := models.DriftStatus(ctx.Query("status"))
+	drifts, total, err := c.reconcileService.ListDrifts(status, ctx.Query("batch_no"), page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"drifts":   drifts,
+	})
+}
+
+// ApplyCorrection 修正对账差异
+func (c *ReconcileController) ApplyCorrection(ctx *gin.Context) {
+	var request struct {
+		DriftID uint                `json:"drift_id" binding:"required"`
+		Mode    models.DriftFixMode `json:"mode" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if request.Mode != models.DriftFixLedger && request.Mode != models.DriftFixWallet {
+		utils.ResClientError(ctx, "mode必须是ledger或wallet")
+		return
+	}
+
+	operatorID, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	drift, err := c.reconcileService.ApplyCorrection(request.DriftID, request.Mode, operatorID)
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "修正成功", gin.H{
+		"drift": drift,
+	})
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RestockController 自动补货控制器
+type RestockController struct {
+	restockService services.RestockService
+}
+
+// NewRestockController 创建自动补货控制器实例
+func NewRestockController() *RestockController {
+	return &RestockController{
+		restockService: services.NewRestockService(),
+	}
+}
+
+// restockStoreRequest 指定商品的请求
+type restockStoreRequest struct {
+	StoreID uint `json:"store_id" binding:"required"`
+}
+
+// SavePolicy 创建或修改商品的补货策略，enabled不传时默认启用
+func (c *RestockController) SavePolicy(ctx *gin.Context) {
+	var request struct {
+		StoreID           uint               `json:"store_id" binding:"required"`
+		Mode              models.RestockMode `json:"mode" binding:"required"`
+		Amount            int64              `json:"amount" binding:"required"`
+		MaxStock          int64              `json:"max_stock"`
+		IntervalMinutes   int                `json:"interval_minutes"`
+		Cron              string             `json:"cron"`
+		LowStockThreshold int64              `json:"low_stock_threshold"`
+		Enabled           *bool              `json:"enabled"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	policy := &models.RestockPolicy{
+		StoreID:           request.StoreID,
+		Mode:              request.Mode,
+		Amount:            request.Amount,
+		MaxStock:          request.MaxStock,
+		IntervalMinutes:   request.IntervalMinutes,
+		Cron:              request.Cron,
+		LowStockThreshold: request.LowStockThreshold,
+		Enabled:           request.Enabled == nil || *request.Enabled,
+	}
+	policy, err := c.restockService.SavePolicy(policy)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "补货策略保存成功", gin.H{
+		"policy": policy,
+	})
+}
+
+// DeletePolicy 删除商品的补货策略
+func (c *RestockController) DeletePolicy(ctx *gin.Context) {
+	var request restockStoreRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if err := c.restockService.DeletePolicy(request.StoreID); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "补货策略删除成功", nil)
+}
+
+// ListPolicies 分页查询补货策略 ?page=1&page_size=10
+func (c *RestockController) ListPolicies(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	policies, total, err := c.restockService.ListPolicies(page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"policies": policies,
+	})
+}
+
+// RestockNow 立即按策略为商品补货
+func (c *RestockController) RestockNow(ctx *gin.Context) {
+	var request restockStoreRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	operatorID, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	restockLog, err := c.restockService.RestockNow(request.StoreID, operatorID)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "补货成功", gin.H{
+		"log": restockLog,
+	})
+}
+
+// ListLogs 分页查询补货记录 ?store_id=&page=1&page_size=10
+func (c *RestockController) ListLogs(ctx *gin.Context) {
+	var storeID uint
+	if value := ctx.Query("store_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			utils.ResClientError(ctx, "store_id格式错误")
+			return
+		}
+		storeID = uint(id)
+	}
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	logs, total, err := c.restockService.ListLogs(storeID, page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"logs":     logs,
+	})
+}
+
+// ListAlerts 分页查询低库存告警 ?open=true&page=1&page_size=10
+func (c *RestockController) ListAlerts(ctx *gin.Context) {
+	onlyOpen := ctx.DefaultQuery("open", "true") == "true"
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	alerts, total, err := c.restockService.ListAlerts(onlyOpen, page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"alerts":   alerts,
+	})
+}
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"errors"
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReviewController 商品评价控制器
+type ReviewController struct {
+	reviewService services.ReviewService
+}
+
+// NewReviewController 创建商品评价控制器实例
+func NewReviewController() *ReviewController {
+	return &ReviewController{
+		reviewService: services.NewReviewService(),
+	}
+}
+
+// ListStoreReviews 分页获取商品评价 ?store_id=1&sort=helpful&page=1&page_size=10
+func (c *ReviewController) ListStoreReviews(ctx *gin.Context) {
+	storeID, err := strconv.ParseUint(ctx.Query("store_id"), 10, 32)
+	if err != nil || storeID == 0 {
+		utils.ResClientError(ctx, "无效的store_id")
+		return
+	}
+
+	sort := models.ReviewSort(ctx.DefaultQuery("sort", string(models.ReviewSortNewest)))
+	if sort != models.ReviewSortNewest && sort != models.ReviewSortHelpful {
+		utils.ResClientError(ctx, "sort必须是newest或helpful")
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	reviews, total, err := c.reviewService.ListStoreReviews(uint(storeID), sort, page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"reviews":  reviews,
+	})
+}
+
+// SubmitReview 发表或修改当前登录用户对商品的评价
+func (c *ReviewController) SubmitReview(ctx *gin.Context) {
+	var request struct {
+		StoreID uint   `json:"store_id" binding:"required"`
+		Rating  int    `json:"rating" binding:"required"`
+		Content string `json:"content"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if request.Rating < 1 || request.Rating > 5 {
+		utils.ResClientError(ctx, "rating必须是1-5")
+		return
+	}
+	if len([]rune(request.Content)) > 1000 {
+		utils.ResClientError(ctx, "评价内容不能超过1000个字")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	review, err := c.reviewService.SubmitReview(uid, request.StoreID, request.Rating, request.Content)
+	if err != nil {
+		if errors.Is(err, services.ErrConcurrentModification) {
+			resServiceError(ctx, err)
+			return
+		}
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "评价成功", gin.H{
+		"review": review,
+	})
+}
+
+// MarkHelpful 标记评价有帮助
+func (c *ReviewController) MarkHelpful(ctx *gin.Context) {
+	var request struct {
+		ReviewID uint `json:"review_id" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	review, err := c.reviewService.MarkHelpful(uid, request.ReviewID)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "标记成功", gin.H{
+		"review": review,
+	})
+}
+
+// ReportReview 举报评价
+func (c *ReviewController) ReportReview(ctx *gin.Context) {
+	var request struct {
+		ReviewID uint   `json:"review_id" binding:"required"`
+		Reason   string `json:"reason" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	if _, err := c.reviewService.ReportReview(uid, request.ReviewID, request.Reason); err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "举报成功", nil)
+}
+
+// ListReviews 管理员查询评价 ?store_id=&status=pending&page=1&page_size=10
+func (c *ReviewController) ListReviews(ctx *gin.Context) {
+	storeID, err := strconv.ParseUint(ctx.DefaultQuery("store_id", "0"), 10, 32)
+	if err != nil {
+		utils.ResClientError(ctx, "无效的store_id")
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	reviews, total, err := c.reviewService.ListReviews(uint(storeID), models.ReviewStatus(ctx.Query("status")), page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"reviews":  reviews,
+	})
+}
+
+// ModerateReview 管理员审核评价
+func (c *ReviewController) ModerateReview(ctx *gin.Context) {
+	var request struct {
+		ReviewID uint                `json:"review_id" binding:"required"`
+		Status   models.ReviewStatus `json:"status" binding:"required"`
+		Note     string              `json:"note"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	review, err := c.reviewService.ModerateReview(request.ReviewID, request.Status, request.Note)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "审核成功", gin.H{
+		"review": review,
+	})
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SpendLimitController 消费限额控制器
+type SpendLimitController struct {
+	spendLimitService services.SpendLimitService
+}
+
+// NewSpendLimitController 创建消费限额控制器实例
+func NewSpendLimitController() *SpendLimitController {
+	return &SpendLimitController{
+		spendLimitService: services.NewSpendLimitService(),
+	}
+}
+
+// GetMyLimits 获取当前登录用户的消费限额及使用情况
+func (c *SpendLimitController) GetMyLimits(ctx *gin.Context) {
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	limits, err := c.spendLimitService.GetUserLimits(uid)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"limits": limits,
+	})
+}
+
+// SetMyLimit 当前登录用户设置自我限额，amount为0表示移除
+func (c *SpendLimitController) SetMyLimit(ctx *gin.Context) {
+	var request struct {
+		Currency string             `json:"currency" binding:"required"`
+		Period   models.SpendPeriod `json:"period" binding:"required"`
+		Amount   int64              `json:"amount"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	limit, err := c.spendLimitService.SetSelfLimit(uid, request.Currency, request.Period, request.Amount)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	message := "设置成功"
+	if limit.PendingAt != nil {
+		message = "限额放宽将在冷静期结束后生效"
+	}
+	utils.ResSuccess(ctx, message, gin.H{
+		"limit": limit,
+	})
+}
+
+// SetLimit 管理员设置全局（user_id为0）或指定玩家的限额，amount为0表示移除
+func (c *SpendLimitController) SetLimit(ctx *gin.Context) {
+	var request struct {
+		UserID   uint               `json:"user_id"`
+		Currency string             `json:"currency" binding:"required"`
+		Period   models.SpendPeriod `json:"period" binding:"required"`
+		Amount   int64              `json:"amount"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	limit, err := c.spendLimitService.SetLimit(request.UserID, request.Currency, request.Period, request.Amount)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "设置成功", gin.H{
+		"limit": limit,
+	})
+}
+
+// ListLimits 管理员查询限额配置 ?user_id=0
+func (c *SpendLimitController) ListLimits(ctx *gin.Context) {
+	userID, err := strconv.ParseUint(ctx.DefaultQuery("user_id", "0"), 10, 32)
+	if err != nil {
+		utils.ResClientError(ctx, "无效的user_id")
+		return
+	}
+
+	limits, err := c.spendLimitService.ListLimits(uint(userID))
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"limits": limits,
+	})
+}
@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"errors"
 	"goDDD1/models"
 	"goDDD1/services"
 	"goDDD1/utils"
+	"log"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
 type StoreController struct {
@@ -29,20 +33,13 @@ func (c *StoreController) CreateStore(ctx *gin.Context) {
 		utils.ResClientError(ctx, "JSON数据格式错误")
 		return
 	}
-	if store.CostType != models.CostTypeCoin && store.CostType != models.CostTypeDiamond {
-		utils.ResClientError(ctx, "CostTpye类型错误")
-		return
-	}
-
-	if store.StoreType != models.StoreTypeGood && store.StoreType != models.StoreTypeGift {
-		utils.ResClientError(ctx, "StoreType类型错误")
-		return
-	}
-	if store.Tag != models.TagNormal && store.Tag != models.TagClothes && store.Tag != models.TagWeapon && store.Tag != models.TagArtifact && store.Tag != models.TagConsumable {
-		utils.ResClientError(ctx, "Tag类型错误")
+	if err := services.ValidateStore(&store, nil); err != nil {
+		utils.ResClientError(ctx, err.Error())
 		return
 	}
 
+	// 销量只由购买累加
+	store.SalesCount = 0
 	if err := c.storeService.CreateStore(&store); err != nil {
 		utils.ResServerError(ctx, err)
 		return
@@ -55,12 +52,31 @@ func (c *StoreController) CreateStore(ctx *gin.Context) {
 func (c *StoreController) UpdateStore(ctx *gin.Context) {
 	// 定义更新请求结构体
 	type UpdateRequest struct {
-		ID       *uint            `json:"id" binding:"required"`
-		Name     *string          `json:"name,omitempty"`
-		Price    *int64           `json:"price,omitempty"`
-		Stock    *int64           `json:"stock,omitempty"`
-		Status   *int             `json:"status,omitempty"`
-		CostType *models.CostType `json:"cost_type,omitempty"`
+		ID   *uint   `json:"id" binding:"required"`
+		Name *string `json:"name,omitempty"`
+		// 商品描述，用于搜索
+		Description *string          `json:"description,omitempty"`
+		Price       *int64           `json:"price,omitempty"`
+		Stock       *int64           `json:"stock,omitempty"`
+		Status      *int             `json:"status,omitempty"`
+		CostType    *models.CostType `json:"cost_type,omitempty"`
+		// 促销价及促销时间，促销价为0表示取消促销
+		SalePrice   *int64     `json:"sale_price,omitempty"`
+		SaleStartAt *time.Time `json:"sale_start_at,omitempty"`
+		SaleEndAt   *time.Time `json:"sale_end_at,omitempty"`
+		// 限购周期和数量，limit_count为0表示不限购
+		LimitPeriod *models.PurchaseLimitPeriod `json:"limit_period,omitempty"`
+		LimitCount  *int64                      `json:"limit_count,omitempty"`
+		// 可售时间段和每周循环售卖配置，循环配置传空字符串表示取消
+		AvailableFrom  *time.Time `json:"available_from,omitempty"`
+		AvailableUntil *time.Time `json:"available_until,omitempty"`
+		ScheduleDays   *string    `json:"schedule_days,omitempty"`
+		ScheduleStart  *string    `json:"schedule_start,omitempty"`
+		ScheduleEnd    *string    `json:"schedule_end,omitempty"`
+		// 购买条件：最低等级、最低VIP等级和前置商品，传0表示取消
+		RequiredLevel    *uint `json:"required_level,omitempty"`
+		RequiredVipLevel *uint `json:"required_vip_level,omitempty"`
+		RequiredStoreID  *uint `json:"required_store_id,omitempty"`
 	}
 
 	var requestData UpdateRequest
@@ -103,36 +119,92 @@ func (c *StoreController) UpdateStore(ctx *gin.Context) {
 		return
 	}
 
-	// 首先查询现有的store
-	storeID := strconv.Itoa(int(*requestData.ID))
-	existingStore, err := c.storeService.GetStoreByID(storeID)
-	if err != nil {
-		utils.ResClientError(ctx, "指定的商店ID不存在")
+	// 验证促销时间
+	if requestData.SaleStartAt != nil && requestData.SaleEndAt != nil && !requestData.SaleEndAt.After(*requestData.SaleStartAt) {
+		utils.ResClientError(ctx, "sale_end_at必须晚于sale_start_at")
 		return
 	}
 
-	// 将UpdateRequest转换为models.Store
-	// 只更新提供的字段，保留其他字段的原值
-	if requestData.Name != nil {
-		existingStore.Name = *requestData.Name
-	}
-	if requestData.Price != nil {
-		existingStore.Price = *requestData.Price
-	}
-	if requestData.Stock != nil {
-		existingStore.Stock = *requestData.Stock
+	operatorID, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
 	}
-	if requestData.Status != nil {
-		existingStore.Status = *requestData.Status
+
+	// 调用服务层更新，在锁定后的最新商品数据上只修改提供的字段，保留其他字段的原值
+	var invalid error
+	updatedStore, err := c.storeService.UpdateStore(*requestData.ID, func(store *models.Store) error {
+		if requestData.Name != nil {
+			store.Name = *requestData.Name
+		}
+		if requestData.Description != nil {
+			store.Description = *requestData.Description
+		}
+		if requestData.Price != nil {
+			store.Price = *requestData.Price
+		}
+		if requestData.Stock != nil {
+			store.Stock = *requestData.Stock
+		}
+		if requestData.Status != nil {
+			store.Status = *requestData.Status
+		}
+		if requestData.CostType != nil {
+			store.CostType = *requestData.CostType
+		}
+		if requestData.SalePrice != nil {
+			store.SalePrice = *requestData.SalePrice
+		}
+		if requestData.SaleStartAt != nil {
+			store.SaleStartAt = requestData.SaleStartAt
+		}
+		if requestData.SaleEndAt != nil {
+			store.SaleEndAt = requestData.SaleEndAt
+		}
+		if requestData.LimitPeriod != nil {
+			store.LimitPeriod = *requestData.LimitPeriod
+		}
+		if requestData.LimitCount != nil {
+			store.LimitCount = *requestData.LimitCount
+		}
+		if requestData.AvailableFrom != nil {
+			store.AvailableFrom = requestData.AvailableFrom
+		}
+		if requestData.AvailableUntil != nil {
+			store.AvailableUntil = requestData.AvailableUntil
+		}
+		if requestData.ScheduleDays != nil {
+			store.ScheduleDays = *requestData.ScheduleDays
+		}
+		if requestData.ScheduleStart != nil {
+			store.ScheduleStart = *requestData.ScheduleStart
+		}
+		if requestData.ScheduleEnd != nil {
+			store.ScheduleEnd = *requestData.ScheduleEnd
+		}
+		if requestData.RequiredLevel != nil {
+			store.RequiredLevel = *requestData.RequiredLevel
+		}
+		if requestData.RequiredVipLevel != nil {
+			store.RequiredVipLevel = *requestData.RequiredVipLevel
+		}
+		if requestData.RequiredStoreID != nil {
+			store.RequiredStoreID = *requestData.RequiredStoreID
+		}
+
+		invalid = validateUpdatedStore(store)
+		return invalid
+	}, operatorID)
+	if invalid != nil {
+		utils.ResClientError(ctx, invalid.Error())
+		return
 	}
-	if requestData.CostType != nil {
-		existingStore.CostType = *requestData.CostType
+	if gorm.IsRecordNotFoundError(err) {
+		utils.ResClientError(ctx, "指定的商店ID不存在")
+		return
 	}
-
-	// 调用服务层更新
-	updatedStore, err := c.storeService.UpdateStore(existingStore)
 	if err != nil {
-		utils.ResServerError(ctx, err)
+		resServiceError(ctx, err)
 		return
 	}
 
@@ -143,6 +215,30 @@ func (c *StoreController) UpdateStore(ctx *gin.Context) {
 	})
 }
 
+// validateUpdatedStore 校验合并修改后的商品配置
+func validateUpdatedStore(store *models.Store) error {
+	// 验证限购配置
+	if err := services.ValidatePurchaseLimit(store.LimitPeriod, store.LimitCount); err != nil {
+		return err
+	}
+
+	// 验证可售时间配置
+	if err := services.ValidateAvailability(store); err != nil {
+		return err
+	}
+
+	// 验证前置商品
+	if err := services.ValidatePrerequisite(store, nil); err != nil {
+		return err
+	}
+
+	// 验证 SalePrice，促销价必须低于原价
+	if store.SalePrice < 0 || (store.SalePrice > 0 && store.SalePrice >= store.Price) {
+		return errors.New("sale_price必须大于等于0且小于price")
+	}
+	return nil
+}
+
 func (c *StoreController) GetStoreByID(ctx *gin.Context) {
 	id := ctx.Query("id")
 	if id == "" {
@@ -156,6 +252,7 @@ func (c *StoreController) GetStoreByID(ctx *gin.Context) {
 	}
 
 	storeDTO := store.ToStoreDTO()
+	c.fillStoreDetails(ctx, storeDTO)
 
 	utils.ResSuccess(ctx, "获取成功", gin.H{
 		"store": storeDTO,
@@ -173,6 +270,7 @@ func (c *StoreController) GetStoreByTag(ctx *gin.Context) {
 		utils.ResServerError(ctx, err)
 		return
 	}
+	c.fillStoreDetails(ctx, stores...)
 	utils.ResSuccess(ctx, "获取成功", gin.H{
 		"stores": stores,
 	})
@@ -199,6 +297,7 @@ func (c *StoreController) GetStoreByTagPage(ctx *gin.Context) {
 		utils.ResServerError(ctx, err)
 		return
 	}
+	c.fillStoreDetails(ctx, stores...)
 	utils.ResSuccess(ctx, "获取成功", gin.H{
 		"stores": stores,
 		"total":  total,
@@ -211,6 +310,7 @@ func (c *StoreController) GetAllStores(ctx *gin.Context) {
 		utils.ResServerError(ctx, err)
 		return
 	}
+	c.fillStoreDetails(ctx, stores...)
 
 	utils.ResSuccess(ctx, "获取商店列表成功", gin.H{
 		"stores": stores,
@@ -218,12 +318,87 @@ func (c *StoreController) GetAllStores(ctx *gin.Context) {
 	})
 }
 
+// SearchStores 搜索商品 ?keyword=&tag=&category_id=&cost_type=&store_type=&min_price=&max_price=&available=1&in_stock=0&sort=newest&cursor=&limit=20
+func (c *StoreController) SearchStores(ctx *gin.Context) {
+	categoryID, err := strconv.ParseUint(ctx.DefaultQuery("category_id", "0"), 10, 32)
+	if err != nil {
+		utils.ResClientError(ctx, "无效的category_id")
+		return
+	}
+
+	query := &models.StoreSearchQuery{
+		Keyword:       ctx.Query("keyword"),
+		Tag:           models.Tag(ctx.Query("tag")),
+		CategoryID:    uint(categoryID),
+		CostType:      models.CostType(ctx.Query("cost_type")),
+		StoreType:     models.StoreType(ctx.Query("store_type")),
+		AvailableOnly: ctx.DefaultQuery("available", "1") == "1",
+		InStockOnly:   ctx.Query("in_stock") == "1",
+		Sort:          models.StoreSortType(ctx.DefaultQuery("sort", string(models.StoreSortNewest))),
+		Cursor:        ctx.Query("cursor"),
+	}
+
+	switch query.Sort {
+	case models.StoreSortNewest, models.StoreSortPriceAsc, models.StoreSortPriceDesc, models.StoreSortPopular:
+	default:
+		utils.ResClientError(ctx, "sort必须是newest、price_asc、price_desc或popular")
+		return
+	}
+
+	for _, param := range []struct {
+		name string
+		dest **int64
+	}{
+		{"min_price", &query.MinPrice},
+		{"max_price", &query.MaxPrice},
+	} {
+		value := ctx.Query(param.name)
+		if value == "" {
+			continue
+		}
+		price, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || price < 0 {
+			utils.ResClientError(ctx, param.name+"必须是大于等于0的整数")
+			return
+		}
+		*param.dest = &price
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		utils.ResClientError(ctx, "min_price不能大于max_price")
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	query.Limit = limit
+
+	result, err := c.storeService.SearchStores(query)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) || errors.Is(err, services.ErrCategoryNotFound) {
+			utils.ResClientError(ctx, err.Error())
+			return
+		}
+		resServiceError(ctx, err)
+		return
+	}
+	c.fillStoreDetails(ctx, result.Stores...)
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"stores":      result.Stores,
+		"next_cursor": result.NextCursor,
+		"has_more":    result.HasMore,
+	})
+}
+
 func (c *StoreController) BuyGoods(ctx *gin.Context) {
 	// 定义购买请求结构体
 	type BuyRequest struct {
-		UserID  uint `json:"user_id" binding:"required"`
 		StoreID uint `json:"store_id" binding:"required"`
 		Num     uint `json:"num" binding:"required"`
+		// 使用的优惠券（玩家优惠券ID），为0表示不使用
+		CouponID uint `json:"coupon_id"`
 	}
 
 	var requestData BuyRequest
@@ -237,11 +412,89 @@ func (c *StoreController) BuyGoods(ctx *gin.Context) {
 		return
 	}
 
-	err := c.storeService.BuyGoods(requestData.UserID, requestData.StoreID, requestData.Num)
+	uid, err := utils.GetCurrentUID(ctx)
 	if err != nil {
-		utils.ResServerError(ctx, err)
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	order, err := c.storeService.BuyGoods(uid, requestData.StoreID, requestData.Num, requestData.CouponID)
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "购买成功", gin.H{
+		"order": order,
+	})
+}
+
+// SetBundleItems 设置礼包内容，整体替换原有内容
+func (c *StoreController) SetBundleItems(ctx *gin.Context) {
+	var request struct {
+		StoreID uint                      `json:"store_id" binding:"required"`
+		Items   []*models.StoreBundleItem `json:"items" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "json数据格式错误")
+		return
+	}
+
+	items, err := c.storeService.SetBundleItems(request.StoreID, request.Items)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "礼包内容设置成功", gin.H{
+		"items": items,
+	})
+}
+
+// Quote 计算购买指定商品的价格明细，与实际购买使用相同的优惠规则
+func (c *StoreController) Quote(ctx *gin.Context) {
+	var request struct {
+		Lines    []services.PurchaseLine `json:"lines" binding:"required"`
+		CouponID uint                    `json:"coupon_id"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "json数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
 		return
 	}
 
-	utils.ResSuccess(ctx, "购买成功", nil)
+	quote, err := c.storeService.Quote(uid, request.Lines, request.CouponID)
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "报价成功", gin.H{
+		"quote": quote,
+	})
+}
+
+// fillStoreDetails 填充礼包内容、当前登录用户限购商品的剩余可购买数量和未满足的购买条件，失败时不影响商品查询
+func (c *StoreController) fillStoreDetails(ctx *gin.Context, stores ...*models.StoreDTO) {
+	if err := c.storeService.FillBundleContents(stores...); err != nil {
+		log.Printf("查询礼包内容失败: %v", err)
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		return
+	}
+	if err := c.storeService.FillRemainingPurchases(uid, stores...); err != nil {
+		log.Printf("查询用户%d限购剩余数量失败: %v", uid, err)
+	}
+	if err := c.storeService.FillLockStatus(uid, stores...); err != nil {
+		log.Printf("查询用户%d商品购买条件失败: %v", uid, err)
+	}
 }
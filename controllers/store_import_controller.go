@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storeImportMaxSize 导入文件的最大字节数
+const storeImportMaxSize = 10 << 20
+
+// StoreImportController 商品批量导入导出控制器
+type StoreImportController struct {
+	storeImportService services.StoreImportService
+}
+
+// NewStoreImportController 创建商品批量导入导出控制器实例
+func NewStoreImportController() *StoreImportController {
+	return &StoreImportController{
+		storeImportService: services.NewStoreImportService(),
+	}
+}
+
+// Export 导出全部商品 ?format=csv|json，默认csv
+func (c *StoreImportController) Export(ctx *gin.Context) {
+	format := models.StoreFileFormat(ctx.DefaultQuery("format", string(models.StoreFileCSV)))
+	if format != models.StoreFileCSV && format != models.StoreFileJSON {
+		utils.ResClientError(ctx, "format必须是csv或json")
+		return
+	}
+
+	data, err := c.storeImportService.Export(format)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == models.StoreFileJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	filename := fmt.Sprintf("stores-%s.%s", time.Now().Format("20060102150405"), format)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, contentType, data)
+}
+
+// Import 导入商品（multipart表单字段file），format不传时按文件扩展名判断；
+// dry_run默认为true，只返回每行的差异和校验结果，传false时在一个事务中写入
+func (c *StoreImportController) Import(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, storeImportMaxSize+multipartOverhead)
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.ResClientError(ctx, fmt.Sprintf("导入文件不能超过%dMB", storeImportMaxSize>>20))
+			return
+		}
+		utils.ResClientError(ctx, "请上传导入文件file")
+		return
+	}
+
+	format := models.StoreFileFormat(ctx.PostForm("format"))
+	if format == "" {
+		format = models.StoreFileFormatOf(fileHeader.Filename)
+	}
+	if format != models.StoreFileCSV && format != models.StoreFileJSON {
+		utils.ResClientError(ctx, "format必须是csv或json")
+		return
+	}
+	dryRun := ctx.DefaultPostForm("dry_run", "true") != "false"
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, storeImportMaxSize+1))
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+	if len(data) > storeImportMaxSize {
+		utils.ResClientError(ctx, fmt.Sprintf("导入文件不能超过%dMB", storeImportMaxSize>>20))
+		return
+	}
+
+	operatorID, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	result, err := c.storeImportService.Import(data, format, dryRun, operatorID)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	message := "导入预览完成"
+	if result.Applied {
+		message = "导入成功"
+	} else if !dryRun {
+		message = "存在校验未通过的行，未导入任何数据"
+	}
+	utils.ResSuccess(ctx, message, gin.H{
+		"result": result,
+	})
+}
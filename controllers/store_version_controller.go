@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StoreVersionController 商品草稿、发布和版本历史控制器
+type StoreVersionController struct {
+	storeVersionService services.StoreVersionService
+}
+
+// NewStoreVersionController 创建商品草稿、发布和版本历史控制器实例
+func NewStoreVersionController() *StoreVersionController {
+	return &StoreVersionController{
+		storeVersionService: services.NewStoreVersionService(),
+	}
+}
+
+// storeVersionIDsRequest 指定一批草稿的请求
+type storeVersionIDsRequest struct {
+	VersionIDs []uint `json:"version_ids" binding:"required"`
+}
+
+// SaveDraft 创建或修改草稿，fields的键与商品导入导出的列名相同，例如{"price": 100, "sale_end_at": null}
+func (c *StoreVersionController) SaveDraft(ctx *gin.Context) {
+	var request struct {
+		ID      uint                   `json:"id"`
+		StoreID uint                   `json:"store_id"`
+		Fields  map[string]interface{} `json:"fields" binding:"required"`
+		Note    string                 `json:"note"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+	if request.ID == 0 && request.StoreID == 0 {
+		utils.ResClientError(ctx, "新建草稿时store_id不能为空")
+		return
+	}
+
+	operatorID, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	version, err := c.storeVersionService.SaveDraft(&services.StoreDraftRequest{
+		ID:      request.ID,
+		StoreID: request.StoreID,
+		Fields:  request.Fields,
+		Note:    request.Note,
+	}, operatorID)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "草稿保存成功", gin.H{
+		"version": version,
+	})
+}
+
+// GetVersion 查询版本详情 ?id=1
+func (c *StoreVersionController) GetVersion(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		utils.ResClientError(ctx, "id格式错误")
+		return
+	}
+
+	version, err := c.storeVersionService.GetVersion(uint(id))
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"version": version,
+	})
+}
+
+// Schedule 设置草稿的定时发布时间，publish_at不传表示取消定时发布
+func (c *StoreVersionController) Schedule(ctx *gin.Context) {
+	var request struct {
+		VersionIDs []uint     `json:"version_ids" binding:"required"`
+		PublishAt  *time.Time `json:"publish_at"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	operatorID, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	versions, err := c.storeVersionService.Schedule(request.VersionIDs, request.PublishAt, operatorID)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	message := "定时发布设置成功"
+	if request.PublishAt == nil {
+		message = "已取消定时发布"
+	}
+	utils.ResSuccess(ctx, message, gin.H{
+		"versions": versions,
+	})
+}
+
+// Publish 立即发布一批草稿
+func (c *StoreVersionController) Publish(ctx *gin.Context) {
+	var request storeVersionIDsRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	operatorID, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	versions, err := c.storeVersionService.Publish(request.VersionIDs, operatorID)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "发布成功", gin.H{
+		"versions": versions,
+	})
+}
+
+// Discard 废弃草稿
+func (c *StoreVersionController) Discard(ctx *gin.Context) {
+	var request struct {
+		ID uint `json:"id" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	version, err := c.storeVersionService.Discard(request.ID)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "草稿已废弃", gin.H{
+		"version": version,
+	})
+}
+
+// Rollback 将商品回滚到指定的已发布版本
+func (c *StoreVersionController) Rollback(ctx *gin.Context) {
+	var request struct {
+		StoreID uint   `json:"store_id" binding:"required"`
+		Number  uint   `json:"number" binding:"required"`
+		Note    string `json:"note"`
+	}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	operatorID, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	version, err := c.storeVersionService.Rollback(request.StoreID, request.Number, request.Note, operatorID)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "回滚成功", gin.H{
+		"version": version,
+	})
+}
+
+// ListVersions 分页查询商品的版本历史和草稿 ?store_id=1&status=published&page=1&page_size=10
+func (c *StoreVersionController) ListVersions(ctx *gin.Context) {
+	storeID, err := strconv.ParseUint(ctx.Query("store_id"), 10, 64)
+	if err != nil || storeID == 0 {
+		utils.ResClientError(ctx, "store_id格式错误")
+		return
+	}
+	status := models.StoreVersionStatus(ctx.Query("status"))
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	versions, total, err := c.storeVersionService.ListVersions(uint(storeID), status, page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+		"versions": versions,
+	})
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TransferController 玩家转账控制器
+type TransferController struct {
+	transferService services.TransferService
+}
+
+// NewTransferController 创建转账控制器实例
+func NewTransferController() *TransferController {
+	return &TransferController{
+		transferService: services.NewTransferService(),
+	}
+}
+
+// Transfer 当前登录用户向其他玩家转账
+func (c *TransferController) Transfer(ctx *gin.Context) {
+	var request struct {
+		ToUserID   uint              `json:"to_user_id" binding:"required"`
+		WalletType models.WalletType `json:"type" binding:"required"`
+		Amount     int64             `json:"amount" binding:"required"`
+		Remark     string            `json:"remark"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if request.WalletType != models.Coin && request.WalletType != models.Diamond {
+		utils.ResClientError(ctx, "无效的钱包类型")
+		return
+	}
+
+	if request.Amount <= 0 {
+		utils.ResClientError(ctx, "amount必须大于0")
+		return
+	}
+
+	if len(request.Remark) > 255 {
+		utils.ResClientError(ctx, "备注过长")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	transfer, err := c.transferService.Transfer(uid, request.ToUserID, request.WalletType, request.Amount, request.Remark)
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "转账成功", gin.H{
+		"transfer": transfer,
+	})
+}
+
+// GetMyTransfers 获取当前登录用户的转账记录
+func (c *TransferController) GetMyTransfers(ctx *gin.Context) {
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize <= 0 {
+		pageSize = 10
+	}
+
+	transfers, total, err := c.transferService.GetUserTransfers(uid, page, pageSize)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+		"transfers": transfers,
+	})
+}
+
+// ReverseTransfer 管理员撤销转账
+func (c *TransferController) ReverseTransfer(ctx *gin.Context) {
+	var request struct {
+		TransferID uint   `json:"transfer_id" binding:"required"`
+		Reason     string `json:"reason" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	operatorID, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	transfer, err := c.transferService.ReverseTransfer(request.TransferID, operatorID, request.Reason)
+	if err != nil {
+		resServiceError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "撤销转账成功", gin.H{
+		"transfer": transfer,
+	})
+}
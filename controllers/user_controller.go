@@ -5,6 +5,7 @@ import (
 	"goDDD1/services"
 	"goDDD1/utils"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -127,6 +128,7 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 		Email     string `json:"email,omitempty"`
 		Password  string `json:"password,omitempty"`
 		IsDeleted string `json:"is_deleted,omitempty"`
+		Timezone  string `json:"timezone,omitempty"`
 	}
 
 	if err := ctx.ShouldBindJSON(&requestData); err != nil {
@@ -157,6 +159,13 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 	if requestData.Password != "" {
 		user.Password = requestData.Password
 	}
+	if requestData.Timezone != "" {
+		if _, err := time.LoadLocation(requestData.Timezone); err != nil {
+			utils.ResClientError(ctx, "无效的时区: "+requestData.Timezone)
+			return
+		}
+		user.Timezone = requestData.Timezone
+	}
 	if requestData.IsDeleted != "" {
 		user.IsDeleted = requestData.IsDeleted
 	} else {
@@ -172,3 +181,30 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 	// 6. 成功响应：返回更新后的用户信息
 	utils.ResSuccess(ctx, "更新用户成功", user)
 }
+
+// SetVip 管理员设置用户VIP等级，expire_at为空表示永久有效，vip_level为0表示取消VIP
+func (c *UserController) SetVip(ctx *gin.Context) {
+	var request struct {
+		UID      uint       `json:"uid" binding:"required"`
+		VipLevel uint       `json:"vip_level"`
+		ExpireAt *time.Time `json:"expire_at"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	if request.ExpireAt != nil && !request.ExpireAt.After(time.Now()) {
+		utils.ResClientError(ctx, "expire_at必须晚于当前时间")
+		return
+	}
+
+	user, err := c.userService.SetVip(request.UID, request.VipLevel, request.ExpireAt)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "VIP设置成功", user)
+}
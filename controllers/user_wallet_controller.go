@@ -98,7 +98,7 @@ func (c *UserWalletController) UpdateWalletBalance(ctx *gin.Context) {
 	}
 
 	if err := c.walletService.UpdateWalletBalance(request.UserID, request.WalletType, request.Amount); err != nil {
-		utils.ResServerError(ctx, err)
+		resServiceError(ctx, err)
 		return
 	}
 
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"goDDD1/services"
+	"goDDD1/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WishlistController 收藏控制器
+type WishlistController struct {
+	wishlistService services.WishlistService
+}
+
+// NewWishlistController 创建收藏控制器实例
+func NewWishlistController() *WishlistController {
+	return &WishlistController{
+		wishlistService: services.NewWishlistService(),
+	}
+}
+
+// wishlistItemRequest 收藏请求
+type wishlistItemRequest struct {
+	StoreID uint `json:"store_id" binding:"required"`
+}
+
+// GetWishlist 获取当前登录用户的收藏列表
+func (c *WishlistController) GetWishlist(ctx *gin.Context) {
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	items, err := c.wishlistService.GetWishlist(uid)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "查询成功", gin.H{
+		"items": items,
+	})
+}
+
+// AddItem 收藏商品
+func (c *WishlistController) AddItem(ctx *gin.Context) {
+	var request wishlistItemRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	items, err := c.wishlistService.AddItem(uid, request.StoreID)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	utils.ResSuccess(ctx, "收藏成功", gin.H{
+		"items": items,
+	})
+}
+
+// RemoveItem 取消收藏
+func (c *WishlistController) RemoveItem(ctx *gin.Context) {
+	var request wishlistItemRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		utils.ResClientError(ctx, "JSON数据格式错误")
+		return
+	}
+
+	uid, err := utils.GetCurrentUID(ctx)
+	if err != nil {
+		utils.ResClientError(ctx, err.Error())
+		return
+	}
+
+	items, err := c.wishlistService.RemoveItem(uid, request.StoreID)
+	if err != nil {
+		utils.ResServerError(ctx, err)
+		return
+	}
+
+	utils.ResSuccess(ctx, "取消收藏成功", gin.H{
+		"items": items,
+	})
+}
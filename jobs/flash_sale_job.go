@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/services"
+	"goDDD1/utils"
+	"log"
+	"time"
+)
+
+// StartFlashSaleJob 启动秒杀订单写入协程和剩余库存结算定时任务
+func StartFlashSaleJob() {
+	flashSaleService := services.NewFlashSaleService()
+	flashSaleConfig := config.GetFlashSaleConfig()
+
+	// 上次退出时未处理完成的请求放回队列重新处理，同一请求号只会写入一次
+	if flashSaleConfig.Workers > 0 {
+		if count, err := utils.RestoreQueue(models.CacheKeyFlashSaleProcessing, models.CacheKeyFlashSaleQueue); err != nil {
+			log.Printf("恢复未处理完成的秒杀请求失败: %v", err)
+		} else if count > 0 {
+			log.Printf("已恢复未处理完成的秒杀请求%d条", count)
+		}
+	}
+
+	for i := 0; i < flashSaleConfig.Workers; i++ {
+		go runFlashSaleWorker(i, flashSaleService)
+	}
+	if flashSaleConfig.Workers > 0 {
+		log.Printf("秒杀订单写入协程已启动，数量：%d", flashSaleConfig.Workers)
+	}
+
+	Every("flash_sale_settle", flashSaleConfig.SettleInterval, func() error {
+		count, err := flashSaleService.SettleEnded()
+		if count > 0 {
+			log.Printf("已结算秒杀活动%d个", count)
+		}
+		return err
+	})
+}
+
+// runFlashSaleWorker 从队列中依次取出秒杀请求写入订单，处理完成后从处理中队列移除
+func runFlashSaleWorker(id int, flashSaleService services.FlashSaleService) {
+	for {
+		var request models.FlashSaleRequest
+		raw, ok, err := utils.PopQueueTo(models.CacheKeyFlashSaleQueue, models.CacheKeyFlashSaleProcessing, 5*time.Second, &request)
+		if err != nil {
+			log.Printf("秒杀写入协程%d读取队列失败: %v", id, err)
+			if raw != "" {
+				// 无法解析的数据不再重试
+				_ = utils.AckQueue(models.CacheKeyFlashSaleProcessing, raw)
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		processFlashSaleRequest(id, flashSaleService, &request)
+		if err := utils.AckQueue(models.CacheKeyFlashSaleProcessing, raw); err != nil {
+			log.Printf("秒杀写入协程%d确认请求%s失败: %v", id, request.RequestNo, err)
+		}
+	}
+}
+
+// processFlashSaleRequest 处理一条秒杀请求，并捕获处理中的panic
+func processFlashSaleRequest(id int, flashSaleService services.FlashSaleService, request *models.FlashSaleRequest) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("秒杀写入协程%d处理请求%s异常: %v", id, request.RequestNo, r)
+		}
+	}()
+
+	if err := flashSaleService.ProcessRequest(request); err != nil {
+		log.Printf("秒杀请求%s下单失败: %v", request.RequestNo, err)
+	}
+}
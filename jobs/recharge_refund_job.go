@@ -0,0 +1,19 @@
+package jobs
+
+import (
+	"goDDD1/config"
+	"goDDD1/services"
+	"log"
+)
+
+// StartRechargeRefundJob 启动充值渠道退款重试定时任务
+func StartRechargeRefundJob() {
+	rechargeService := services.NewRechargeService()
+	Every("recharge_refund", config.GetJobConfig().RechargeRefundInterval, func() error {
+		count, err := rechargeService.RetryRefunds()
+		if count > 0 {
+			log.Printf("已完成渠道退款%d笔", count)
+		}
+		return err
+	})
+}
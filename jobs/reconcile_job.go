@@ -0,0 +1,15 @@
+package jobs
+
+import (
+	"goDDD1/config"
+	"goDDD1/services"
+)
+
+// StartReconcileJob 启动钱包对账定时任务
+func StartReconcileJob() {
+	reconcileService := services.NewReconcileService()
+	Every("wallet_reconcile", config.GetJobConfig().ReconcileInterval, func() error {
+		_, _, err := reconcileService.RunReconciliation()
+		return err
+	})
+}
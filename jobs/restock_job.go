@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"goDDD1/config"
+	"goDDD1/services"
+	"log"
+)
+
+// StartRestockJob 启动自动补货任务，执行到期的补货策略后检查低库存告警
+func StartRestockJob() {
+	restockService := services.NewRestockService()
+
+	Every("restock", config.GetRestockConfig().Interval, func() error {
+		logs, err := restockService.RunDuePolicies()
+		if err != nil {
+			return err
+		}
+		if len(logs) > 0 {
+			log.Printf("自动补货%d个商品", len(logs))
+		}
+
+		alerts, err := restockService.CheckLowStock()
+		if len(alerts) > 0 {
+			log.Printf("新增低库存告警%d条", len(alerts))
+		}
+		return err
+	})
+}
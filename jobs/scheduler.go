@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"fmt"
+	"goDDD1/utils"
+	"log"
+	"time"
+)
+
+// Every 按固定间隔在后台执行任务，interval小于等于0时不启动。
+// 多实例部署时通过Redis锁保证同一时刻只有一个实例在执行该任务。
+func Every(name string, interval time.Duration, task func() error) {
+	if interval <= 0 {
+		log.Printf("定时任务[%s]未启用", name)
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			runOnce(name, interval, task)
+		}
+	}()
+
+	log.Printf("定时任务[%s]已启动，执行间隔：%s", name, interval)
+}
+
+// runOnce 加锁后执行一次任务，并捕获任务中的panic
+func runOnce(name string, interval time.Duration, task func() error) {
+	lockKey := fmt.Sprintf("job:lock:%s", name)
+	locked, err := utils.TryLock(lockKey, interval)
+	if err != nil || !locked {
+		return
+	}
+	defer utils.Unlock(lockKey)
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("定时任务[%s]执行异常: %v", name, r)
+		}
+	}()
+
+	if err := task(); err != nil {
+		log.Printf("定时任务[%s]执行失败: %v", name, err)
+	}
+}
@@ -0,0 +1,20 @@
+package jobs
+
+import (
+	"goDDD1/config"
+	"goDDD1/services"
+	"log"
+)
+
+// StartStoreScheduleJob 启动商品可售时间检查任务，商品进入或离开可售时间时发布事件并使商品列表缓存失效
+func StartStoreScheduleJob() {
+	storeScheduleService := services.NewStoreScheduleService()
+
+	Every("store_schedule", config.GetStoreConfig().ScheduleInterval, func() error {
+		events, err := storeScheduleService.CheckTransitions()
+		if len(events) > 0 {
+			log.Printf("商品可售状态变化%d个", len(events))
+		}
+		return err
+	})
+}
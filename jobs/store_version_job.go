@@ -0,0 +1,20 @@
+package jobs
+
+import (
+	"goDDD1/config"
+	"goDDD1/services"
+	"log"
+)
+
+// StartStoreVersionJob 启动定时发布任务，按定时发布时间分批发布到期的商品草稿
+func StartStoreVersionJob() {
+	storeVersionService := services.NewStoreVersionService()
+
+	Every("store_version", config.GetStoreConfig().PublishInterval, func() error {
+		versions, err := storeVersionService.RunScheduled()
+		if len(versions) > 0 {
+			log.Printf("定时发布商品草稿%d个", len(versions))
+		}
+		return err
+	})
+}
@@ -0,0 +1,19 @@
+package jobs
+
+import (
+	"goDDD1/config"
+	"goDDD1/services"
+	"log"
+)
+
+// StartWalletExpireJob 启动过期货币扣除定时任务
+func StartWalletExpireJob() {
+	walletService := services.NewUserWalletService()
+	Every("wallet_expire", config.GetJobConfig().WalletExpireInterval, func() error {
+		count, err := walletService.ExpireWalletBuckets()
+		if count > 0 {
+			log.Printf("已处理过期货币桶%d个", count)
+		}
+		return err
+	})
+}
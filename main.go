@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"goDDD1/config"
+	"goDDD1/jobs"
 	"goDDD1/models"
 	"goDDD1/routes"
 	"log"
@@ -18,6 +19,11 @@ func main() {
 		log.Println("未找到.env文件，将使用默认配置")
 	}
 
+	// 校验支付配置
+	if err := config.GetPaymentConfig().Validate(); err != nil {
+		log.Fatalf("支付配置错误: %v", err)
+	}
+
 	// 初始化数据库
 	db := config.InitDB()
 	defer config.CloseDB()
@@ -33,11 +39,65 @@ func main() {
 		&models.UserCurrencyFlow{},
 		&models.LevelConfig{},
 		&models.LevelHistory{},
-		&models.RewardPackage{},      // 添加奖励包表
-		&models.RewardPackageItem{},  // 添加奖励包物品表
-		&models.RewardRecord{},       // 添加奖励记录表
+		&models.RewardPackage{},     // 添加奖励包表
+		&models.RewardPackageItem{}, // 添加奖励包物品表
+		&models.RewardRecord{},      // 添加奖励记录表
+		&models.UserTransfer{},      // 添加玩家转账记录表
+		&models.WalletDrift{},       // 添加钱包对账差异表
+		&models.WalletBucket{},      // 添加货币桶表
+		&models.RechargeProduct{},   // 添加充值商品表
+		&models.RechargeOrder{},     // 添加充值订单表
+		&models.PurchaseRefund{},    // 添加购买退款记录表
+		&models.SpendLimit{},        // 添加消费限额表
+		&models.Order{},             // 添加商城订单表
+		&models.OrderItem{},         // 添加订单商品表
+		&models.OrderDiscount{},     // 添加订单优惠明细表
+		&models.ComboDiscount{},     // 添加组合优惠表
+		&models.ComboDiscountItem{}, // 添加组合优惠商品表
+		&models.FlashSale{},         // 添加秒杀活动表
+		&models.FlashSaleOrder{},    // 添加秒杀请求结果表
+		&models.PurchaseCounter{},   // 添加限购计数表
+		&models.CouponCampaign{},    // 添加优惠券活动表
+		&models.CouponCode{},        // 添加优惠券兑换码表
+		&models.UserCoupon{},        // 添加玩家优惠券表
+		&models.StoreBundleItem{},   // 添加礼包内容表
+		&models.Category{},          // 添加商品分类表
+		&models.StoreCategory{},     // 添加商品分类关联表
+		&models.StoreReview{},       // 添加商品评价表
+		&models.ReviewVote{},        // 添加评价点赞表
+		&models.ReviewReport{},      // 添加评价举报表
+		&models.WishlistItem{},      // 添加收藏表
+		&models.Notification{},      // 添加站内通知表
+		&models.Gift{},              // 添加礼物表
+		&models.RestockPolicy{},     // 添加补货策略表
+		&models.RestockLog{},        // 添加补货记录表
+		&models.StockAlert{},        // 添加低库存告警表
+		&models.MediaFile{},         // 添加商品图片表
+		&models.StoreVersion{},      // 添加商品版本表
+		&models.OrderBucketUsage{},  // 添加订单货币桶消耗记录表
 	)
 
+	// 钱包余额和商品库存不允许为负数
+	config.EnsureNonNegativeConstraints(db)
+
+	// 商品搜索索引
+	config.EnsureStoreSearchIndexes(db)
+
+	// 命令行子命令执行完毕后直接退出
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:])
+		return
+	}
+
+	// 启动后台定时任务
+	jobs.StartReconcileJob()
+	jobs.StartWalletExpireJob()
+	jobs.StartRechargeRefundJob()
+	jobs.StartFlashSaleJob()
+	jobs.StartStoreScheduleJob()
+	jobs.StartRestockJob()
+	jobs.StartStoreVersionJob()
+
 	// 设置服务器端口
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"goDDD1/config"
 	"goDDD1/utils"
 
 	"github.com/gin-gonic/gin"
@@ -111,10 +112,9 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 这里可以添加管理员权限检查逻辑
-		// 例如：检查用户角色、权限等
-		uid, exists := c.Get("uid")
-		if !exists {
+		// 只有配置在ADMIN_UIDS中的用户可以访问管理员接口
+		uid, err := utils.GetCurrentUID(c)
+		if err != nil || !config.GetAdminConfig().IsAdmin(uid) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "权限不足",
 				"code":  403,
@@ -123,9 +123,6 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// TODO: 根据uid查询用户角色，验证是否为管理员
-		_ = uid
-
 		c.Next()
 	}
 }
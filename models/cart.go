@@ -0,0 +1,26 @@
+package models
+
+// redis缓存key
+const (
+	CacheKeyUserCart = "cart:%d" // 用户购物车，%d 为用户ID，哈希字段为商品ID，值为数量
+)
+
+// CartItem 购物车中的一行商品，价格和库存为查看时的实时数据
+type CartItem struct {
+	StoreID   uint     `json:"store_id"`
+	Name      string   `json:"name"`
+	CostType  CostType `json:"cost_type"`
+	UnitPrice int64    `json:"unit_price"`
+	Quantity  int64    `json:"quantity"`
+	Stock     int64    `json:"stock"`
+	Subtotal  int64    `json:"subtotal"`  // 原价小计
+	Available bool     `json:"available"` // 商品在售且库存充足
+	Message   string   `json:"message,omitempty"`
+}
+
+// CartView 购物车详情
+type CartView struct {
+	UserID uint             `json:"user_id"`
+	Items  []*CartItem      `json:"items"`
+	Totals map[string]int64 `json:"totals"` // 按货币汇总的原价合计
+}
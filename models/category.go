@@ -0,0 +1,50 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Category 商品分类，通过ParentID组成树，Path记录从根节点到当前节点的ID路径，如/1/5/
+type Category struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	ParentID  uint      `gorm:"not null;default:0;index" json:"parent_id"` // 0表示根节点
+	Code      string    `gorm:"size:20;not null;unique" json:"code"`       // 分类编码，商品的Tag对应分类编码
+	Name      string    `gorm:"size:50;not null" json:"name"`
+	Icon      string    `gorm:"size:255;not null;default:''" json:"icon"`
+	SortOrder int       `gorm:"not null;default:0" json:"sort_order"` // 同级按从小到大排序
+	Status    int       `gorm:"not null;default:1" json:"status"`     // 1:启用 0:停用，停用的分类及其子分类不对玩家展示
+	Path      string    `gorm:"size:255;not null;default:'';index" json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Category) TableName() string {
+	return "categories"
+}
+
+// ChildPath 返回子节点的路径前缀
+func (c *Category) ChildPath() string {
+	return fmt.Sprintf("%s%d/", c.Path, c.ID)
+}
+
+// StoreCategory 商品与分类的对应关系，一个商品可以属于多个分类
+type StoreCategory struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	StoreID    uint      `gorm:"not null;unique_index:idx_store_category" json:"store_id"`
+	CategoryID uint      `gorm:"not null;unique_index:idx_store_category;index" json:"category_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (StoreCategory) TableName() string {
+	return "store_categories"
+}
+
+// CategoryNode 分类树节点，ItemCount为该节点及其所有子节点下的上架商品数量（同一商品只计一次）
+type CategoryNode struct {
+	*Category
+	ItemCount int64           `json:"item_count"`
+	Children  []*CategoryNode `json:"children"`
+}
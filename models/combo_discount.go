@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// ComboDiscount 组合购买优惠：一次购买中包含全部指定商品时，按套数对这些商品打折
+type ComboDiscount struct {
+	ID              uint                `gorm:"primary_key" json:"id"`
+	Name            string              `gorm:"size:100;not null" json:"name"`
+	DiscountPercent uint                `gorm:"not null" json:"discount_percent"` // 优惠百分比，如10表示减免10%
+	Status          int                 `gorm:"not null;default:1" json:"status"` // 1:启用 0:停用
+	StartAt         *time.Time          `json:"start_at"`                         // 开始时间，为空表示不限
+	EndAt           *time.Time          `json:"end_at"`                           // 结束时间，为空表示不限
+	Items           []ComboDiscountItem `gorm:"foreignkey:ComboDiscountID" json:"items"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ComboDiscount) TableName() string {
+	return "combo_discounts"
+}
+
+// IsActive 判断组合优惠在指定时间是否生效
+func (c *ComboDiscount) IsActive(now time.Time) bool {
+	if c.Status != 1 {
+		return false
+	}
+	if c.StartAt != nil && now.Before(*c.StartAt) {
+		return false
+	}
+	if c.EndAt != nil && !now.Before(*c.EndAt) {
+		return false
+	}
+	return true
+}
+
+// ComboDiscountItem 组合优惠中的一件商品
+type ComboDiscountItem struct {
+	ID              uint  `gorm:"primary_key" json:"id"`
+	ComboDiscountID uint  `gorm:"not null;index" json:"combo_discount_id"`
+	StoreID         uint  `gorm:"not null;index" json:"store_id"`
+	Quantity        int64 `gorm:"not null;default:1" json:"quantity"` // 每套所需数量
+}
+
+// TableName 指定表名
+func (ComboDiscountItem) TableName() string {
+	return "combo_discount_items"
+}
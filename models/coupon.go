@@ -0,0 +1,161 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CouponDiscountType 优惠券优惠方式
+type CouponDiscountType string
+
+const (
+	CouponDiscountFixed   CouponDiscountType = "fixed"   // 满减固定金额
+	CouponDiscountPercent CouponDiscountType = "percent" // 按百分比减免
+)
+
+// CouponCodeType 兑换码类型
+type CouponCodeType string
+
+const (
+	CouponCodeShared CouponCodeType = "shared" // 公共兑换码，所有玩家使用同一个码
+	CouponCodeUnique CouponCodeType = "unique" // 批量生成的一次性兑换码，每个码只能领取一次
+)
+
+// UserCouponStatus 玩家优惠券状态
+type UserCouponStatus string
+
+const (
+	UserCouponUnused UserCouponStatus = "unused" // 未使用
+	UserCouponUsed   UserCouponStatus = "used"   // 已使用
+)
+
+// CouponCampaign 优惠券活动，定义优惠规则、使用范围和领取上限
+type CouponCampaign struct {
+	ID            uint               `gorm:"primary_key" json:"id"`
+	Name          string             `gorm:"size:100;not null" json:"name"`
+	DiscountType  CouponDiscountType `gorm:"size:20;not null" json:"discount_type"`
+	DiscountValue int64              `gorm:"not null" json:"discount_value"`                 // 固定金额或减免百分比
+	MaxDiscount   int64              `gorm:"not null;default:0" json:"max_discount"`         // 百分比优惠的最高减免金额，0表示不限
+	CostType      string             `gorm:"size:20;not null;default:''" json:"cost_type"`   // 限定货币，为空表示不限
+	Tags          string             `gorm:"size:255;not null;default:''" json:"tags"`       // 限定商品标签，逗号分隔，为空表示不限
+	StoreIDs      string             `gorm:"size:1000;not null;default:''" json:"store_ids"` // 限定商品ID，逗号分隔，为空表示不限
+	MinSpend      int64              `gorm:"not null;default:0" json:"min_spend"`            // 适用商品的最低消费
+	CodeType      CouponCodeType     `gorm:"size:20;not null" json:"code_type"`
+	TotalLimit    int64              `gorm:"not null;default:0" json:"total_limit"`    // 全部玩家合计可领取数量，0表示不限
+	PerUserLimit  int64              `gorm:"not null;default:1" json:"per_user_limit"` // 每个玩家可领取数量
+	ClaimedCount  int64              `gorm:"not null;default:0" json:"claimed_count"`
+	RedeemedCount int64              `gorm:"not null;default:0" json:"redeemed_count"`
+	StartAt       *time.Time         `json:"start_at"`                         // 开始时间，为空表示不限
+	EndAt         *time.Time         `json:"end_at"`                           // 结束时间，为空表示不限，过期后已领取的优惠券也不能使用
+	Status        int                `gorm:"not null;default:1" json:"status"` // 1:启用 0:停用
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (CouponCampaign) TableName() string {
+	return "coupon_campaigns"
+}
+
+// IsActive 判断活动在指定时间是否有效
+func (c *CouponCampaign) IsActive(now time.Time) bool {
+	if c.Status != 1 {
+		return false
+	}
+	if c.StartAt != nil && now.Before(*c.StartAt) {
+		return false
+	}
+	if c.EndAt != nil && !now.Before(*c.EndAt) {
+		return false
+	}
+	return true
+}
+
+// TagList 限定的商品标签
+func (c *CouponCampaign) TagList() []Tag {
+	tags := make([]Tag, 0)
+	for _, tag := range strings.Split(c.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, Tag(tag))
+		}
+	}
+	return tags
+}
+
+// StoreIDList 限定的商品ID
+func (c *CouponCampaign) StoreIDList() []uint {
+	ids := make([]uint, 0)
+	for _, value := range strings.Split(c.StoreIDs, ",") {
+		if id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32); err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// Applies 判断优惠券是否适用于指定商品
+func (c *CouponCampaign) Applies(store *Store) bool {
+	if c.CostType != "" && c.CostType != string(store.CostType) {
+		return false
+	}
+	if tags := c.TagList(); len(tags) > 0 {
+		matched := false
+		for _, tag := range tags {
+			if tag == store.Tag {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if ids := c.StoreIDList(); len(ids) > 0 {
+		for _, id := range ids {
+			if id == store.ID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// CouponCode 优惠券兑换码
+type CouponCode struct {
+	ID           uint      `gorm:"primary_key" json:"id"`
+	CampaignID   uint      `gorm:"not null;index" json:"campaign_id"`
+	Code         string    `gorm:"size:32;not null;unique_index" json:"code"`
+	MaxClaims    int64     `gorm:"not null;default:1" json:"max_claims"` // 可领取次数，0表示不限（公共兑换码）
+	ClaimedCount int64     `gorm:"not null;default:0" json:"claimed_count"`
+	Status       int       `gorm:"not null;default:1" json:"status"` // 1:可用 0:作废
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (CouponCode) TableName() string {
+	return "coupon_codes"
+}
+
+// UserCoupon 玩家领取到账户中的优惠券
+type UserCoupon struct {
+	ID         uint             `gorm:"primary_key" json:"id"`
+	UserID     uint             `gorm:"not null;index" json:"user_id"`
+	CampaignID uint             `gorm:"not null;index" json:"campaign_id"`
+	CodeID     uint             `gorm:"not null" json:"code_id"`
+	Code       string           `gorm:"size:32;not null" json:"code"`
+	Status     UserCouponStatus `gorm:"size:20;not null;index" json:"status"`
+	OrderID    uint             `gorm:"not null;default:0" json:"order_id"` // 使用优惠券的订单
+	UsedAt     *time.Time       `json:"used_at"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+
+	// 关联关系
+	Campaign CouponCampaign `gorm:"foreignkey:CampaignID" json:"campaign"`
+}
+
+// TableName 指定表名
+func (UserCoupon) TableName() string {
+	return "user_coupons"
+}
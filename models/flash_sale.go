@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+)
+
+// 秒杀相关的Redis键
+const (
+	CacheKeyFlashSaleStock      = "flash_sale:stock:%d"   // 秒杀剩余库存，%d 为秒杀活动ID
+	CacheKeyFlashSaleBought     = "flash_sale:bought:%d"  // 秒杀已购数量，%d 为秒杀活动ID，哈希字段为用户ID，值为数量
+	CacheKeyFlashSaleResult     = "flash_sale:result:%s"  // 秒杀请求处理结果，%s 为请求号
+	CacheKeyFlashSaleQueue      = "flash_sale:queue"      // 待写入数据库的秒杀请求队列
+	CacheKeyFlashSaleProcessing = "flash_sale:processing" // 已从队列取出、尚未处理完成的秒杀请求
+)
+
+// FlashSaleStatus 秒杀活动状态
+type FlashSaleStatus string
+
+const (
+	FlashSaleStatusActive   FlashSaleStatus = "active"   // 进行中（未到开始时间时为待开始）
+	FlashSaleStatusSettled  FlashSaleStatus = "settled"  // 已结束并已将未售出库存退回商品
+	FlashSaleStatusCanceled FlashSaleStatus = "canceled" // 已取消
+)
+
+// FlashSale 秒杀活动，创建时从商品库存中划出独立的秒杀库存并加载到Redis
+type FlashSale struct {
+	ID           uint            `gorm:"primary_key" json:"id"`
+	StoreID      uint            `gorm:"not null;index" json:"store_id"`
+	Name         string          `gorm:"size:100;not null" json:"name"`
+	Price        int64           `gorm:"not null" json:"price"`                    // 秒杀单价，货币类型与商品相同
+	TotalStock   int64           `gorm:"not null" json:"total_stock"`              // 秒杀库存
+	SoldCount    int64           `gorm:"not null;default:0" json:"sold_count"`     // 已成功写入订单的数量
+	PerUserLimit int64           `gorm:"not null;default:1" json:"per_user_limit"` // 每个用户最多购买数量
+	StartAt      time.Time       `gorm:"not null" json:"start_at"`
+	EndAt        time.Time       `gorm:"not null;index" json:"end_at"`
+	Status       FlashSaleStatus `gorm:"size:20;not null;index" json:"status"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (FlashSale) TableName() string {
+	return "flash_sales"
+}
+
+// IsOpen 判断秒杀在指定时间是否可以下单
+func (f *FlashSale) IsOpen(now time.Time) bool {
+	return f.Status == FlashSaleStatusActive && !now.Before(f.StartAt) && now.Before(f.EndAt)
+}
+
+// FlashSaleOrderStatus 秒杀请求处理状态
+type FlashSaleOrderStatus string
+
+const (
+	FlashSaleOrderPending FlashSaleOrderStatus = "pending" // 已抢到库存，等待写入订单
+	FlashSaleOrderSuccess FlashSaleOrderStatus = "success" // 下单成功
+	FlashSaleOrderFailed  FlashSaleOrderStatus = "failed"  // 下单失败，库存已退回
+)
+
+// FlashSaleOrder 秒杀请求的处理结果，每个请求号只处理一次
+type FlashSaleOrder struct {
+	ID          uint                 `gorm:"primary_key" json:"id"`
+	RequestNo   string               `gorm:"size:32;not null;unique_index" json:"request_no"`
+	FlashSaleID uint                 `gorm:"not null;index" json:"flash_sale_id"`
+	UserID      uint                 `gorm:"not null;index" json:"user_id"`
+	Quantity    int64                `gorm:"not null" json:"quantity"`
+	Amount      int64                `gorm:"not null" json:"amount"` // 实付金额
+	Status      FlashSaleOrderStatus `gorm:"size:20;not null" json:"status"`
+	OrderID     uint                 `gorm:"not null;default:0" json:"order_id"` // 成功时对应的商城订单ID
+	OrderNo     string               `gorm:"size:32" json:"order_no"`
+	Reason      string               `gorm:"size:255" json:"reason"` // 失败原因
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (FlashSaleOrder) TableName() string {
+	return "flash_sale_orders"
+}
+
+// FlashSaleRequest 进入写入队列的秒杀请求
+type FlashSaleRequest struct {
+	RequestNo   string    `json:"request_no"`
+	FlashSaleID uint      `json:"flash_sale_id"`
+	UserID      uint      `json:"user_id"`
+	Quantity    int64     `json:"quantity"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// FlashSaleResult 客户端轮询的秒杀结果
+type FlashSaleResult struct {
+	RequestNo string               `json:"request_no"`
+	UserID    uint                 `json:"user_id"`
+	Status    FlashSaleOrderStatus `json:"status"`
+	OrderNo   string               `json:"order_no,omitempty"`
+	Reason    string               `json:"reason,omitempty"`
+}
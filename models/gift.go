@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// GiftStatus 礼物状态
+type GiftStatus string
+
+const (
+	GiftStatusPending  GiftStatus = "pending"  // 等待接收方接受
+	GiftStatusAccepted GiftStatus = "accepted" // 已接受，物品已放入接收方背包
+	GiftStatusDeclined GiftStatus = "declined" // 已拒绝，物品退回赠送方背包
+)
+
+// GiftBox 礼物记录的查询方向
+type GiftBox string
+
+const (
+	GiftBoxSent     GiftBox = "sent"     // 送出的礼物
+	GiftBoxReceived GiftBox = "received" // 收到的礼物
+)
+
+// Gift 玩家购买后赠送给其他玩家的礼物，赠送方付款并获得经验，接收方接受后获得物品
+type Gift struct {
+	ID          uint       `gorm:"primary_key" json:"id"`
+	GiftNo      string     `gorm:"size:32;not null;unique_index" json:"gift_no"`
+	OrderID     uint       `gorm:"not null;unique_index" json:"order_id"`
+	SenderID    uint       `gorm:"not null;index" json:"sender_id"`
+	RecipientID uint       `gorm:"not null;index" json:"recipient_id"`
+	StoreID     uint       `gorm:"not null" json:"store_id"`
+	StoreName   string     `gorm:"size:50;not null" json:"store_name"` // 商品名称快照
+	Quantity    int64      `gorm:"not null" json:"quantity"`
+	Message     string     `gorm:"size:200;not null;default:''" json:"message"`
+	Status      GiftStatus `gorm:"size:20;not null" json:"status"`
+	HandledAt   *time.Time `json:"handled_at"` // 接受或拒绝的时间
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Gift) TableName() string {
+	return "gifts"
+}
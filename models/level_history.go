@@ -11,6 +11,7 @@ type LevelHistory struct {
 	OldLevel        uint      `gorm:"not null" json:"old_level"`   // 旧等级
 	NewLevel        uint      `gorm:"not null" json:"new_level"`   // 新等级
 	ExpGained       uint      `json:"exp_gained"`                  // 获得的经验值
+	ExpDeducted     uint      `json:"exp_deducted"`                // 扣减的经验值（退款等场景）
 	Experience      uint      `json:"experience"`                  // 升级后的经验值
 	CoinRewarded    uint      `json:"coin_rewarded"`               // 奖励的金币
 	DiamondRewarded uint      `json:"diamond_rewarded"`            // 奖励的钻石
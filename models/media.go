@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// MediaFile 上传的图片及其缩略图，按内容校验和去重，同一张图片只保存一份
+type MediaFile struct {
+	ID           uint      `gorm:"primary_key" json:"id"`
+	Checksum     string    `gorm:"size:64;not null;unique_index" json:"checksum"` // 原图内容的SHA-256
+	Key          string    `gorm:"size:255;not null" json:"key"`                  // 原图在存储中的路径
+	ThumbnailKey string    `gorm:"size:255;not null" json:"thumbnail_key"`        // 缩略图在存储中的路径
+	URL          string    `gorm:"size:255;not null" json:"url"`
+	ThumbnailURL string    `gorm:"size:255;not null" json:"thumbnail_url"`
+	ContentType  string    `gorm:"size:50;not null" json:"content_type"`
+	Size         int64     `gorm:"not null" json:"size"`
+	Width        int       `gorm:"not null" json:"width"`
+	Height       int       `gorm:"not null" json:"height"`
+	UploaderID   uint      `gorm:"not null;index" json:"uploader_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (MediaFile) TableName() string {
+	return "media_files"
+}
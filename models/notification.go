@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationType 站内通知类型
+type NotificationType string
+
+const (
+	NotificationPriceDrop    NotificationType = "price_drop"    // 收藏商品降价
+	NotificationBackInStock  NotificationType = "back_in_stock" // 收藏商品补货
+	NotificationGiftSent     NotificationType = "gift_sent"     // 礼物已送出
+	NotificationGiftReceived NotificationType = "gift_received" // 收到礼物
+	NotificationGiftAccepted NotificationType = "gift_accepted" // 送出的礼物被接受
+	NotificationGiftDeclined NotificationType = "gift_declined" // 送出的礼物被拒绝
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        uint             `gorm:"primary_key" json:"id"`
+	UserID    uint             `gorm:"not null;index:idx_notification_user_read" json:"user_id"`
+	Type      NotificationType `gorm:"size:30;not null" json:"type"`
+	Title     string           `gorm:"size:100;not null" json:"title"`
+	Content   string           `gorm:"size:500;not null" json:"content"`
+	StoreID   uint             `gorm:"not null;default:0" json:"store_id,omitempty"` // 关联的商品，0表示无
+	IsRead    bool             `gorm:"not null;default:false;index:idx_notification_user_read" json:"is_read"`
+	ReadAt    *time.Time       `json:"read_at,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderStatus 商城订单状态
+type OrderStatus string
+
+const (
+	OrderStatusPaid              OrderStatus = "paid"               // 已支付
+	OrderStatusPartiallyRefunded OrderStatus = "partially_refunded" // 部分退款
+	OrderStatusRefunded          OrderStatus = "refunded"           // 已全部退款
+)
+
+// OrderCostTypeMixed 订单包含多种货币时的货币类型，各货币金额见Totals
+const OrderCostTypeMixed = "mixed"
+
+// Order 商城订单，多种货币的订单不汇总金额，按货币分别记录在Totals中
+type Order struct {
+	ID             uint        `gorm:"primary_key" json:"id"`
+	OrderNo        string      `gorm:"size:32;not null;unique_index" json:"order_no"` // 订单号
+	UserID         uint        `gorm:"not null;index" json:"user_id"`                 // 用户ID
+	CostType       string      `gorm:"size:20;not null" json:"cost_type"`             // 支付货币类型
+	OriginalPrice  int64       `gorm:"not null" json:"original_price"`                // 原价合计
+	DiscountAmount int64       `gorm:"not null;default:0" json:"discount_amount"`     // 优惠合计
+	FinalPrice     int64       `gorm:"not null" json:"final_price"`                   // 实付金额
+	RefundedAmount int64       `gorm:"not null;default:0" json:"refunded_amount"`     // 已退款金额，多种货币的订单不汇总，见Totals
+	Status         OrderStatus `gorm:"size:20;not null" json:"status"`
+	PaidAt         *time.Time  `json:"paid_at"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+
+	// 关联关系
+	Items     []OrderItem     `gorm:"foreignkey:OrderID" json:"items,omitempty"`     // 订单商品
+	Discounts []OrderDiscount `gorm:"foreignkey:OrderID" json:"discounts,omitempty"` // 订单使用的优惠
+
+	Totals []OrderCurrencyTotal `gorm:"-" json:"totals,omitempty"` // 按货币汇总的金额
+	Gift   *Gift                `gorm:"-" json:"gift,omitempty"`   // 赠送订单对应的礼物
+}
+
+// OrderCurrencyTotal 订单中某一货币的金额汇总
+type OrderCurrencyTotal struct {
+	CostType       string `json:"cost_type"`
+	OriginalPrice  int64  `json:"original_price"`
+	DiscountAmount int64  `json:"discount_amount"`
+	FinalPrice     int64  `json:"final_price"`
+	RefundedAmount int64  `json:"refunded_amount"`
+}
+
+// FillTotals 根据订单商品按货币汇总金额
+func (o *Order) FillTotals() {
+	o.Totals = make([]OrderCurrencyTotal, 0)
+	index := make(map[string]int)
+	for _, item := range o.Items {
+		i, ok := index[item.CostType]
+		if !ok {
+			i = len(o.Totals)
+			index[item.CostType] = i
+			o.Totals = append(o.Totals, OrderCurrencyTotal{CostType: item.CostType})
+		}
+		o.Totals[i].OriginalPrice += item.OriginalPrice
+		o.Totals[i].DiscountAmount += item.DiscountAmount
+		o.Totals[i].FinalPrice += item.FinalPrice
+		o.Totals[i].RefundedAmount += item.RefundedAmount
+	}
+}
+
+// TableName 指定表名
+func (Order) TableName() string {
+	return "orders"
+}
+
+// OrderItem 订单商品，名称和价格为下单时的快照
+type OrderItem struct {
+	ID               uint      `gorm:"primary_key" json:"id"`
+	OrderID          uint      `gorm:"not null;index" json:"order_id"`
+	StoreID          uint      `gorm:"not null;index" json:"store_id"`
+	StoreName        string    `gorm:"size:50;not null" json:"store_name"` // 商品名称快照
+	CostType         string    `gorm:"size:20;not null" json:"cost_type"`  // 货币类型快照
+	UnitPrice        int64     `gorm:"not null" json:"unit_price"`         // 单价快照
+	Quantity         int64     `gorm:"not null" json:"quantity"`
+	OriginalPrice    int64     `gorm:"not null" json:"original_price"`              // 原价小计
+	DiscountAmount   int64     `gorm:"not null;default:0" json:"discount_amount"`   // 优惠小计
+	FinalPrice       int64     `gorm:"not null" json:"final_price"`                 // 实付小计
+	RefundedQuantity int64     `gorm:"not null;default:0" json:"refunded_quantity"` // 已退款数量
+	RefundedAmount   int64     `gorm:"not null;default:0" json:"refunded_amount"`   // 已退款金额
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (OrderItem) TableName() string {
+	return "order_items"
+}
+
+// OrderDiscount 订单使用的优惠明细
+type OrderDiscount struct {
+	ID          uint      `gorm:"primary_key" json:"id"`
+	OrderID     uint      `gorm:"not null;index" json:"order_id"`
+	OrderItemID uint      `gorm:"not null;default:0" json:"order_item_id"` // 作用的订单商品，0表示整单优惠
+	Type        string    `gorm:"size:20;not null" json:"type"`            // 优惠类型
+	Name        string    `gorm:"size:100;not null" json:"name"`           // 优惠说明
+	CostType    string    `gorm:"size:20;not null" json:"cost_type"`
+	Amount      int64     `gorm:"not null" json:"amount"` // 优惠金额
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (OrderDiscount) TableName() string {
+	return "order_discounts"
+}
@@ -0,0 +1,36 @@
+package models
+
+// 价格调整类型
+const (
+	PriceAdjustSale   = "sale"   // 商品促销价
+	PriceAdjustCombo  = "combo"  // 组合购买优惠
+	PriceAdjustLevel  = "level"  // 等级折扣
+	PriceAdjustCoupon = "coupon" // 优惠券
+	PriceAdjustFlash  = "flash"  // 秒杀价
+)
+
+// PriceAdjustment 一条价格调整明细
+type PriceAdjustment struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Amount int64  `json:"amount"` // 优惠金额
+}
+
+// PriceQuoteLine 报价中的一行商品
+type PriceQuoteLine struct {
+	StoreID        uint              `json:"store_id"`
+	Name           string            `json:"name"`
+	CostType       string            `json:"cost_type"`
+	UnitPrice      int64             `json:"unit_price"`
+	Quantity       int64             `json:"quantity"`
+	OriginalPrice  int64             `json:"original_price"`
+	DiscountAmount int64             `json:"discount_amount"`
+	FinalPrice     int64             `json:"final_price"`
+	Adjustments    []PriceAdjustment `json:"adjustments"`
+}
+
+// PriceQuote 报价结果，包含逐行的价格明细和按货币的汇总
+type PriceQuote struct {
+	Lines  []*PriceQuoteLine    `json:"lines"`
+	Totals []OrderCurrencyTotal `json:"totals"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// PurchaseCounter 用户在一个限购周期内购买某个商品的数量，周期变化时使用新的记录，实现按周期重置
+type PurchaseCounter struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	UserID    uint      `gorm:"not null;unique_index:idx_purchase_counter" json:"user_id"`
+	StoreID   uint      `gorm:"not null;unique_index:idx_purchase_counter" json:"store_id"`
+	PeriodKey string    `gorm:"size:20;not null;unique_index:idx_purchase_counter" json:"period_key"` // 周期标识，如d20240101、w20240101、lifetime
+	Count     int64     `gorm:"not null;default:0" json:"count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PurchaseCounter) TableName() string {
+	return "purchase_counters"
+}
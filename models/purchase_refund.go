@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// PurchaseRefund 商城购买退款记录
+type PurchaseRefund struct {
+	ID          uint      `gorm:"primary_key" json:"id"`
+	FlowID      uint      `gorm:"not null;index" json:"flow_id"`          // 被退款的购买流水ID
+	UserID      uint      `gorm:"not null;index" json:"user_id"`          // 用户ID
+	StoreID     uint      `gorm:"not null" json:"store_id"`               // 商品ID
+	CostType    string    `gorm:"size:20;not null" json:"cost_type"`      // 退还的货币类型
+	Quantity    int64     `gorm:"not null" json:"quantity"`               // 退款数量
+	Amount      int64     `gorm:"not null" json:"amount"`                 // 退还的货币数量
+	ExpReverted uint      `gorm:"not null;default:0" json:"exp_reverted"` // 扣回的经验值
+	OldLevel    uint      `gorm:"not null" json:"old_level"`              // 退款前等级
+	NewLevel    uint      `gorm:"not null" json:"new_level"`              // 退款后等级
+	Reason      string    `gorm:"size:255;not null" json:"reason"`        // 退款原因
+	OperatorID  uint      `gorm:"not null" json:"operator_id"`            // 操作人UID
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (PurchaseRefund) TableName() string {
+	return "purchase_refunds"
+}
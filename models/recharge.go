@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+)
+
+// RechargeOrderStatus 充值订单状态
+type RechargeOrderStatus string
+
+const (
+	RechargeStatusCreated   RechargeOrderStatus = "created"   // 已创建，等待支付
+	RechargeStatusPaid      RechargeOrderStatus = "paid"      // 已支付，等待发放
+	RechargeStatusFulfilled RechargeOrderStatus = "fulfilled" // 钻石已发放
+	RechargeStatusFailed    RechargeOrderStatus = "failed"    // 支付失败
+	RechargeStatusRefunding RechargeOrderStatus = "refunding" // 等待支付渠道退款
+	RechargeStatusRefunded  RechargeOrderStatus = "refunded"  // 已退款
+)
+
+// RechargeProduct 充值商品
+type RechargeProduct struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	Name       string    `gorm:"size:50;not null" json:"name"`                   // 商品名称
+	Price      int64     `gorm:"not null" json:"price"`                          // 价格（法币，单位：分）
+	Currency   string    `gorm:"size:10;not null;default:'CNY'" json:"currency"` // 法币币种
+	Diamonds   int64     `gorm:"not null" json:"diamonds"`                       // 发放的钻石数量
+	FirstBonus int64     `gorm:"not null;default:0" json:"first_bonus"`          // 首次购买该商品额外赠送的钻石
+	Status     int       `gorm:"not null;default:1" json:"status"`               // 1:上架 0:下架
+	SortOrder  int       `gorm:"not null;default:0" json:"sort_order"`           // 排序
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (RechargeProduct) TableName() string {
+	return "recharge_products"
+}
+
+// RechargeOrder 充值订单
+type RechargeOrder struct {
+	ID              uint                `gorm:"primary_key" json:"id"`
+	OrderNo         string              `gorm:"size:32;not null;unique_index" json:"order_no"` // 订单号
+	UserID          uint                `gorm:"not null;index" json:"user_id"`                 // 用户ID
+	ProductID       uint                `gorm:"not null" json:"product_id"`                    // 充值商品ID
+	ProductName     string              `gorm:"size:50" json:"product_name"`                   // 下单时的商品名称
+	Amount          int64               `gorm:"not null" json:"amount"`                        // 支付金额（分）
+	Currency        string              `gorm:"size:10;not null" json:"currency"`              // 法币币种
+	Diamonds        int64               `gorm:"not null" json:"diamonds"`                      // 发放的钻石数量
+	Bonus           int64               `gorm:"not null;default:0" json:"bonus"`               // 实际发放的首充赠送
+	Status          RechargeOrderStatus `gorm:"size:20;not null;index" json:"status"`          // 订单状态
+	Provider        string              `gorm:"size:20;not null" json:"provider"`              // 支付渠道
+	ProviderTradeNo string              `gorm:"size:64;index" json:"provider_trade_no"`        // 支付渠道交易号
+	FailReason      string              `gorm:"size:255" json:"fail_reason"`                   // 失败或退款原因
+	PaidAt          *time.Time          `json:"paid_at"`
+	FulfilledAt     *time.Time          `json:"fulfilled_at"`
+	RefundedAt      *time.Time          `json:"refunded_at"`
+	CreatedAt       time.Time           `gorm:"index" json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (RechargeOrder) TableName() string {
+	return "recharge_orders"
+}
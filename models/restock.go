@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+)
+
+// RestockMode 自动补货方式
+type RestockMode string
+
+const (
+	RestockModeFixed     RestockMode = "fixed"     // 补满：库存低于Amount时补到Amount
+	RestockModeIncrement RestockMode = "increment" // 递增：每次增加Amount，不超过MaxStock
+)
+
+// RestockTrigger 补货触发方式
+type RestockTrigger string
+
+const (
+	RestockTriggerSchedule RestockTrigger = "schedule" // 定时任务按计划执行
+	RestockTriggerManual   RestockTrigger = "manual"   // 管理员手动执行
+)
+
+// RestockPolicy 商品自动补货策略，每个商品一条。
+// 执行计划二选一：IntervalMinutes按固定周期执行，Cron按cron表达式（分 时 日 月 周）执行。
+// LowStockThreshold大于0时，库存低于该值会产生低库存告警，策略停用时告警仍然有效
+type RestockPolicy struct {
+	ID                uint        `gorm:"primary_key" json:"id"`
+	StoreID           uint        `gorm:"not null;unique_index" json:"store_id"`
+	Mode              RestockMode `gorm:"size:20;not null" json:"mode"`
+	Amount            int64       `gorm:"not null" json:"amount"`                        // 补满的目标库存或每次递增的数量
+	MaxStock          int64       `gorm:"not null;default:0" json:"max_stock"`           // 递增方式的库存上限
+	IntervalMinutes   int         `gorm:"not null;default:0" json:"interval_minutes"`    // 执行周期（分钟）
+	Cron              string      `gorm:"size:100;not null;default:''" json:"cron"`      // cron表达式
+	LowStockThreshold int64       `gorm:"not null;default:0" json:"low_stock_threshold"` // 低库存告警阈值，0表示不告警
+	Enabled           bool        `gorm:"not null" json:"enabled"`
+	NextRunAt         *time.Time  `gorm:"index" json:"next_run_at"` // 下次执行时间，停用时为空
+	LastRunAt         *time.Time  `json:"last_run_at"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (RestockPolicy) TableName() string {
+	return "restock_policies"
+}
+
+// RestockAmount 根据当前库存计算本次补货数量，不需要补货时返回0
+func (p *RestockPolicy) RestockAmount(stock int64) int64 {
+	switch p.Mode {
+	case RestockModeFixed:
+		if stock < p.Amount {
+			return p.Amount - stock
+		}
+	case RestockModeIncrement:
+		if stock >= p.MaxStock {
+			return 0
+		}
+		if stock+p.Amount > p.MaxStock {
+			return p.MaxStock - stock
+		}
+		return p.Amount
+	}
+	return 0
+}
+
+// RestockLog 补货记录，每次实际增加库存都会记录，用于审计
+type RestockLog struct {
+	ID          uint           `gorm:"primary_key" json:"id"`
+	PolicyID    uint           `gorm:"not null;index" json:"policy_id"`
+	StoreID     uint           `gorm:"not null;index" json:"store_id"`
+	Mode        RestockMode    `gorm:"size:20;not null" json:"mode"`
+	Trigger     RestockTrigger `gorm:"column:trigger_type;size:20;not null" json:"trigger"`
+	StockBefore int64          `gorm:"not null" json:"stock_before"`
+	StockAfter  int64          `gorm:"not null" json:"stock_after"`
+	Added       int64          `gorm:"not null" json:"added"`
+	OperatorID  uint           `gorm:"not null;default:0" json:"operator_id"` // 手动执行时的操作人UID
+	CreatedAt   time.Time      `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (RestockLog) TableName() string {
+	return "restock_logs"
+}
+
+// StockAlert 低库存告警，同一商品同时只有一条未解决的告警，库存恢复到阈值以上时自动解决
+type StockAlert struct {
+	ID         uint       `gorm:"primary_key" json:"id"`
+	StoreID    uint       `gorm:"not null;index" json:"store_id"`
+	StoreName  string     `gorm:"size:50;not null" json:"store_name"`
+	Stock      int64      `gorm:"not null" json:"stock"`     // 告警时的库存
+	Threshold  int64      `gorm:"not null" json:"threshold"` // 告警时的阈值
+	Resolved   bool       `gorm:"not null;default:false;index" json:"resolved"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (StockAlert) TableName() string {
+	return "stock_alerts"
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+)
+
+// ReviewStatus 评价审核状态
+type ReviewStatus string
+
+const (
+	ReviewStatusPending  ReviewStatus = "pending"  // 待审核
+	ReviewStatusApproved ReviewStatus = "approved" // 已通过，对玩家展示并计入评分
+	ReviewStatusRejected ReviewStatus = "rejected" // 审核未通过
+	ReviewStatusHidden   ReviewStatus = "hidden"   // 被举报或管理员隐藏
+)
+
+// ReviewSort 评价列表排序方式
+type ReviewSort string
+
+const (
+	ReviewSortNewest  ReviewSort = "newest"  // 最新
+	ReviewSortHelpful ReviewSort = "helpful" // 最有帮助
+)
+
+// StoreReview 商品评价，每个玩家对每个商品只能有一条评价
+type StoreReview struct {
+	ID           uint         `gorm:"primary_key" json:"id"`
+	StoreID      uint         `gorm:"not null;unique_index:idx_store_review_user" json:"store_id"`
+	UserID       uint         `gorm:"not null;unique_index:idx_store_review_user;index" json:"user_id"`
+	Rating       int          `gorm:"not null" json:"rating"` // 1-5星
+	Content      string       `gorm:"size:1000;not null;default:''" json:"content"`
+	Status       ReviewStatus `gorm:"size:20;not null;index" json:"status"`
+	HelpfulCount int64        `gorm:"not null;default:0" json:"helpful_count"`
+	ReportCount  int64        `gorm:"not null;default:0" json:"report_count"`
+	ModerateNote string       `gorm:"size:255;not null;default:''" json:"moderate_note,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (StoreReview) TableName() string {
+	return "store_reviews"
+}
+
+// ReviewVote 玩家认为评价有帮助的记录
+type ReviewVote struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	ReviewID  uint      `gorm:"not null;unique_index:idx_review_vote_user" json:"review_id"`
+	UserID    uint      `gorm:"not null;unique_index:idx_review_vote_user" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (ReviewVote) TableName() string {
+	return "review_votes"
+}
+
+// ReviewReport 玩家举报评价的记录
+type ReviewReport struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	ReviewID  uint      `gorm:"not null;unique_index:idx_review_report_user" json:"review_id"`
+	UserID    uint      `gorm:"not null;unique_index:idx_review_report_user" json:"user_id"`
+	Reason    string    `gorm:"size:255;not null" json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (ReviewReport) TableName() string {
+	return "review_reports"
+}
@@ -15,6 +15,9 @@ const (
 	RewardTypeDiamond RewardFlowType = "diamond" // 钻石奖励
 )
 
+// RewardFlowRefCurrencyFlow 奖励流水关联的货币流水，RefID为user_currency_flow的ID
+const RewardFlowRefCurrencyFlow = "currency_flow"
+
 // RewardFlow 奖励流水记录模型
 type RewardFlow struct {
 	ID          uint         `gorm:"primary_key" json:"id"`
@@ -23,6 +26,8 @@ type RewardFlow struct {
 	ItemID      uint         `gorm:"index" json:"item_id"`                     // 商品ID（物品奖励时使用）
 	Quantity    int64        `gorm:"not null" json:"quantity"`                 // 获得数量
 	Source      string       `gorm:"size:50;not null" json:"source"`           // 奖励来源
+	RefType     string       `gorm:"size:20" json:"ref_type"`                  // 关联记录类型
+	RefID       uint         `gorm:"index" json:"ref_id"`                      // 关联记录ID
 	Ctime       time.Time    `gorm:"not null" json:"ctime"`                    // 创建时间
 	Utime       time.Time    `gorm:"not null" json:"utime"`                    // 更新时间
 }
@@ -27,13 +27,14 @@ const (
 )
 
 type RewardPackageItem struct {
-	ID        uint      `gorm:"primaryKey"`
-	PackageID uint      `gorm:"column:package_id"`
-	ItemType  uint      `gorm:"column:item_type" json:"item_type"` // 0:商品货物, 1:货币, 2+:预留扩展
-	ItemID    uint      `gorm:"column:item_id" json:"item_id"`
-	Num       uint      `gorm:"column:num" json:"num"`
-	CreatedAt time.Time `gorm:"column:created_at"`
-	UpdatedAt time.Time `gorm:"column:updated_at"`
+	ID          uint      `gorm:"primaryKey"`
+	PackageID   uint      `gorm:"column:package_id"`
+	ItemType    uint      `gorm:"column:item_type" json:"item_type"` // 0:商品货物, 1:货币, 2+:预留扩展
+	ItemID      uint      `gorm:"column:item_id" json:"item_id"`
+	Num         uint      `gorm:"column:num" json:"num"`
+	ExpireHours uint      `gorm:"column:expire_hours;default:0" json:"expire_hours"` // 货币奖励有效期（小时），0表示永不过期
+	CreatedAt   time.Time `gorm:"column:created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at"`
 }
 
 func (RewardPackageItem) TableName() string {
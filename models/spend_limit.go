@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+// SpendPeriod 消费限额统计周期
+type SpendPeriod string
+
+const (
+	SpendPeriodDaily   SpendPeriod = "daily"   // 每日
+	SpendPeriodWeekly  SpendPeriod = "weekly"  // 每周（周一开始）
+	SpendPeriodMonthly SpendPeriod = "monthly" // 每月
+)
+
+// SpendLimitSource 消费限额来源
+type SpendLimitSource string
+
+const (
+	SpendLimitSourceAdmin SpendLimitSource = "admin" // 管理员或监管要求设置
+	SpendLimitSourceSelf  SpendLimitSource = "self"  // 玩家（或家长）自行设置
+)
+
+// SpendLimit 消费限额，UserID为0表示对所有玩家生效的全局限额
+type SpendLimit struct {
+	ID            uint             `gorm:"primary_key" json:"id"`
+	UserID        uint             `gorm:"not null;default:0;unique_index:idx_spend_limit_scope" json:"user_id"`
+	Currency      string           `gorm:"size:10;not null;unique_index:idx_spend_limit_scope" json:"currency"` // coin、diamond或法币币种
+	Period        SpendPeriod      `gorm:"size:10;not null;unique_index:idx_spend_limit_scope" json:"period"`
+	Source        SpendLimitSource `gorm:"size:10;not null;unique_index:idx_spend_limit_scope" json:"source"`
+	Amount        int64            `gorm:"not null" json:"amount"`                   // 周期内消费上限
+	PendingAmount int64            `gorm:"not null;default:0" json:"pending_amount"` // 冷静期后生效的上限，0表示移除限额
+	PendingAt     *time.Time       `json:"pending_at,omitempty"`                     // 待生效变更的生效时间，为空表示没有待生效变更
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SpendLimit) TableName() string {
+	return "spend_limits"
+}
+
+// SpendLimitStatus 玩家在某一货币和周期下的限额使用情况
+type SpendLimitStatus struct {
+	Currency     string      `json:"currency"`
+	Period       SpendPeriod `json:"period"`
+	Limit        int64       `json:"limit"`                   // 生效中的上限（取全局、管理员和自我限额中最严格的）
+	Spent        int64       `json:"spent"`                   // 本周期已消费
+	Remaining    int64       `json:"remaining"`               // 本周期剩余额度
+	ResetAt      time.Time   `json:"reset_at"`                // 本周期结束时间
+	SelfLimit    int64       `json:"self_limit,omitempty"`    // 自我限额，0表示未设置
+	PendingLimit int64       `json:"pending_limit,omitempty"` // 冷静期后生效的自我限额
+	PendingAt    *time.Time  `json:"pending_at,omitempty"`    // 自我限额变更的生效时间
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -16,12 +19,25 @@ const (
 type StoreType string
 
 const (
-	StoreTypeGood StoreType = "good"
-	StoreTypeGift StoreType = "gift"
+	StoreTypeGood   StoreType = "good"
+	StoreTypeGift   StoreType = "gift"
+	StoreTypeBundle StoreType = "bundle" // 礼包，购买后发放礼包内容而不是礼包本身
 )
 
+// PurchaseLimitPeriod 单个用户购买次数限制的周期
+type PurchaseLimitPeriod string
+
+const (
+	PurchaseLimitNone     PurchaseLimitPeriod = ""         // 不限购
+	PurchaseLimitDaily    PurchaseLimitPeriod = "daily"    // 每日限购，按用户时区零点重置
+	PurchaseLimitWeekly   PurchaseLimitPeriod = "weekly"   // 每周限购，按用户时区周一零点重置
+	PurchaseLimitLifetime PurchaseLimitPeriod = "lifetime" // 永久限购
+)
+
+// Tag 商品的主分类编码，对应Category.Code，分类由管理后台维护
 type Tag string
 
+// 预置的分类编码，迁移分类时会为其创建同名根分类
 const (
 	TagNormal     Tag = "Normal"
 	TagClothes    Tag = "Clothes"
@@ -31,17 +47,47 @@ const (
 )
 
 type Store struct {
-	ID        uint       `gorm:"primary_key" json:"id"`
-	Name      string     `gorm:"size:50;not null;unique" json:"name"`
-	Price     int64      `gorm:"not null" json:"price"`
-	Stock     int64      `gorm:"not null" json:"stock"`
-	StoreType StoreType  `gorm:"size:20;not null" json:"store_type"`
-	Status    int        `gorm:"not null;default:1" json:"status"`
-	CostType  CostType   `gorm:"size:20;not null" json:"cost_type"`
-	Tag       Tag        `gorm:"size:20;not null;default:normal" json:"tags"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `sql:"index" json:"-"`
+	ID          uint                `gorm:"primary_key" json:"id"`
+	Name        string              `gorm:"size:50;not null;unique" json:"name"`
+	Description string              `gorm:"size:500;not null;default:''" json:"description"`
+	Price       int64               `gorm:"not null" json:"price"`
+	Stock       int64               `gorm:"not null" json:"stock"`
+	StoreType   StoreType           `gorm:"size:20;not null" json:"store_type"`
+	Status      int                 `gorm:"not null;default:1" json:"status"`
+	CostType    CostType            `gorm:"size:20;not null" json:"cost_type"`
+	Tag         Tag                 `gorm:"size:20;not null;default:normal" json:"tags"`
+	Version     uint                `gorm:"not null;default:0" json:"version"`               // 乐观锁版本号
+	SalePrice   int64               `gorm:"not null;default:0" json:"sale_price"`            // 促销价，0表示不促销
+	SaleStartAt *time.Time          `json:"sale_start_at"`                                   // 促销开始时间，为空表示不限
+	SaleEndAt   *time.Time          `json:"sale_end_at"`                                     // 促销结束时间，为空表示不限
+	LimitPeriod PurchaseLimitPeriod `gorm:"size:20;not null;default:''" json:"limit_period"` // 限购周期，为空表示不限购
+	LimitCount  int64               `gorm:"not null;default:0" json:"limit_count"`           // 每个用户每个周期可购买的数量
+	SalesCount  int64               `gorm:"not null;default:0" json:"sales_count"`           // 累计销量，退款时扣回，用于按热度排序
+	// 商品图片和缩略图的访问地址，为空表示没有图片
+	ImageURL     string `gorm:"size:255;not null;default:''" json:"image_url"`
+	ThumbnailURL string `gorm:"size:255;not null;default:''" json:"thumbnail_url"`
+	// 已通过审核的评价数量、评分总和及各星级数量，随评价状态变化增量维护
+	RatingCount  int64 `gorm:"not null;default:0" json:"rating_count"`
+	RatingTotal  int64 `gorm:"not null;default:0" json:"rating_total"`
+	Rating1Count int64 `gorm:"not null;default:0" json:"rating1_count"`
+	Rating2Count int64 `gorm:"not null;default:0" json:"rating2_count"`
+	Rating3Count int64 `gorm:"not null;default:0" json:"rating3_count"`
+	Rating4Count int64 `gorm:"not null;default:0" json:"rating4_count"`
+	Rating5Count int64 `gorm:"not null;default:0" json:"rating5_count"`
+	// 可售时间段，为空表示不限
+	AvailableFrom  *time.Time `gorm:"index" json:"available_from"`
+	AvailableUntil *time.Time `gorm:"index" json:"available_until"`
+	// 每周循环售卖配置，按服务器时区计算：可售星期（1-7表示周一到周日，逗号分隔）和每日可售时间（15:04），为空表示不限
+	ScheduleDays  string `gorm:"size:20;not null;default:''" json:"schedule_days"`
+	ScheduleStart string `gorm:"size:5;not null;default:''" json:"schedule_start"`
+	ScheduleEnd   string `gorm:"size:5;not null;default:''" json:"schedule_end"`
+	// 购买条件：最低等级、最低VIP等级和需要先拥有的商品，为0表示不限制
+	RequiredLevel    uint       `gorm:"not null;default:0" json:"required_level"`
+	RequiredVipLevel uint       `gorm:"not null;default:0" json:"required_vip_level"`
+	RequiredStoreID  uint       `gorm:"not null;default:0" json:"required_store_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	DeletedAt        *time.Time `sql:"index" json:"-"`
 }
 
 func (Store) TableName() string {
@@ -52,27 +98,188 @@ func (s *Store) BeforeCreate(scope *gorm.Scope) error {
 	return nil
 }
 
+// HasRequirements 判断商品是否设置了购买条件
+func (s *Store) HasRequirements() bool {
+	return s.RequiredLevel > 0 || s.RequiredVipLevel > 0 || s.RequiredStoreID > 0
+}
+
+// HasPurchaseLimit 判断商品是否限购
+func (s *Store) HasPurchaseLimit() bool {
+	return s.LimitPeriod != PurchaseLimitNone && s.LimitCount > 0
+}
+
+// IsOnSale 判断商品在指定时间是否处于促销中
+func (s *Store) IsOnSale(now time.Time) bool {
+	if s.SalePrice <= 0 || s.SalePrice >= s.Price {
+		return false
+	}
+	if s.SaleStartAt != nil && now.Before(*s.SaleStartAt) {
+		return false
+	}
+	if s.SaleEndAt != nil && !now.Before(*s.SaleEndAt) {
+		return false
+	}
+	return true
+}
+
+// CurrentPrice 返回商品在指定时间的实际售价，促销中为促销价
+func (s *Store) CurrentPrice(now time.Time) int64 {
+	if s.IsOnSale(now) {
+		return s.SalePrice
+	}
+	return s.Price
+}
+
+// HasSchedule 判断商品是否配置了可售时间段或每周循环售卖
+func (s *Store) HasSchedule() bool {
+	return s.AvailableFrom != nil || s.AvailableUntil != nil || s.ScheduleDays != "" || s.ScheduleStart != "" || s.ScheduleEnd != ""
+}
+
+// ScheduleDayList 解析每周可售星期，1-7表示周一到周日
+func (s *Store) ScheduleDayList() []int {
+	days := make([]int, 0)
+	for _, value := range strings.Split(s.ScheduleDays, ",") {
+		if day, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && day >= 1 && day <= 7 {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// IsAvailable 判断商品在指定时间是否处于可售时间内，不包含上下架状态
+func (s *Store) IsAvailable(now time.Time) bool {
+	if s.AvailableFrom != nil && now.Before(*s.AvailableFrom) {
+		return false
+	}
+	if s.AvailableUntil != nil && !now.Before(*s.AvailableUntil) {
+		return false
+	}
+
+	if s.ScheduleDays != "" {
+		today := ISOWeekday(now)
+		matched := false
+		for _, day := range s.ScheduleDayList() {
+			if day == today {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	clock := now.Format("15:04")
+	if s.ScheduleStart != "" && clock < s.ScheduleStart {
+		return false
+	}
+	if s.ScheduleEnd != "" && clock >= s.ScheduleEnd {
+		return false
+	}
+	return true
+}
+
+// ISOWeekday 返回1-7表示的星期，周一为1，周日为7
+func ISOWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// RatingColumns 由评价维护的评分统计字段，更新商品信息时不能覆盖
+var RatingColumns = []string{"rating_count", "rating_total", "rating1_count", "rating2_count", "rating3_count", "rating4_count", "rating5_count"}
+
+// RatingAverage 平均评分，保留一位小数，没有评价时为0
+func (s *Store) RatingAverage() float64 {
+	if s.RatingCount == 0 {
+		return 0
+	}
+	return math.Round(float64(s.RatingTotal)*10/float64(s.RatingCount)) / 10
+}
+
+// RatingHistogram 各星级评价数量，下标0为1星
+func (s *Store) RatingHistogram() []int64 {
+	return []int64{s.Rating1Count, s.Rating2Count, s.Rating3Count, s.Rating4Count, s.Rating5Count}
+}
+
 type StoreDTO struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Price     int64     `json:"price"`
-	Stock     int64     `json:"stock"`
-	StoreType StoreType `json:"store_type"`
-	Status    int       `json:"status"`
-	CostType  CostType  `json:"cost_type"`
-	Tag       Tag       `json:"tag"`
+	ID          uint                `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Price       int64               `json:"price"`
+	Stock       int64               `json:"stock"`
+	StoreType   StoreType           `json:"store_type"`
+	Status      int                 `json:"status"`
+	CostType    CostType            `json:"cost_type"`
+	Tag         Tag                 `json:"tag"`
+	SalePrice   int64               `json:"sale_price,omitempty"`
+	SaleStartAt *time.Time          `json:"sale_start_at,omitempty"`
+	SaleEndAt   *time.Time          `json:"sale_end_at,omitempty"`
+	LimitPeriod PurchaseLimitPeriod `json:"limit_period,omitempty"`
+	LimitCount  int64               `json:"limit_count,omitempty"`
+	SalesCount  int64               `json:"sales_count"`
+	// 商品图片和缩略图的访问地址
+	ImageURL     string `json:"image_url,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	// 平均评分和各星级评价数量（下标0为1星）
+	RatingAverage   float64 `json:"rating_average"`
+	RatingCount     int64   `json:"rating_count"`
+	RatingHistogram []int64 `json:"rating_histogram"`
+	// 可售时间段和每周循环售卖配置
+	AvailableFrom  *time.Time `json:"available_from,omitempty"`
+	AvailableUntil *time.Time `json:"available_until,omitempty"`
+	ScheduleDays   string     `json:"schedule_days,omitempty"`
+	ScheduleStart  string     `json:"schedule_start,omitempty"`
+	ScheduleEnd    string     `json:"schedule_end,omitempty"`
+	// 购买条件，以及当前登录用户是否未满足条件和未满足的原因
+	RequiredLevel    uint   `json:"required_level,omitempty"`
+	RequiredVipLevel uint   `json:"required_vip_level,omitempty"`
+	RequiredStoreID  uint   `json:"required_store_id,omitempty"`
+	Locked           bool   `json:"locked"`
+	LockReason       string `json:"lock_reason,omitempty"`
+	// 当前登录用户本周期剩余可购买数量，不限购时为空
+	RemainingPurchases *int64 `json:"remaining_purchases,omitempty"`
+	// 礼包内容、按原价计算的价值和相比单独购买节省的数量，仅礼包有值
+	Contents []*StoreBundleItem `json:"contents,omitempty"`
+	Value    int64              `json:"value,omitempty"`
+	Savings  int64              `json:"savings,omitempty"`
 }
 
 func (s *Store) ToStoreDTO() *StoreDTO {
 	return &StoreDTO{
-		ID:        s.ID,
-		Name:      s.Name,
-		Price:     s.Price,
-		Stock:     s.Stock,
-		StoreType: s.StoreType,
-		Status:    s.Status,
-		CostType:  s.CostType,
-		Tag:       s.Tag, // 添加这一行
+		ID:          s.ID,
+		Name:        s.Name,
+		Description: s.Description,
+		Price:       s.Price,
+		Stock:       s.Stock,
+		StoreType:   s.StoreType,
+		Status:      s.Status,
+		CostType:    s.CostType,
+		Tag:         s.Tag, // 添加这一行
+		SalePrice:   s.SalePrice,
+		SaleStartAt: s.SaleStartAt,
+		SaleEndAt:   s.SaleEndAt,
+		LimitPeriod: s.LimitPeriod,
+		LimitCount:  s.LimitCount,
+		SalesCount:  s.SalesCount,
+
+		ImageURL:     s.ImageURL,
+		ThumbnailURL: s.ThumbnailURL,
+
+		RatingAverage:   s.RatingAverage(),
+		RatingCount:     s.RatingCount,
+		RatingHistogram: s.RatingHistogram(),
+
+		AvailableFrom:  s.AvailableFrom,
+		AvailableUntil: s.AvailableUntil,
+		ScheduleDays:   s.ScheduleDays,
+		ScheduleStart:  s.ScheduleStart,
+		ScheduleEnd:    s.ScheduleEnd,
+
+		RequiredLevel:    s.RequiredLevel,
+		RequiredVipLevel: s.RequiredVipLevel,
+		RequiredStoreID:  s.RequiredStoreID,
 	}
 }
 
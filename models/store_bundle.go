@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// StoreBundleItem 礼包内容，与RewardPackageItem类似，内容可以是商品或货币
+type StoreBundleItem struct {
+	ID        uint       `gorm:"primary_key" json:"id"`
+	BundleID  uint       `gorm:"not null;index" json:"bundle_id"`             // 礼包商品ID
+	ItemType  uint       `gorm:"not null" json:"item_type"`                   // 0:商品货物, 1:货币
+	StoreID   uint       `gorm:"not null;default:0" json:"store_id"`          // 商品货物的商品ID
+	Currency  WalletType `gorm:"size:20;not null;default:''" json:"currency"` // 货币类型
+	Num       int64      `gorm:"not null" json:"num"`                         // 每个礼包包含的数量
+	StoreName string     `gorm:"-" json:"store_name,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (StoreBundleItem) TableName() string {
+	return "store_bundle_items"
+}
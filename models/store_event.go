@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	CacheKeyStoreCatalogVersion = "store:catalog:version"     // 商品列表缓存版本号，商品变更或上下架时递增
+	CacheKeyStoreCatalog        = "store:catalog:%d:%s"       // 商品列表缓存，%d 为缓存版本号，%s 为查询条件
+	CacheKeyStoreScheduleCheck  = "store:schedule:checked_at" // 可售时间检查任务上次检查的时间
+)
+
+// StoreEventType 商品事件类型
+type StoreEventType string
+
+const (
+	StoreEventLive    StoreEventType = "live"    // 商品进入可售时间
+	StoreEventExpired StoreEventType = "expired" // 商品离开可售时间
+)
+
+// StoreEvent 商品可售状态变化事件
+type StoreEvent struct {
+	Type      StoreEventType `json:"type"`
+	StoreID   uint           `json:"store_id"`
+	StoreName string         `json:"store_name"`
+	Tag       Tag            `json:"tag"`
+	At        time.Time      `json:"at"`
+}
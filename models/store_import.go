@@ -0,0 +1,63 @@
+package models
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// StoreFileFormat 商品批量导入导出的文件格式
+type StoreFileFormat string
+
+const (
+	StoreFileCSV  StoreFileFormat = "csv"
+	StoreFileJSON StoreFileFormat = "json"
+)
+
+// StoreFileFormatOf 根据文件扩展名判断文件格式，无法判断时返回空字符串
+func StoreFileFormatOf(filename string) StoreFileFormat {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return StoreFileCSV
+	case ".json":
+		return StoreFileJSON
+	}
+	return ""
+}
+
+// StoreImportStatus 导入行的处理结果
+type StoreImportStatus string
+
+const (
+	StoreImportCreated   StoreImportStatus = "created"   // 新建商品
+	StoreImportUpdated   StoreImportStatus = "updated"   // 修改已有商品
+	StoreImportUnchanged StoreImportStatus = "unchanged" // 与已有商品相同
+	StoreImportInvalid   StoreImportStatus = "invalid"   // 校验未通过
+)
+
+// StoreFieldChange 商品字段的变化，用于导入预览和版本历史
+type StoreFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// StoreImportRow 导入文件中一行商品的处理结果，Row为文件中的行号（CSV从表头下一行的2开始，JSON从1开始）
+type StoreImportRow struct {
+	Row     int                 `json:"row"`
+	ID      uint                `json:"id,omitempty"`
+	Name    string              `json:"name"`
+	Status  StoreImportStatus   `json:"status"`
+	Changes []*StoreFieldChange `json:"changes,omitempty"`
+	Errors  []string            `json:"errors,omitempty"`
+}
+
+// StoreImportResult 商品批量导入的结果。存在校验未通过的行时不会写入任何数据
+type StoreImportResult struct {
+	DryRun    bool              `json:"dry_run"`
+	Applied   bool              `json:"applied"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Invalid   int               `json:"invalid"`
+	Rows      []*StoreImportRow `json:"rows"`
+}
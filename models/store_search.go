@@ -0,0 +1,34 @@
+package models
+
+// StoreSortType 商品搜索排序方式
+type StoreSortType string
+
+const (
+	StoreSortNewest    StoreSortType = "newest"     // 最新上架，默认
+	StoreSortPriceAsc  StoreSortType = "price_asc"  // 价格从低到高
+	StoreSortPriceDesc StoreSortType = "price_desc" // 价格从高到低
+	StoreSortPopular   StoreSortType = "popular"    // 销量从高到低
+)
+
+// StoreSearchQuery 商品搜索条件，零值表示不按该条件过滤
+type StoreSearchQuery struct {
+	Keyword       string        `json:"keyword,omitempty"` // 匹配商品名称和描述
+	Tag           Tag           `json:"tag,omitempty"`
+	CategoryID    uint          `json:"category_id,omitempty"` // 包含该分类的所有启用子分类
+	CostType      CostType      `json:"cost_type,omitempty"`
+	StoreType     StoreType     `json:"store_type,omitempty"`
+	MinPrice      *int64        `json:"min_price,omitempty"`
+	MaxPrice      *int64        `json:"max_price,omitempty"`
+	AvailableOnly bool          `json:"available_only,omitempty"` // 只返回当前处于可售时间内的商品
+	InStockOnly   bool          `json:"in_stock_only,omitempty"`  // 只返回有库存的商品
+	Sort          StoreSortType `json:"sort"`
+	Cursor        string        `json:"cursor,omitempty"` // 上一页返回的next_cursor，为空表示第一页
+	Limit         int           `json:"limit"`
+}
+
+// StoreSearchResult 商品搜索结果
+type StoreSearchResult struct {
+	Stores     []*StoreDTO `json:"stores"`
+	NextCursor string      `json:"next_cursor,omitempty"` // 为空表示没有更多数据
+	HasMore    bool        `json:"has_more"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// StoreVersionStatus 商品版本状态
+type StoreVersionStatus string
+
+const (
+	StoreVersionDraft     StoreVersionStatus = "draft"     // 草稿，未生效
+	StoreVersionScheduled StoreVersionStatus = "scheduled" // 已设置定时发布
+	StoreVersionPublished StoreVersionStatus = "published" // 已发布
+	StoreVersionDiscarded StoreVersionStatus = "discarded" // 已废弃
+)
+
+// StoreVersionSource 商品版本的来源
+type StoreVersionSource string
+
+const (
+	StoreVersionSourceInitial  StoreVersionSource = "initial"  // 首次记录版本时商品的原值
+	StoreVersionSourceDraft    StoreVersionSource = "draft"    // 发布草稿
+	StoreVersionSourceUpdate   StoreVersionSource = "update"   // 通过修改商品接口直接修改
+	StoreVersionSourceImport   StoreVersionSource = "import"   // 批量导入
+	StoreVersionSourceRollback StoreVersionSource = "rollback" // 回滚到历史版本
+)
+
+// StoreVersion 商品版本。草稿只保存修改的字段，发布时按当前商品计算差异并保存发布后的完整字段值，
+// 已发布的版本按发布顺序编号，组成商品的修改历史，可以回滚到任意一个已发布的版本
+type StoreVersion struct {
+	ID          uint               `gorm:"primary_key" json:"id"`
+	StoreID     uint               `gorm:"not null;index" json:"store_id"`
+	Number      uint               `gorm:"not null;default:0" json:"number"` // 发布后的版本号，从1开始，未发布时为0
+	Status      StoreVersionStatus `gorm:"size:20;not null;index" json:"status"`
+	Source      StoreVersionSource `gorm:"size:20;not null" json:"source"`
+	Note        string             `gorm:"size:200;not null;default:''" json:"note"`
+	Fields      string             `gorm:"type:text" json:"-"`                                  // 草稿修改的字段，JSON对象
+	Snapshot    string             `gorm:"type:text" json:"-"`                                  // 发布后商品的完整字段值，JSON对象
+	Changes     string             `gorm:"type:text" json:"-"`                                  // 发布时的字段差异，JSON数组
+	RollbackTo  uint               `gorm:"not null;default:0" json:"rollback_to,omitempty"`     // 回滚的目标版本号
+	PublishAt   *time.Time         `gorm:"index" json:"publish_at"`                             // 定时发布时间
+	Error       string             `gorm:"size:500;not null;default:''" json:"error,omitempty"` // 定时发布失败的原因
+	CreatedBy   uint               `gorm:"not null;default:0" json:"created_by"`
+	ScheduledBy uint               `gorm:"not null;default:0" json:"scheduled_by,omitempty"`
+	PublishedBy uint               `gorm:"not null;default:0" json:"published_by,omitempty"`
+	PublishedAt *time.Time         `json:"published_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+
+	FieldValues    map[string]string   `gorm:"-" json:"fields,omitempty"`
+	SnapshotValues map[string]string   `gorm:"-" json:"snapshot,omitempty"`
+	ChangeList     []*StoreFieldChange `gorm:"-" json:"changes,omitempty"`
+}
+
+// TableName 指定表名
+func (StoreVersion) TableName() string {
+	return "store_versions"
+}
+
+// Decode 将JSON字段解析到FieldValues和ChangeList，withSnapshot为true时同时解析完整字段值
+func (v *StoreVersion) Decode(withSnapshot bool) error {
+	if v.Fields != "" {
+		if err := json.Unmarshal([]byte(v.Fields), &v.FieldValues); err != nil {
+			return err
+		}
+	}
+	if v.Changes != "" {
+		if err := json.Unmarshal([]byte(v.Changes), &v.ChangeList); err != nil {
+			return err
+		}
+	}
+	if withSnapshot && v.Snapshot != "" {
+		if err := json.Unmarshal([]byte(v.Snapshot), &v.SnapshotValues); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Editable 草稿和定时发布的版本可以修改、发布或废弃
+func (v *StoreVersion) Editable() bool {
+	return v.Status == StoreVersionDraft || v.Status == StoreVersionScheduled
+}
@@ -13,18 +13,22 @@ const (
 
 // User 用户模型
 type User struct {
-	ID         uint       `gorm:"primary_key" json:"id"`
-	UID        uint       `gorm:"not null;unique" json:"uid"` // 用户唯一标识，从10000开始自增
-	Username   string     `gorm:"size:50;not null;unique" json:"username"`
-	Email      string     `gorm:"size:100;not null;unique" json:"email"`
-	Password   string     `gorm:"size:100;not null" json:"password"`
-	Level      uint       `gorm:"default:1" json:"level"`       // 用户等级，默认为1级
-	Experience uint       `gorm:"default:0" json:"experience"`  // 用户经验值
-	TotalSpent uint       `gorm:"default:0" json:"total_spent"` // 用户总消费金额
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
-	DeletedAt  *time.Time `sql:"index" json:"-"`
-	IsDeleted  string     `gorm:"default:0;size:1" json:"is_deleted"`
+	ID            uint       `gorm:"primary_key" json:"id"`
+	UID           uint       `gorm:"not null;unique" json:"uid"` // 用户唯一标识，从10000开始自增
+	Username      string     `gorm:"size:50;not null;unique" json:"username"`
+	Email         string     `gorm:"size:100;not null;unique" json:"email"`
+	Password      string     `gorm:"size:100;not null" json:"password"`
+	Level         uint       `gorm:"default:1" json:"level"`          // 用户等级，默认为1级
+	Experience    uint       `gorm:"default:0" json:"experience"`     // 用户经验值
+	RewardedLevel uint       `gorm:"default:0" json:"rewarded_level"` // 已发放升级奖励的最高等级，降级后再次升级不重复发放；为0时以当前等级为准
+	TotalSpent    uint       `gorm:"default:0" json:"total_spent"`    // 用户总消费金额
+	Timezone      string     `gorm:"size:50" json:"timezone"`         // 用户时区，如Asia/Shanghai，为空时使用默认时区
+	VipLevel      uint       `gorm:"default:0" json:"vip_level"`      // VIP等级，0表示不是VIP
+	VipExpireAt   *time.Time `json:"vip_expire_at"`                   // VIP到期时间，为空表示永久有效
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `sql:"index" json:"-"`
+	IsDeleted     string     `gorm:"default:0;size:1" json:"is_deleted"`
 }
 
 // TableName 指定表名
@@ -32,6 +36,14 @@ func (User) TableName() string {
 	return "users"
 }
 
+// CurrentVipLevel 返回指定时间有效的VIP等级，已过期时返回0
+func (u *User) CurrentVipLevel(now time.Time) uint {
+	if u.VipExpireAt != nil && !now.Before(*u.VipExpireAt) {
+		return 0
+	}
+	return u.VipLevel
+}
+
 // BeforeCreate 创建前的钩子
 func (u *User) BeforeCreate(scope *gorm.Scope) error {
 	// 如果UID为空，则自动生成从10000开始的UID
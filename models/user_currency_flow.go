@@ -6,14 +6,33 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
+// 流水关联的业务类型
+const (
+	FlowRefTransfer        = "transfer"         // 玩家转账
+	FlowRefTransferFee     = "transfer_fee"     // 转账手续费
+	FlowRefTransferReverse = "transfer_reverse" // 转账撤销
+	FlowRefAdminAdjust     = "admin_adjust"     // 管理员调整余额
+	FlowRefReconcile       = "reconcile"        // 对账修正
+	FlowRefExpired         = "expired"          // 货币过期
+	FlowRefRecharge        = "recharge"         // 充值到账
+	FlowRefRechargeRefund  = "recharge_refund"  // 充值退款
+	FlowRefPurchase        = "purchase"         // 商城购买
+	FlowRefPurchaseRefund  = "purchase_refund"  // 商城购买退款
+	FlowRefBundle          = "bundle"           // 礼包内含货币到账
+)
+
 type UserCurrencyFlow struct {
-	ID          uint      `gorm:"primary_key" json:"id"`
-	UserID      uint      `gorm:"not null" json:"user_id"`
-	StoreID     uint      `gorm:"not null" json:"store_id"`
-	CostType    string    `gorm:"size:20;not null" json:"cost_type"`
-	Description string    `gorm:"size:255;not null" json:"description"`
-	Price       int64     `gorm:"not null" json:"price"`
-	Ctime       time.Time `gorm:"not null" json:"ctime"`
+	ID            uint      `gorm:"primary_key" json:"id"`
+	UserID        uint      `gorm:"not null" json:"user_id"`
+	StoreID       uint      `gorm:"not null" json:"store_id"`
+	CostType      string    `gorm:"size:20;not null" json:"cost_type"`
+	Description   string    `gorm:"size:255;not null" json:"description"`
+	Price         int64     `gorm:"not null" json:"price"`
+	Quantity      int64     `gorm:"default:0" json:"quantity"`       // 购买或退款的商品数量
+	CounterpartID uint      `gorm:"default:0" json:"counterpart_id"` // 交易对方UID（转账时使用）
+	RefType       string    `gorm:"size:20" json:"ref_type"`         // 关联业务类型
+	RefID         uint      `gorm:"default:0" json:"ref_id"`         // 关联业务ID
+	Ctime         time.Time `gorm:"not null" json:"ctime"`
 }
 
 func (UserCurrencyFlow) TableName() string {
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// TransferStatus 转账状态
+type TransferStatus string
+
+const (
+	TransferStatusSuccess  TransferStatus = "success"  // 转账成功
+	TransferStatusReversed TransferStatus = "reversed" // 已被管理员撤销
+)
+
+// UserTransfer 玩家之间的货币转账记录
+type UserTransfer struct {
+	ID            uint           `gorm:"primary_key" json:"id"`
+	FromUserID    uint           `gorm:"not null;index" json:"from_user_id"`   // 转出方UID
+	ToUserID      uint           `gorm:"not null;index" json:"to_user_id"`     // 接收方UID
+	Type          WalletType     `gorm:"size:20;not null" json:"type"`         // 货币类型
+	Amount        int64          `gorm:"not null" json:"amount"`               // 到账金额
+	Fee           int64          `gorm:"not null;default:0" json:"fee"`        // 手续费（由转出方额外支付）
+	FeeUserID     uint           `gorm:"default:0" json:"fee_user_id"`         // 手续费收款的系统账户UID
+	Remark        string         `gorm:"size:255" json:"remark"`               // 转账备注
+	Status        TransferStatus `gorm:"size:20;not null;index" json:"status"` // 转账状态
+	ReversedBy    uint           `gorm:"default:0" json:"reversed_by"`         // 执行撤销的管理员UID
+	ReverseReason string         `gorm:"size:255" json:"reverse_reason"`       // 撤销原因
+	ReversedAt    *time.Time     `json:"reversed_at"`                          // 撤销时间
+	CreatedAt     time.Time      `gorm:"index" json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserTransfer) TableName() string {
+	return "user_transfers"
+}
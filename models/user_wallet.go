@@ -14,15 +14,36 @@ const (
 	Diamond WalletType = "diamond"
 )
 
+// 新用户钱包的初始赠送额度
+const (
+	InitialCoinGrant    int64 = 1000
+	InitialDiamondGrant int64 = 200
+)
+
+// InitialGrant 获取指定钱包类型的初始赠送额度
+func InitialGrant(walletType WalletType) int64 {
+	switch walletType {
+	case Coin:
+		return InitialCoinGrant
+	case Diamond:
+		return InitialDiamondGrant
+	default:
+		return 0
+	}
+}
+
 // UserWallet 用户钱包模型
 type UserWallet struct {
 	ID        uint       `gorm:"primary_key" json:"id"`
 	UserID    uint       `gorm:"not null;index" json:"user_id"`
 	Num       int64      `gorm:"not null;default:0" json:"num"`
 	Type      WalletType `gorm:"size:20;not null" json:"type"`
+	Version   uint       `gorm:"not null;default:0" json:"version"` // 乐观锁版本号
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `sql:"index" json:"-"`
+
+	Expiries []WalletExpiry `gorm:"-" json:"expiries,omitempty"` // 即将过期的余额，按过期时间升序
 }
 
 // TableName 指定表名
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// BucketStatus 货币桶状态
+type BucketStatus string
+
+const (
+	BucketStatusActive    BucketStatus = "active"    // 仍有余额可用
+	BucketStatusExhausted BucketStatus = "exhausted" // 已全部消耗
+	BucketStatusExpired   BucketStatus = "expired"   // 已过期
+)
+
+// WalletBucket 货币桶，钱包余额由若干个带发放时间和过期时间的桶组成
+type WalletBucket struct {
+	ID        uint         `gorm:"primary_key" json:"id"`
+	UserID    uint         `gorm:"not null;index" json:"user_id"`        // 用户ID
+	Type      WalletType   `gorm:"size:20;not null" json:"type"`         // 货币类型
+	Amount    int64        `gorm:"not null" json:"amount"`               // 发放数量
+	Remaining int64        `gorm:"not null" json:"remaining"`            // 剩余数量
+	Source    string       `gorm:"size:255" json:"source"`               // 来源描述
+	Status    BucketStatus `gorm:"size:20;not null;index" json:"status"` // 状态
+	GrantedAt time.Time    `gorm:"not null" json:"granted_at"`           // 发放时间
+	ExpiresAt *time.Time   `gorm:"index" json:"expires_at"`              // 过期时间，为空表示永不过期
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (WalletBucket) TableName() string {
+	return "wallet_buckets"
+}
+
+// WalletExpiry 钱包中即将过期的余额
+type WalletExpiry struct {
+	Amount    int64     `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OrderBucketUsage 订单付款时消耗的货币桶，退款时按记录恢复原过期时间
+type OrderBucketUsage struct {
+	ID        uint       `gorm:"primary_key" json:"id"`
+	OrderID   uint       `gorm:"not null;index" json:"order_id"`     // 商城订单ID
+	CostType  string     `gorm:"size:20;not null" json:"cost_type"`  // 货币类型
+	Amount    int64      `gorm:"not null" json:"amount"`             // 从过期时间相同的货币桶中消耗的数量
+	Restored  int64      `gorm:"not null;default:0" json:"restored"` // 已退款恢复的数量
+	ExpiresAt *time.Time `json:"expires_at"`                         // 消耗的货币桶的过期时间，为空表示永不过期
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (OrderBucketUsage) TableName() string {
+	return "order_bucket_usages"
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// DriftStatus 对账差异状态
+type DriftStatus string
+
+const (
+	DriftStatusOpen       DriftStatus = "open"       // 待处理
+	DriftStatusFixed      DriftStatus = "fixed"      // 已修正
+	DriftStatusSuperseded DriftStatus = "superseded" // 已被新一轮对账结果取代
+)
+
+// DriftFixMode 对账差异修正方式
+type DriftFixMode string
+
+const (
+	DriftFixLedger DriftFixMode = "ledger" // 以钱包余额为准，补记一条修正流水
+	DriftFixWallet DriftFixMode = "wallet" // 以流水为准，将钱包余额改为期望值
+)
+
+// WalletDrift 钱包对账差异报告
+type WalletDrift struct {
+	ID        uint         `gorm:"primary_key" json:"id"`
+	BatchNo   string       `gorm:"size:32;not null;index" json:"batch_no"` // 对账批次号
+	UserID    uint         `gorm:"not null;index" json:"user_id"`          // 用户ID
+	Type      WalletType   `gorm:"size:20;not null" json:"type"`           // 货币类型
+	Expected  int64        `gorm:"not null" json:"expected"`               // 根据流水推算的期望余额
+	Actual    int64        `gorm:"not null" json:"actual"`                 // 钱包实际余额
+	Drift     int64        `gorm:"not null" json:"drift"`                  // 差额 = 实际 - 期望
+	Status    DriftStatus  `gorm:"size:20;not null;index" json:"status"`   // 处理状态
+	FixMode   DriftFixMode `gorm:"size:20" json:"fix_mode"`                // 修正方式
+	FixedBy   uint         `gorm:"default:0" json:"fixed_by"`              // 修正操作人UID
+	FixedAt   *time.Time   `json:"fixed_at"`                               // 修正时间
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (WalletDrift) TableName() string {
+	return "wallet_drifts"
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// WishlistItem 玩家收藏的商品
+type WishlistItem struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	UserID     uint      `gorm:"not null;unique_index:idx_wishlist_user_store" json:"user_id"`
+	StoreID    uint      `gorm:"not null;unique_index:idx_wishlist_user_store;index" json:"store_id"`
+	PriceAtAdd int64     `gorm:"not null" json:"price_at_add"` // 收藏时的实际售价
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (WishlistItem) TableName() string {
+	return "wishlist_items"
+}
+
+// WishlistEntry 收藏列表中的一项，价格和库存以当前商品数据为准
+type WishlistEntry struct {
+	StoreID      uint      `json:"store_id"`
+	Name         string    `json:"name"`
+	CostType     CostType  `json:"cost_type"`
+	Price        int64     `json:"price"`         // 原价
+	CurrentPrice int64     `json:"current_price"` // 当前实际售价，促销中为促销价
+	PriceAtAdd   int64     `json:"price_at_add"`
+	Stock        int64     `json:"stock"`
+	Available    bool      `json:"available"` // 是否上架且处于可售时间内
+	AddedAt      time.Time `json:"added_at"`
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"goDDD1/config"
 	"goDDD1/controllers"
 	"goDDD1/middleware"
 
@@ -22,6 +23,28 @@ func SetupRouter() *gin.Engine {
 	vueController := controllers.NewVueController()
 	levelController := controllers.NewLevelController()
 	rewardPackageController := controllers.NewRewardPackageController() // 新增奖励包控制器
+	transferController := controllers.NewTransferController()
+	reconcileController := controllers.NewReconcileController()
+	rechargeController := controllers.NewRechargeController()
+	purchaseRefundController := controllers.NewPurchaseRefundController()
+	spendLimitController := controllers.NewSpendLimitController()
+	orderController := controllers.NewOrderController()
+	cartController := controllers.NewCartController()
+	pricingController := controllers.NewPricingController()
+	flashSaleController := controllers.NewFlashSaleController()
+	couponController := controllers.NewCouponController()
+	categoryController := controllers.NewCategoryController()
+	reviewController := controllers.NewReviewController()
+	wishlistController := controllers.NewWishlistController()
+	notificationController := controllers.NewNotificationController()
+	giftController := controllers.NewGiftController()
+	restockController := controllers.NewRestockController()
+	mediaController := controllers.NewMediaController()
+	storeImportController := controllers.NewStoreImportController()
+	storeVersionController := controllers.NewStoreVersionController()
+
+	// 商品图片，不需要登录即可访问
+	r.GET(config.GetMediaConfig().PublicPath+"/*filepath", mediaController.Serve)
 
 	public := r.Group("/api")
 	{
@@ -37,6 +60,9 @@ func SetupRouter() *gin.Engine {
 			author.POST("/send_code", authorController.SendVerificationCode) // 发送验证码
 			author.GET("/info", vueController.Info)
 		}
+
+		// 支付渠道回调不携带用户令牌，由渠道签名校验来源
+		public.POST("/recharge/callback/:provider", rechargeController.PaymentCallback)
 	}
 
 	// API路由组
@@ -58,6 +84,8 @@ func SetupRouter() *gin.Engine {
 			wallets.GET("/user", userWalletController.GetUserWallets)
 			wallets.GET("/user/type", userWalletController.GetWalletByType)        // 获取指定类型钱包 ?user_id=1&type=coin
 			wallets.POST("/user/update", userWalletController.UpdateWalletBalance) // 更新钱包余额
+			wallets.POST("/transfer", transferController.Transfer)                 // 向其他玩家转账
+			wallets.GET("/transfers", transferController.GetMyTransfers)           // 获取当前用户转账记录
 
 		}
 
@@ -67,9 +95,53 @@ func SetupRouter() *gin.Engine {
 			store.GET("/get", storeController.GetStoreByID)
 			store.POST("/update", storeController.UpdateStore)
 			store.POST("/buy", storeController.BuyGoods)
+			store.POST("/gift", giftController.BuyGift) // 购买商品赠送给其他玩家
+			store.POST("/quote", storeController.Quote) // 计算价格明细
 			store.GET("/tag", storeController.GetStoreByTag)
 			store.GET("/tag/page", storeController.GetStoreByTagPage)
 			store.GET("/all", storeController.GetAllStores)
+			store.GET("/search", storeController.SearchStores) // 搜索商品，游标分页
+		}
+
+		// 商品分类相关路由
+		categories := protected.Group("/categories")
+		{
+			categories.GET("", categoryController.GetTree)          // 分类树及商品数量
+			categories.GET("/stores", categoryController.GetStores) // 分类下的商品 ?category_id=1
+		}
+
+		// 商品评价相关路由
+		reviews := protected.Group("/reviews")
+		{
+			reviews.GET("", reviewController.ListStoreReviews)     // 商品评价列表 ?store_id=1&sort=helpful
+			reviews.POST("/submit", reviewController.SubmitReview) // 发表或修改评价
+			reviews.POST("/helpful", reviewController.MarkHelpful) // 标记评价有帮助
+			reviews.POST("/report", reviewController.ReportReview) // 举报评价
+		}
+
+		// 购物车相关路由
+		cart := protected.Group("/cart")
+		{
+			cart.GET("", cartController.GetCart)            // 查看购物车
+			cart.POST("/add", cartController.AddItem)       // 添加商品
+			cart.POST("/update", cartController.UpdateItem) // 修改商品数量
+			cart.POST("/remove", cartController.RemoveItem) // 移除商品
+			cart.POST("/clear", cartController.ClearCart)   // 清空购物车
+			cart.POST("/checkout", cartController.Checkout) // 结算购物车
+		}
+
+		// 优惠券相关路由
+		coupons := protected.Group("/coupons")
+		{
+			coupons.POST("/claim", couponController.ClaimCoupon) // 使用兑换码领取优惠券
+		}
+
+		// 秒杀相关路由
+		flashSales := protected.Group("/flash-sales")
+		{
+			flashSales.GET("", flashSaleController.ListOpenSales)      // 进行中和即将开始的秒杀
+			flashSales.POST("/purchase", flashSaleController.Purchase) // 参与秒杀
+			flashSales.GET("/result", flashSaleController.GetResult)   // 轮询秒杀结果 ?request_no=
 		}
 
 		backpack := protected.Group("/backpack")
@@ -102,6 +174,150 @@ func SetupRouter() *gin.Engine {
 			rewards.POST("/grant", rewardPackageController.GrantReward)                         // 手动发放奖励
 			rewards.GET("/records/user/:user_id", rewardPackageController.GetUserRewardRecords) // 获取用户奖励记录
 		}
+
+		// 充值相关路由
+		recharge := protected.Group("/recharge")
+		{
+			recharge.GET("/products", rechargeController.ListProducts) // 获取充值商品列表
+			recharge.POST("/orders", rechargeController.CreateOrder)   // 创建充值订单
+			recharge.GET("/orders", rechargeController.GetMyOrders)    // 获取当前用户充值订单
+		}
+
+		// 当前登录用户相关路由
+		me := protected.Group("/me")
+		{
+			me.GET("/limits", spendLimitController.GetMyLimits)                 // 获取消费限额及使用情况
+			me.POST("/limits", spendLimitController.SetMyLimit)                 // 设置自我消费限额
+			me.GET("/orders", orderController.GetMyOrders)                      // 获取购买记录
+			me.GET("/orders/:order_no", orderController.GetMyOrder)             // 获取订单详情
+			me.GET("/coupons", couponController.GetMyCoupons)                   // 获取我的优惠券
+			me.GET("/wishlist", wishlistController.GetWishlist)                 // 获取收藏列表
+			me.POST("/wishlist/add", wishlistController.AddItem)                // 收藏商品
+			me.POST("/wishlist/remove", wishlistController.RemoveItem)          // 取消收藏
+			me.GET("/notifications", notificationController.GetMyNotifications) // 获取站内通知
+			me.POST("/notifications/read", notificationController.MarkRead)     // 标记通知已读
+			me.GET("/gifts", giftController.GetMyGifts)                         // 获取送出或收到的礼物
+			me.POST("/gifts/accept", giftController.AcceptGift)                 // 接受礼物
+			me.POST("/gifts/decline", giftController.DeclineGift)               // 拒绝礼物
+		}
+	}
+
+	// 管理员路由组
+	admin := r.Group("/api/admin")
+	admin.Use(middleware.AdminAuthMiddleware())
+	{
+		adminUsers := admin.Group("/users")
+		{
+			adminUsers.POST("/vip", userController.SetVip) // 设置用户VIP等级
+		}
+
+		adminStores := admin.Group("/stores")
+		{
+			adminStores.GET("/export", storeImportController.Export)          // 导出全部商品 ?format=csv|json
+			adminStores.POST("/import", storeImportController.Import)         // 批量导入商品，默认只预览差异
+			adminStores.POST("/bundle/items", storeController.SetBundleItems) // 设置礼包内容
+			adminStores.POST("/image", mediaController.UploadImage)           // 上传商品图片
+			adminStores.POST("/image/set", mediaController.SetStoreImage)     // 设置商品图片
+		}
+
+		storeVersions := admin.Group("/store-versions")
+		{
+			storeVersions.GET("", storeVersionController.ListVersions)       // 商品版本历史和草稿 ?store_id=1&status=
+			storeVersions.GET("/get", storeVersionController.GetVersion)     // 版本详情，草稿包含与当前商品的差异 ?id=1
+			storeVersions.POST("/draft", storeVersionController.SaveDraft)   // 创建或修改草稿
+			storeVersions.POST("/schedule", storeVersionController.Schedule) // 设置或取消定时发布
+			storeVersions.POST("/publish", storeVersionController.Publish)   // 立即发布一批草稿
+			storeVersions.POST("/discard", storeVersionController.Discard)   // 废弃草稿
+			storeVersions.POST("/rollback", storeVersionController.Rollback) // 回滚到历史版本
+		}
+
+		transfers := admin.Group("/transfers")
+		{
+			transfers.POST("/reverse", transferController.ReverseTransfer) // 撤销转账
+		}
+
+		reconcile := admin.Group("/reconcile")
+		{
+			reconcile.POST("/run", reconcileController.RunReconciliation) // 手动触发钱包对账
+			reconcile.GET("/drifts", reconcileController.ListDrifts)      // 查询对账差异
+			reconcile.POST("/fix", reconcileController.ApplyCorrection)   // 修正对账差异
+		}
+
+		recharge := admin.Group("/recharge")
+		{
+			recharge.POST("/products/create", rechargeController.CreateProduct) // 创建充值商品
+			recharge.POST("/products/update", rechargeController.UpdateProduct) // 更新充值商品
+			recharge.GET("/orders", rechargeController.SearchOrders)            // 查询充值订单
+			recharge.POST("/refund", rechargeController.RefundOrder)            // 充值订单退款
+			if config.GetPaymentConfig().MockEnabled {
+				recharge.POST("/mock/pay", rechargeController.MockPay) // 模拟支付成功，只在启用模拟支付时注册
+			}
+		}
+
+		purchases := admin.Group("/purchases")
+		{
+			purchases.POST("/refund", purchaseRefundController.RefundPurchase) // 商城购买退款
+			purchases.GET("/refunds", purchaseRefundController.ListRefunds)    // 查询购买退款记录
+		}
+
+		orders := admin.Group("/orders")
+		{
+			orders.GET("", orderController.SearchOrders)       // 查询商城订单
+			orders.GET("/:order_no", orderController.GetOrder) // 获取商城订单详情
+		}
+
+		limits := admin.Group("/limits")
+		{
+			limits.GET("", spendLimitController.ListLimits)    // 查询消费限额配置
+			limits.POST("/set", spendLimitController.SetLimit) // 设置全局或玩家消费限额
+		}
+
+		pricing := admin.Group("/pricing")
+		{
+			pricing.GET("/combos", pricingController.ListCombos)          // 查询组合优惠
+			pricing.POST("/combos/create", pricingController.CreateCombo) // 创建组合优惠
+			pricing.POST("/combos/update", pricingController.UpdateCombo) // 更新组合优惠
+		}
+
+		coupons := admin.Group("/coupons")
+		{
+			coupons.GET("/campaigns", couponController.ListCampaigns)          // 查询优惠券活动
+			coupons.POST("/campaigns/create", couponController.CreateCampaign) // 创建优惠券活动
+			coupons.POST("/codes/generate", couponController.GenerateCodes)    // 生成兑换码
+			coupons.GET("/codes", couponController.ListCodes)                  // 查询兑换码
+		}
+
+		categories := admin.Group("/categories")
+		{
+			categories.GET("", categoryController.ListAll)                        // 查询完整分类树
+			categories.POST("/create", categoryController.CreateCategory)         // 创建分类
+			categories.POST("/update", categoryController.UpdateCategory)         // 更新分类
+			categories.POST("/delete", categoryController.DeleteCategory)         // 删除分类
+			categories.POST("/stores/set", categoryController.SetStoreCategories) // 设置商品所属分类
+		}
+
+		reviews := admin.Group("/reviews")
+		{
+			reviews.GET("", reviewController.ListReviews)              // 查询评价
+			reviews.POST("/moderate", reviewController.ModerateReview) // 审核、隐藏评价
+		}
+
+		flashSales := admin.Group("/flash-sales")
+		{
+			flashSales.GET("", flashSaleController.ListSales)          // 查询秒杀活动
+			flashSales.POST("/create", flashSaleController.CreateSale) // 创建秒杀活动
+			flashSales.POST("/cancel", flashSaleController.CancelSale) // 取消秒杀活动
+		}
+
+		restock := admin.Group("/restock")
+		{
+			restock.GET("/policies", restockController.ListPolicies)         // 查询补货策略
+			restock.POST("/policies/save", restockController.SavePolicy)     // 创建或修改补货策略
+			restock.POST("/policies/delete", restockController.DeletePolicy) // 删除补货策略
+			restock.POST("/run", restockController.RestockNow)               // 立即补货
+			restock.GET("/logs", restockController.ListLogs)                 // 查询补货记录
+			restock.GET("/alerts", restockController.ListAlerts)             // 查询低库存告警
+		}
 	}
 
 	return r
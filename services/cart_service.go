@@ -0,0 +1,242 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/utils"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	cartExpiration  = 7 * 24 * time.Hour // 购物车保留时间，每次修改后刷新
+	cartMaxLines    = 50                 // 购物车最多商品种类
+	cartMaxQuantity = 999                // 单个商品最大数量
+)
+
+// CartService 购物车服务接口
+type CartService interface {
+	// 添加商品到购物车，已存在时累加数量
+	AddItem(userID uint, storeID uint, quantity int64) (*models.CartView, error)
+	// 修改购物车中商品的数量，数量为0时移除
+	UpdateItem(userID uint, storeID uint, quantity int64) (*models.CartView, error)
+	// 从购物车移除商品
+	RemoveItem(userID uint, storeID uint) (*models.CartView, error)
+	// 查看购物车，附带实时价格和库存
+	GetCart(userID uint) (*models.CartView, error)
+	// 清空购物车
+	ClearCart(userID uint) error
+	// 结算购物车中的全部商品，userCouponID为0表示不使用优惠券
+	Checkout(userID uint, userCouponID uint) (*models.Order, error)
+}
+
+// cartService 购物车服务实现
+type cartService struct {
+	storeService StoreService
+}
+
+// NewCartService 创建购物车服务实例
+func NewCartService() CartService {
+	return &cartService{
+		storeService: NewStoreService(),
+	}
+}
+
+// AddItem 添加商品到购物车
+func (s *cartService) AddItem(userID uint, storeID uint, quantity int64) (*models.CartView, error) {
+	if quantity <= 0 {
+		return nil, errors.New("数量必须大于0")
+	}
+
+	lines, err := s.loadLines(userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := lines[storeID]; !ok && len(lines) >= cartMaxLines {
+		return nil, fmt.Errorf("购物车最多只能添加%d种商品", cartMaxLines)
+	}
+	if lines[storeID]+quantity > cartMaxQuantity {
+		return nil, fmt.Errorf("单个商品数量不能超过%d", cartMaxQuantity)
+	}
+	if err := s.checkStore(storeID); err != nil {
+		return nil, err
+	}
+
+	cacheKey := fmt.Sprintf(models.CacheKeyUserCart, userID)
+	if _, err := utils.IncrHashField(cacheKey, strconv.FormatUint(uint64(storeID), 10), quantity, cartExpiration); err != nil {
+		return nil, err
+	}
+	return s.GetCart(userID)
+}
+
+// UpdateItem 修改购物车中商品的数量
+func (s *cartService) UpdateItem(userID uint, storeID uint, quantity int64) (*models.CartView, error) {
+	if quantity < 0 {
+		return nil, errors.New("数量不能为负数")
+	}
+	if quantity == 0 {
+		return s.RemoveItem(userID, storeID)
+	}
+	if quantity > cartMaxQuantity {
+		return nil, fmt.Errorf("单个商品数量不能超过%d", cartMaxQuantity)
+	}
+
+	lines, err := s.loadLines(userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := lines[storeID]; !ok {
+		return nil, errors.New("购物车中没有该商品")
+	}
+
+	cacheKey := fmt.Sprintf(models.CacheKeyUserCart, userID)
+	if err := utils.SetHashField(cacheKey, strconv.FormatUint(uint64(storeID), 10), quantity, cartExpiration); err != nil {
+		return nil, err
+	}
+	return s.GetCart(userID)
+}
+
+// RemoveItem 从购物车移除商品
+func (s *cartService) RemoveItem(userID uint, storeID uint) (*models.CartView, error) {
+	cacheKey := fmt.Sprintf(models.CacheKeyUserCart, userID)
+	if err := utils.DelHashField(cacheKey, strconv.FormatUint(uint64(storeID), 10)); err != nil {
+		return nil, err
+	}
+	return s.GetCart(userID)
+}
+
+// GetCart 查看购物车，价格和库存以当前商品数据为准
+func (s *cartService) GetCart(userID uint) (*models.CartView, error) {
+	lines, err := s.loadLines(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &models.CartView{
+		UserID: userID,
+		Items:  make([]*models.CartItem, 0, len(lines)),
+		Totals: make(map[string]int64),
+	}
+	if len(lines) == 0 {
+		return view, nil
+	}
+
+	storeIDs := make([]uint, 0, len(lines))
+	for storeID := range lines {
+		storeIDs = append(storeIDs, storeID)
+	}
+	sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+
+	var stores []*models.Store
+	if err := config.Database.Where("id IN (?)", storeIDs).Find(&stores).Error; err != nil {
+		return nil, err
+	}
+	storeMap := make(map[uint]*models.Store, len(stores))
+	for _, store := range stores {
+		storeMap[store.ID] = store
+	}
+
+	now := time.Now()
+	for _, storeID := range storeIDs {
+		item := &models.CartItem{
+			StoreID:  storeID,
+			Quantity: lines[storeID],
+		}
+
+		store, ok := storeMap[storeID]
+		switch {
+		case !ok:
+			item.Message = "商品不存在"
+		case store.Status != 1:
+			item.Message = "商品已下架"
+		case !store.IsAvailable(now):
+			item.Message = "商品不在可售时间内"
+		case store.Stock < item.Quantity:
+			item.Message = "库存不足"
+		default:
+			item.Available = true
+		}
+
+		if ok {
+			item.Name = store.Name
+			item.CostType = store.CostType
+			item.UnitPrice = store.Price
+			item.Stock = store.Stock
+			item.Subtotal = store.Price * item.Quantity
+			if item.Available {
+				view.Totals[string(store.CostType)] += item.Subtotal
+			}
+		}
+
+		view.Items = append(view.Items, item)
+	}
+
+	return view, nil
+}
+
+// ClearCart 清空购物车
+func (s *cartService) ClearCart(userID uint) error {
+	return utils.DeleteCache(fmt.Sprintf(models.CacheKeyUserCart, userID))
+}
+
+// Checkout 结算购物车，所有商品在同一个事务中购买，成功后清空购物车
+func (s *cartService) Checkout(userID uint, userCouponID uint) (*models.Order, error) {
+	lines, err := s.loadLines(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("购物车为空")
+	}
+
+	purchaseLines := make([]PurchaseLine, 0, len(lines))
+	for storeID, quantity := range lines {
+		purchaseLines = append(purchaseLines, PurchaseLine{StoreID: storeID, Quantity: uint(quantity)})
+	}
+
+	order, err := s.storeService.Checkout(userID, purchaseLines, userCouponID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ClearCart(userID); err != nil {
+		// 订单已生成，清空购物车失败不影响结算结果
+		log.Printf("清空用户%d的购物车失败: %v", userID, err)
+	}
+	return order, nil
+}
+
+// loadLines 读取购物车中的商品ID和数量
+func (s *cartService) loadLines(userID uint) (map[uint]int64, error) {
+	fields, err := utils.GetHashAll(fmt.Sprintf(models.CacheKeyUserCart, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(map[uint]int64, len(fields))
+	for field, value := range fields {
+		storeID, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			continue
+		}
+		quantity, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || quantity <= 0 {
+			continue
+		}
+		lines[uint(storeID)] = quantity
+	}
+	return lines, nil
+}
+
+// checkStore 检查商品是否在售
+func (s *cartService) checkStore(storeID uint) error {
+	var store models.Store
+	if err := config.Database.Where("id = ? AND status = 1", storeID).First(&store).Error; err != nil {
+		return errors.New("商品不存在或已下架")
+	}
+	return nil
+}
@@ -0,0 +1,485 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// CategoryService 商品分类服务接口
+type CategoryService interface {
+	// 分类管理
+	CreateCategory(category *models.Category) error
+	UpdateCategory(category *models.Category) (*models.Category, error)
+	DeleteCategory(id uint) error
+	GetCategoryByCode(code string) (*models.Category, error)
+	// 获取分类树，activeOnly为true时只包含启用的分类，商品数量只统计可售商品
+	GetCategoryTree(activeOnly bool) ([]*models.CategoryNode, error)
+	// 分页获取分类及其子分类下的可售商品
+	GetCategoryStores(categoryID uint, page, pageSize int) ([]*models.StoreDTO, int64, error)
+	// 设置商品所属的分类，整体替换原有分类
+	SetStoreCategories(storeID uint, categoryIDs []uint) error
+	// 为商品已有的Tag创建对应的根分类并关联商品，可重复执行
+	MigrateTagCategories() (int, int64, error)
+}
+
+type categoryService struct{}
+
+// NewCategoryService 创建商品分类服务实例
+func NewCategoryService() CategoryService {
+	return &categoryService{}
+}
+
+// CreateCategory 创建分类，ParentID为0时创建根分类
+func (s *categoryService) CreateCategory(category *models.Category) error {
+	category.ID = 0
+	category.Path = "/"
+	if category.ParentID != 0 {
+		var parent models.Category
+		if err := config.Database.First(&parent, category.ParentID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return errors.New("父分类不存在")
+			}
+			return err
+		}
+		category.Path = parent.ChildPath()
+	}
+
+	if err := config.Database.Create(category).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return fmt.Errorf("分类编码%s已存在", category.Code)
+		}
+		return err
+	}
+	invalidateStoreCatalog()
+	return nil
+}
+
+// UpdateCategory 更新分类，修改父分类时同步更新所有子分类的路径，修改编码时同步更新商品的Tag
+func (s *categoryService) UpdateCategory(category *models.Category) (*models.Category, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var current models.Category
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&current, category.ID).Error; err != nil {
+		SafeRollback(tx)
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("分类不存在")
+		}
+		return nil, err
+	}
+
+	oldChildPath := current.ChildPath()
+	if category.ParentID != current.ParentID {
+		current.Path = "/"
+		if category.ParentID != 0 {
+			var parent models.Category
+			if err := tx.First(&parent, category.ParentID).Error; err != nil {
+				SafeRollback(tx)
+				if gorm.IsRecordNotFoundError(err) {
+					return nil, errors.New("父分类不存在")
+				}
+				return nil, err
+			}
+			// 不能移动到自身或自身的子分类下
+			if parent.ID == current.ID || strings.HasPrefix(parent.Path, oldChildPath) {
+				SafeRollback(tx)
+				return nil, errors.New("不能将分类移动到自身或其子分类下")
+			}
+			current.Path = parent.ChildPath()
+		}
+		current.ParentID = category.ParentID
+	}
+
+	oldCode := current.Code
+	current.Code = category.Code
+	current.Name = category.Name
+	current.Icon = category.Icon
+	current.SortOrder = category.SortOrder
+	current.Status = category.Status
+	if err := tx.Save(&current).Error; err != nil {
+		SafeRollback(tx)
+		if isDuplicateKeyError(err) {
+			return nil, fmt.Errorf("分类编码%s已存在", category.Code)
+		}
+		return nil, err
+	}
+
+	// 分类编码变化时同步修改使用该编码作为Tag的商品
+	if current.Code != oldCode {
+		if err := tx.Model(&models.Store{}).Where("tag = ?", oldCode).UpdateColumn("tag", current.Code).Error; err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	if newChildPath := current.ChildPath(); newChildPath != oldChildPath {
+		if err := tx.Model(&models.Category{}).Where("path LIKE ?", escapeLike(oldChildPath)+"%").
+			UpdateColumn("path", gorm.Expr("CONCAT(?, SUBSTRING(path, ?))", newChildPath, len(oldChildPath)+1)).Error; err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	invalidateStoreCatalog()
+	return &current, nil
+}
+
+// DeleteCategory 删除分类，存在子分类或商品时不允许删除
+func (s *categoryService) DeleteCategory(id uint) error {
+	var category models.Category
+	if err := config.Database.First(&category, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return errors.New("分类不存在")
+		}
+		return err
+	}
+
+	var count int64
+	if err := config.Database.Model(&models.Category{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("请先删除子分类")
+	}
+	if err := config.Database.Model(&models.StoreCategory{}).Where("category_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("请先移除分类下的商品")
+	}
+	if err := config.Database.Model(&models.Store{}).Where("tag = ?", category.Code).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("仍有商品的Tag使用该分类编码")
+	}
+
+	if err := config.Database.Delete(&category).Error; err != nil {
+		return err
+	}
+	invalidateStoreCatalog()
+	return nil
+}
+
+// GetCategoryByCode 根据分类编码获取分类
+func (s *categoryService) GetCategoryByCode(code string) (*models.Category, error) {
+	var category models.Category
+	if err := config.Database.Where("code = ?", code).First(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetCategoryTree 获取分类树，玩家查询的结果使用商品列表缓存
+func (s *categoryService) GetCategoryTree(activeOnly bool) ([]*models.CategoryNode, error) {
+	if !activeOnly {
+		return buildCategoryTree(false)
+	}
+
+	var tree []*models.CategoryNode
+	err := loadStoreCatalog("categories", &tree, func() error {
+		var err error
+		tree, err = buildCategoryTree(true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// buildCategoryTree 加载全部分类组装成树，并统计每个节点子树下去重后的商品数量
+func buildCategoryTree(activeOnly bool) ([]*models.CategoryNode, error) {
+	var categories []*models.Category
+	if err := config.Database.Order("sort_order asc").Order("id asc").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	// 查询每个分类直接关联的商品
+	db := config.Database.Table("store_categories").
+		Select("store_categories.category_id, store_categories.store_id").
+		Joins("JOIN stores ON stores.id = store_categories.store_id AND stores.deleted_at IS NULL").
+		Where("stores.status = 1")
+	if activeOnly {
+		db = whereAvailable(db, time.Now())
+	}
+	rows, err := db.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	direct := make(map[uint][]uint)
+	for rows.Next() {
+		var categoryID, storeID uint
+		if err := rows.Scan(&categoryID, &storeID); err != nil {
+			return nil, err
+		}
+		direct[categoryID] = append(direct[categoryID], storeID)
+	}
+
+	// 分类按sort_order排序加载，子节点按加载顺序追加即保持同级顺序
+	nodes := make(map[uint]*models.CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &models.CategoryNode{Category: category, Children: make([]*models.CategoryNode, 0)}
+	}
+	roots := make([]*models.CategoryNode, 0)
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if activeOnly && category.Status != 1 {
+			continue
+		}
+		if category.ParentID == 0 {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[category.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	// 停用分类不会挂到树上，其子分类也随之隐藏
+	for _, root := range roots {
+		countCategoryItems(root, direct)
+	}
+	return roots, nil
+}
+
+// countCategoryItems 递归统计节点子树下的商品，返回子树中的商品ID集合
+func countCategoryItems(node *models.CategoryNode, direct map[uint][]uint) map[uint]bool {
+	stores := make(map[uint]bool)
+	for _, storeID := range direct[node.ID] {
+		stores[storeID] = true
+	}
+	for _, child := range node.Children {
+		for storeID := range countCategoryItems(child, direct) {
+			stores[storeID] = true
+		}
+	}
+	node.ItemCount = int64(len(stores))
+	return stores
+}
+
+// GetCategoryStores 分页获取启用分类及其子分类下的可售商品
+func (s *categoryService) GetCategoryStores(categoryID uint, page, pageSize int) ([]*models.StoreDTO, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	var catalog storeCatalogPage
+	err := loadStoreCatalog(fmt.Sprintf("category:%d:%d:%d", categoryID, page, pageSize), &catalog, func() error {
+		categoryIDs, err := activeCategorySubtree(config.Database, categoryID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		query := func() *gorm.DB {
+			return whereAvailable(config.Database.Model(&models.Store{}), now).
+				Where("status = 1").
+				Where("id IN (?)", config.Database.Table("store_categories").Select("store_id").Where("category_id IN (?)", categoryIDs).SubQuery())
+		}
+
+		if err := query().Count(&catalog.Total).Error; err != nil {
+			return err
+		}
+
+		var stores []*models.Store
+		if err := query().Order("id").Offset(offset).Limit(pageSize).Find(&stores).Error; err != nil {
+			return err
+		}
+		catalog.Stores = make([]*models.StoreDTO, len(stores))
+		for i, store := range stores {
+			catalog.Stores[i] = store.ToStoreDTO()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return catalog.Stores, catalog.Total, nil
+}
+
+// activeCategorySubtree 返回启用分类及其所有启用子分类的ID，分类本身或任一上级分类停用时视为不存在
+func activeCategorySubtree(db *gorm.DB, categoryID uint) ([]uint, error) {
+	var category models.Category
+	if err := db.First(&category, categoryID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+
+	var descendants []*models.Category
+	if err := db.Where("path LIKE ?", escapeLike(category.ChildPath())+"%").Find(&descendants).Error; err != nil {
+		return nil, err
+	}
+
+	// 检查上级分类是否启用
+	var disabled int64
+	ancestorIDs := strings.Split(strings.Trim(category.Path, "/"), "/")
+	if category.Path != "/" {
+		if err := db.Model(&models.Category{}).Where("id IN (?) AND status <> 1", ancestorIDs).Count(&disabled).Error; err != nil {
+			return nil, err
+		}
+	}
+	if category.Status != 1 || disabled > 0 {
+		return nil, ErrCategoryNotFound
+	}
+
+	// 子分类停用时其整个子树都不展示
+	disabledPaths := make([]string, 0)
+	for _, descendant := range descendants {
+		if descendant.Status != 1 {
+			disabledPaths = append(disabledPaths, descendant.ChildPath())
+		}
+	}
+	ids := []uint{category.ID}
+	for _, descendant := range descendants {
+		if descendant.Status != 1 {
+			continue
+		}
+		hidden := false
+		for _, path := range disabledPaths {
+			if strings.HasPrefix(descendant.Path, path) {
+				hidden = true
+				break
+			}
+		}
+		if !hidden {
+			ids = append(ids, descendant.ID)
+		}
+	}
+	return ids, nil
+}
+
+// SetStoreCategories 设置商品所属的分类
+func (s *categoryService) SetStoreCategories(storeID uint, categoryIDs []uint) error {
+	unique := make(map[uint]bool, len(categoryIDs))
+	ids := make([]uint, 0, len(categoryIDs))
+	for _, id := range categoryIDs {
+		if id != 0 && !unique[id] {
+			unique[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var store models.Store
+	if err := tx.First(&store, storeID).Error; err != nil {
+		SafeRollback(tx)
+		if gorm.IsRecordNotFoundError(err) {
+			return errors.New("商品不存在")
+		}
+		return err
+	}
+
+	if len(ids) > 0 {
+		var count int
+		if err := tx.Model(&models.Category{}).Where("id IN (?)", ids).Count(&count).Error; err != nil {
+			SafeRollback(tx)
+			return err
+		}
+		if count != len(ids) {
+			SafeRollback(tx)
+			return errors.New("部分分类不存在")
+		}
+	}
+
+	if err := tx.Where("store_id = ?", storeID).Delete(models.StoreCategory{}).Error; err != nil {
+		SafeRollback(tx)
+		return err
+	}
+	for _, id := range ids {
+		if err := tx.Create(&models.StoreCategory{StoreID: storeID, CategoryID: id}).Error; err != nil {
+			SafeRollback(tx)
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	invalidateStoreCatalog()
+	return nil
+}
+
+// MigrateTagCategories 为预置Tag和商品已使用的Tag创建同名根分类，并将商品关联到其Tag对应的分类。
+// 返回新建的分类数量和新建的关联数量
+func (s *categoryService) MigrateTagCategories() (int, int64, error) {
+	tags := []string{
+		string(models.TagNormal),
+		string(models.TagClothes),
+		string(models.TagWeapon),
+		string(models.TagArtifact),
+		string(models.TagConsumable),
+	}
+	var used []string
+	if err := config.Database.Model(&models.Store{}).Where("tag <> ''").Pluck("DISTINCT tag", &used).Error; err != nil {
+		return 0, 0, err
+	}
+	tags = append(tags, used...)
+
+	created := 0
+	for i, tag := range tags {
+		var count int
+		if err := config.Database.Model(&models.Category{}).Where("code = ?", tag).Count(&count).Error; err != nil {
+			return created, 0, err
+		}
+		if count > 0 {
+			continue
+		}
+		category := &models.Category{Code: tag, Name: tag, SortOrder: i, Status: 1, Path: "/"}
+		if err := config.Database.Create(category).Error; err != nil {
+			return created, 0, err
+		}
+		created++
+	}
+
+	result := config.Database.Exec("INSERT IGNORE INTO store_categories (store_id, category_id, created_at) " +
+		"SELECT stores.id, categories.id, NOW() FROM stores JOIN categories ON categories.code = stores.tag WHERE stores.deleted_at IS NULL")
+	if result.Error != nil {
+		return created, 0, result.Error
+	}
+
+	invalidateStoreCatalog()
+	return created, result.RowsAffected, nil
+}
+
+// linkTagCategory 将新建商品关联到其Tag对应的分类
+func linkTagCategory(tx *gorm.DB, store *models.Store) error {
+	var category models.Category
+	if err := tx.Where("code = ?", store.Tag).First(&category).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+	return tx.Create(&models.StoreCategory{StoreID: store.ID, CategoryID: category.ID}).Error
+}
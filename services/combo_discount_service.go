@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"goDDD1/config"
+	"goDDD1/models"
+)
+
+// ComboDiscountService 组合优惠服务接口
+type ComboDiscountService interface {
+	// 创建组合优惠
+	CreateCombo(combo *models.ComboDiscount) error
+	// 更新组合优惠，商品列表整体替换
+	UpdateCombo(combo *models.ComboDiscount) error
+	// 获取组合优惠列表
+	ListCombos(onlyActive bool) ([]*models.ComboDiscount, error)
+}
+
+// comboDiscountService 组合优惠服务实现
+type comboDiscountService struct{}
+
+// NewComboDiscountService 创建组合优惠服务实例
+func NewComboDiscountService() ComboDiscountService {
+	return &comboDiscountService{}
+}
+
+// CreateCombo 创建组合优惠
+func (s *comboDiscountService) CreateCombo(combo *models.ComboDiscount) error {
+	if err := validateCombo(combo); err != nil {
+		return err
+	}
+	combo.ID = 0
+	for i := range combo.Items {
+		combo.Items[i].ID = 0
+	}
+	return config.Database.Create(combo).Error
+}
+
+// UpdateCombo 更新组合优惠
+func (s *comboDiscountService) UpdateCombo(combo *models.ComboDiscount) error {
+	if err := validateCombo(combo); err != nil {
+		return err
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var existing models.ComboDiscount
+	if err := tx.First(&existing, combo.ID).Error; err != nil {
+		SafeRollback(tx)
+		return errors.New("组合优惠不存在")
+	}
+
+	if err := tx.Where("combo_discount_id = ?", combo.ID).Delete(&models.ComboDiscountItem{}).Error; err != nil {
+		SafeRollback(tx)
+		return err
+	}
+	for i := range combo.Items {
+		combo.Items[i].ID = 0
+		combo.Items[i].ComboDiscountID = combo.ID
+	}
+	combo.CreatedAt = existing.CreatedAt
+	if err := tx.Save(combo).Error; err != nil {
+		SafeRollback(tx)
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// ListCombos 获取组合优惠列表
+func (s *comboDiscountService) ListCombos(onlyActive bool) ([]*models.ComboDiscount, error) {
+	var combos []*models.ComboDiscount
+	query := config.Database.Preload("Items").Order("id desc")
+	if onlyActive {
+		query = query.Where("status = 1")
+	}
+	if err := query.Find(&combos).Error; err != nil {
+		return nil, err
+	}
+	return combos, nil
+}
+
+// validateCombo 校验组合优惠配置
+func validateCombo(combo *models.ComboDiscount) error {
+	if combo.Name == "" {
+		return errors.New("组合优惠名称不能为空")
+	}
+	if combo.DiscountPercent == 0 || combo.DiscountPercent > 100 {
+		return errors.New("优惠百分比必须在1到100之间")
+	}
+	if combo.StartAt != nil && combo.EndAt != nil && !combo.EndAt.After(*combo.StartAt) {
+		return errors.New("结束时间必须晚于开始时间")
+	}
+	if len(combo.Items) < 2 {
+		return errors.New("组合优惠至少需要两个商品")
+	}
+
+	seen := make(map[uint]bool, len(combo.Items))
+	for _, item := range combo.Items {
+		if item.StoreID == 0 || item.Quantity <= 0 {
+			return errors.New("组合商品和数量不能为空")
+		}
+		if seen[item.StoreID] {
+			return errors.New("组合商品不能重复")
+		}
+		seen[item.StoreID] = true
+	}
+
+	var count int
+	if err := config.Database.Model(&models.Store{}).Where("id IN (?)", storeIDsOf(combo.Items)).Count(&count).Error; err != nil {
+		return err
+	}
+	if count != len(combo.Items) {
+		return errors.New("组合商品不存在")
+	}
+	return nil
+}
+
+// storeIDsOf 获取组合中全部商品ID
+func storeIDsOf(items []models.ComboDiscountItem) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.StoreID)
+	}
+	return ids
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/utils"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	couponCodeLength      = 12    // 批量生成的兑换码长度
+	couponMaxGenerateOnce = 10000 // 单次最多生成的兑换码数量
+)
+
+// CouponService 优惠券服务接口
+type CouponService interface {
+	// 创建优惠券活动
+	CreateCampaign(campaign *models.CouponCampaign) error
+	// 分页查询优惠券活动
+	ListCampaigns(page, pageSize int) ([]*models.CouponCampaign, int64, error)
+	// 为活动生成兑换码：公共兑换码活动生成一个不限次数的码（可指定码），一次性兑换码活动批量生成count个码
+	GenerateCodes(campaignID uint, count int, code string) ([]*models.CouponCode, error)
+	// 分页查询活动的兑换码
+	ListCodes(campaignID uint, page, pageSize int) ([]*models.CouponCode, int64, error)
+	// 玩家使用兑换码领取优惠券
+	ClaimCoupon(userID uint, code string) (*models.UserCoupon, error)
+	// 分页查询玩家的优惠券，status为空时返回全部
+	GetUserCoupons(userID uint, status models.UserCouponStatus, page, pageSize int) ([]*models.UserCoupon, int64, error)
+}
+
+// couponService 优惠券服务实现
+type couponService struct{}
+
+// NewCouponService 创建优惠券服务实例
+func NewCouponService() CouponService {
+	return &couponService{}
+}
+
+// CreateCampaign 创建优惠券活动
+func (s *couponService) CreateCampaign(campaign *models.CouponCampaign) error {
+	if err := validateCampaign(campaign); err != nil {
+		return err
+	}
+	campaign.ID = 0
+	campaign.ClaimedCount = 0
+	campaign.RedeemedCount = 0
+	return config.Database.Create(campaign).Error
+}
+
+// ListCampaigns 分页查询优惠券活动
+func (s *couponService) ListCampaigns(page, pageSize int) ([]*models.CouponCampaign, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	var total int64
+	if err := config.Database.Model(&models.CouponCampaign{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var campaigns []*models.CouponCampaign
+	if err := config.Database.Offset((page - 1) * pageSize).Limit(pageSize).Order("id desc").Find(&campaigns).Error; err != nil {
+		return nil, 0, err
+	}
+	return campaigns, total, nil
+}
+
+// GenerateCodes 生成兑换码
+func (s *couponService) GenerateCodes(campaignID uint, count int, code string) ([]*models.CouponCode, error) {
+	var campaign models.CouponCampaign
+	if err := config.Database.First(&campaign, campaignID).Error; err != nil {
+		return nil, errors.New("优惠券活动不存在")
+	}
+
+	if campaign.CodeType == models.CouponCodeShared {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" {
+			code = utils.GenerateCouponCode(couponCodeLength)
+		}
+		if len(code) > 32 {
+			return nil, errors.New("兑换码长度不能超过32")
+		}
+		couponCode := &models.CouponCode{CampaignID: campaign.ID, Code: code, MaxClaims: 0, Status: 1}
+		if err := config.Database.Create(couponCode).Error; err != nil {
+			if isDuplicateKeyError(err) {
+				return nil, errors.New("兑换码已存在")
+			}
+			return nil, err
+		}
+		return []*models.CouponCode{couponCode}, nil
+	}
+
+	if count <= 0 || count > couponMaxGenerateOnce {
+		return nil, fmt.Errorf("生成数量必须在1到%d之间", couponMaxGenerateOnce)
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	codes := make([]*models.CouponCode, 0, count)
+	for len(codes) < count {
+		couponCode := &models.CouponCode{
+			CampaignID: campaign.ID,
+			Code:       utils.GenerateCouponCode(couponCodeLength),
+			MaxClaims:  1,
+			Status:     1,
+		}
+		if err := tx.Create(couponCode).Error; err != nil {
+			if isDuplicateKeyError(err) {
+				// 随机码重复时重新生成
+				continue
+			}
+			SafeRollback(tx)
+			return nil, err
+		}
+		codes = append(codes, couponCode)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ListCodes 分页查询活动的兑换码
+func (s *couponService) ListCodes(campaignID uint, page, pageSize int) ([]*models.CouponCode, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := config.Database.Model(&models.CouponCode{}).Where("campaign_id = ?", campaignID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var codes []*models.CouponCode
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order("id asc").Find(&codes).Error; err != nil {
+		return nil, 0, err
+	}
+	return codes, total, nil
+}
+
+// ClaimCoupon 使用兑换码领取优惠券，在一个事务中检查兑换码、活动总量和玩家领取上限
+func (s *couponService) ClaimCoupon(userID uint, code string) (*models.UserCoupon, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, errors.New("兑换码不能为空")
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	//1、锁定兑换码和活动，同一活动的领取串行执行
+	var couponCode models.CouponCode
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("code = ?", code).First(&couponCode).Error; err != nil {
+		SafeRollback(tx)
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("兑换码不存在")
+		}
+		return nil, err
+	}
+	if couponCode.Status != 1 {
+		SafeRollback(tx)
+		return nil, errors.New("兑换码已作废")
+	}
+	if couponCode.MaxClaims > 0 && couponCode.ClaimedCount >= couponCode.MaxClaims {
+		SafeRollback(tx)
+		return nil, errors.New("兑换码已被使用")
+	}
+
+	var campaign models.CouponCampaign
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&campaign, couponCode.CampaignID).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if !campaign.IsActive(time.Now()) {
+		SafeRollback(tx)
+		return nil, errors.New("优惠券活动未开始或已结束")
+	}
+	if campaign.TotalLimit > 0 && campaign.ClaimedCount >= campaign.TotalLimit {
+		SafeRollback(tx)
+		return nil, errors.New("优惠券已被领完")
+	}
+
+	//2、检查玩家领取上限
+	var claimed int64
+	if err := tx.Model(&models.UserCoupon{}).Where("user_id = ? AND campaign_id = ?", userID, campaign.ID).Count(&claimed).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if campaign.PerUserLimit > 0 && claimed >= campaign.PerUserLimit {
+		SafeRollback(tx)
+		return nil, errors.New("已达到该优惠券的领取上限")
+	}
+
+	//3、发放优惠券并累加领取数量
+	coupon := &models.UserCoupon{
+		UserID:     userID,
+		CampaignID: campaign.ID,
+		CodeID:     couponCode.ID,
+		Code:       couponCode.Code,
+		Status:     models.UserCouponUnused,
+	}
+	if err := tx.Create(coupon).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := tx.Model(&couponCode).Update("claimed_count", gorm.Expr("claimed_count + 1")).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := tx.Model(&campaign).Update("claimed_count", gorm.Expr("claimed_count + 1")).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	coupon.Campaign = campaign
+	return coupon, nil
+}
+
+// GetUserCoupons 分页查询玩家的优惠券
+func (s *couponService) GetUserCoupons(userID uint, status models.UserCouponStatus, page, pageSize int) ([]*models.UserCoupon, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := config.Database.Model(&models.UserCoupon{}).Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var coupons []*models.UserCoupon
+	if err := query.Preload("Campaign").Offset((page - 1) * pageSize).Limit(pageSize).Order("id desc").Find(&coupons).Error; err != nil {
+		return nil, 0, err
+	}
+	return coupons, total, nil
+}
+
+// loadUserCoupon 加载玩家未使用的优惠券及其活动，db设置了FOR UPDATE时只锁定优惠券本身
+func loadUserCoupon(db *gorm.DB, userID uint, userCouponID uint) (*models.UserCoupon, error) {
+	var coupon models.UserCoupon
+	if err := db.Where("id = ? AND user_id = ?", userCouponID, userID).First(&coupon).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, fmt.Errorf("%w：优惠券不存在", ErrCouponNotApplicable)
+		}
+		return nil, err
+	}
+	if coupon.Status != models.UserCouponUnused {
+		return nil, fmt.Errorf("%w：优惠券已使用", ErrCouponNotApplicable)
+	}
+
+	if err := db.Set("gorm:query_option", "").First(&coupon.Campaign, coupon.CampaignID).Error; err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// redeemUserCoupon 在下单事务中核销优惠券
+func redeemUserCoupon(tx *gorm.DB, coupon *models.UserCoupon, orderID uint, now time.Time) error {
+	result := tx.Model(&models.UserCoupon{}).
+		Where("id = ? AND status = ?", coupon.ID, models.UserCouponUnused).
+		Updates(map[string]interface{}{
+			"status":   models.UserCouponUsed,
+			"order_id": orderID,
+			"used_at":  now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w：优惠券已使用", ErrCouponNotApplicable)
+	}
+
+	return tx.Model(&models.CouponCampaign{}).Where("id = ?", coupon.CampaignID).
+		Update("redeemed_count", gorm.Expr("redeemed_count + 1")).Error
+}
+
+// validateCampaign 校验优惠券活动配置
+func validateCampaign(campaign *models.CouponCampaign) error {
+	if campaign.Name == "" {
+		return errors.New("活动名称不能为空")
+	}
+
+	switch campaign.DiscountType {
+	case models.CouponDiscountFixed:
+		if campaign.DiscountValue <= 0 {
+			return errors.New("优惠金额必须大于0")
+		}
+	case models.CouponDiscountPercent:
+		if campaign.DiscountValue <= 0 || campaign.DiscountValue > 100 {
+			return errors.New("优惠百分比必须在1到100之间")
+		}
+	default:
+		return errors.New("discount_type必须是fixed或percent")
+	}
+
+	if campaign.CostType != "" && campaign.CostType != string(models.CostTypeCoin) && campaign.CostType != string(models.CostTypeDiamond) {
+		return errors.New("cost_type必须是coin或diamond")
+	}
+	// 金额类的配置只有在限定货币时才有意义
+	if campaign.CostType == "" && (campaign.DiscountType == models.CouponDiscountFixed || campaign.MinSpend > 0 || campaign.MaxDiscount > 0) {
+		return errors.New("固定金额优惠、最低消费和最高减免需要指定cost_type")
+	}
+	if campaign.MinSpend < 0 || campaign.MaxDiscount < 0 || campaign.TotalLimit < 0 || campaign.PerUserLimit < 0 {
+		return errors.New("金额和数量配置不能为负数")
+	}
+
+	if campaign.CodeType != models.CouponCodeShared && campaign.CodeType != models.CouponCodeUnique {
+		return errors.New("code_type必须是shared或unique")
+	}
+	if campaign.StartAt != nil && campaign.EndAt != nil && !campaign.EndAt.After(*campaign.StartAt) {
+		return errors.New("结束时间必须晚于开始时间")
+	}
+
+	for _, tag := range campaign.TagList() {
+		var count int
+		if err := config.Database.Model(&models.Category{}).Where("code = ?", tag).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("商品标签%s不存在", tag)
+		}
+	}
+	return nil
+}
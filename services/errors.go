@@ -0,0 +1,21 @@
+package services
+
+import (
+	"errors"
+)
+
+// 业务错误定义，控制器根据这些错误返回不同的错误码
+var (
+	ErrInsufficientFunds      = errors.New("钱包余额不足")
+	ErrInsufficientStock      = errors.New("库存不足")
+	ErrConcurrentModification = errors.New("数据已被其他请求修改，请稍后重试")
+	ErrItemsConsumed          = errors.New("背包中的物品已被使用，无法全部退款")
+	ErrSpendLimitExceeded     = errors.New("超出消费限额")
+	ErrPurchaseLimitReached   = errors.New("已达到购买数量上限")
+	ErrCouponNotApplicable    = errors.New("优惠券不可用")
+	ErrInvalidCursor          = errors.New("无效的分页游标")
+	ErrCategoryNotFound       = errors.New("分类不存在")
+	ErrLevelRequired          = errors.New("等级不足")
+	ErrVipRequired            = errors.New("VIP等级不足")
+	ErrPrerequisiteRequired   = errors.New("未拥有前置商品")
+)
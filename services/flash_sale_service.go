@@ -0,0 +1,652 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/utils"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jinzhu/gorm"
+)
+
+// flashSaleDeductScript 原子地检查并扣减秒杀库存和用户已购数量，已购数量与库存同时过期
+// 返回值：1 成功，0 库存不足，-1 库存未加载，-2 超出每人限购数量
+var flashSaleDeductScript = redis.NewScript(`
+local stock = redis.call('GET', KEYS[1])
+if not stock then
+	return -1
+end
+local quantity = tonumber(ARGV[2])
+if tonumber(stock) < quantity then
+	return 0
+end
+local bought = tonumber(redis.call('HGET', KEYS[2], ARGV[1]) or '0')
+if bought + quantity > tonumber(ARGV[3]) then
+	return -2
+end
+redis.call('DECRBY', KEYS[1], quantity)
+redis.call('HINCRBY', KEYS[2], ARGV[1], quantity)
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return 1
+`)
+
+// flashSaleCompensateScript 退回秒杀库存和用户已购数量，库存已被结算删除时不做任何操作
+var flashSaleCompensateScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('INCRBY', KEYS[1], ARGV[2])
+	redis.call('HINCRBY', KEYS[2], ARGV[1], -tonumber(ARGV[2]))
+	local ttl = redis.call('PTTL', KEYS[1])
+	if ttl > 0 then
+		redis.call('PEXPIRE', KEYS[2], ttl)
+	end
+	return 1
+end
+return 0
+`)
+
+// FlashSaleService 秒杀服务接口
+type FlashSaleService interface {
+	// 创建秒杀活动，从商品库存中划出秒杀库存并加载到Redis
+	CreateFlashSale(sale *models.FlashSale) error
+	// 取消未结束的秒杀活动，剩余库存在结算时退回商品
+	CancelFlashSale(saleID uint) error
+	// 获取秒杀活动列表
+	ListFlashSales(onlyOpen bool) ([]*models.FlashSale, error)
+	// 参与秒杀，在Redis中扣减库存后进入写入队列，返回请求号供客户端轮询结果
+	Purchase(userID uint, saleID uint, quantity int64) (*models.FlashSaleResult, error)
+	// 查询秒杀请求的处理结果
+	GetResult(userID uint, requestNo string) (*models.FlashSaleResult, error)
+	// 处理队列中的一条秒杀请求，写入订单，失败时退回Redis库存
+	ProcessRequest(request *models.FlashSaleRequest) error
+	// 结算已结束的秒杀活动，将未售出的库存退回商品
+	SettleEnded() (int, error)
+}
+
+// flashSaleService 秒杀服务实现
+type flashSaleService struct {
+	levelService      LevelService
+	spendLimitService SpendLimitService
+}
+
+// NewFlashSaleService 创建秒杀服务实例
+func NewFlashSaleService() FlashSaleService {
+	return &flashSaleService{
+		levelService:      NewLevelService(),
+		spendLimitService: NewSpendLimitService(),
+	}
+}
+
+// CreateFlashSale 创建秒杀活动
+func (s *flashSaleService) CreateFlashSale(sale *models.FlashSale) error {
+	if sale.Name == "" {
+		return errors.New("秒杀名称不能为空")
+	}
+	if sale.Price <= 0 || sale.TotalStock <= 0 || sale.PerUserLimit <= 0 {
+		return errors.New("秒杀价格、库存和每人限购数量必须大于0")
+	}
+	if !sale.EndAt.After(sale.StartAt) || !sale.EndAt.After(time.Now()) {
+		return errors.New("秒杀结束时间必须晚于开始时间和当前时间")
+	}
+
+	err := withOptimisticRetry(func() error {
+		tx := config.Database.Begin()
+		defer func() {
+			if r := recover(); r != nil {
+				SafeRollback(tx)
+			}
+		}()
+
+		var store models.Store
+		if err := tx.Where("id = ? AND status = 1", sale.StoreID).First(&store).Error; err != nil {
+			SafeRollback(tx)
+			return errors.New("商品不存在或已下架")
+		}
+		if store.StoreType == models.StoreTypeBundle {
+			SafeRollback(tx)
+			return errors.New("礼包商品不支持秒杀")
+		}
+		if sale.Price >= store.Price {
+			SafeRollback(tx)
+			return errors.New("秒杀价必须低于商品原价")
+		}
+
+		// 秒杀库存从商品库存中划出，下单时不再修改商品库存
+		if err := updateStoreStockWithVersion(tx, &store, -sale.TotalStock); err != nil {
+			SafeRollback(tx)
+			return err
+		}
+
+		sale.ID = 0
+		sale.SoldCount = 0
+		sale.Status = models.FlashSaleStatusActive
+		if err := tx.Create(sale).Error; err != nil {
+			SafeRollback(tx)
+			return err
+		}
+
+		return tx.Commit().Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.loadStock(sale); err != nil {
+		// 库存未加载时会在用户下单时重新加载
+		log.Printf("加载秒杀%d库存失败: %v", sale.ID, err)
+	}
+	return nil
+}
+
+// CancelFlashSale 取消秒杀活动，立即停止下单
+func (s *flashSaleService) CancelFlashSale(saleID uint) error {
+	result := config.Database.Model(&models.FlashSale{}).
+		Where("id = ? AND status = ?", saleID, models.FlashSaleStatusActive).
+		Updates(map[string]interface{}{
+			"status": models.FlashSaleStatusCanceled,
+			"end_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("秒杀活动不存在或已结束")
+	}
+
+	// 清除库存，使后续请求无法再扣减，剩余库存在结算时退回商品
+	s.clearStock(saleID)
+	return nil
+}
+
+// ListFlashSales 获取秒杀活动列表
+func (s *flashSaleService) ListFlashSales(onlyOpen bool) ([]*models.FlashSale, error) {
+	var sales []*models.FlashSale
+	query := config.Database.Order("start_at asc, id asc")
+	if onlyOpen {
+		query = query.Where("status = ? AND end_at > ?", models.FlashSaleStatusActive, time.Now())
+	}
+	if err := query.Find(&sales).Error; err != nil {
+		return nil, err
+	}
+	return sales, nil
+}
+
+// Purchase 参与秒杀，库存和限购检查全部在Redis中完成，不访问商品表
+func (s *flashSaleService) Purchase(userID uint, saleID uint, quantity int64) (*models.FlashSaleResult, error) {
+	if quantity <= 0 {
+		return nil, errors.New("购买数量必须大于0")
+	}
+
+	var sale models.FlashSale
+	if err := config.Database.First(&sale, saleID).Error; err != nil {
+		return nil, errors.New("秒杀活动不存在")
+	}
+	if !sale.IsOpen(time.Now()) {
+		return nil, errors.New("秒杀活动未开始或已结束")
+	}
+	if quantity > sale.PerUserLimit {
+		return nil, ErrPurchaseLimitReached
+	}
+
+	// 秒杀商品同样需要满足购买条件，在扣减Redis库存前预先检查，写入订单时在事务中再次检查并累加限购数量
+	var store models.Store
+	if err := config.Database.First(&store, sale.StoreID).Error; err != nil {
+		return nil, errors.New("商品不存在")
+	}
+	if store.HasRequirements() {
+		var user models.User
+		if err := config.Database.Where("uid = ?", userID).First(&user).Error; err != nil {
+			return nil, err
+		}
+		if err := checkStoreRequirements(config.Database, &user, []*models.Store{&store}, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	code, err := s.deduct(&sale, userID, quantity)
+	if err == nil && code == -1 {
+		// 库存未加载（如Redis重启），重新加载后再试一次
+		if err = s.reloadStock(sale.ID); err == nil {
+			code, err = s.deduct(&sale, userID, quantity)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch code {
+	case 0, -1:
+		return nil, ErrInsufficientStock
+	case -2:
+		return nil, ErrPurchaseLimitReached
+	}
+
+	request := &models.FlashSaleRequest{
+		RequestNo:   utils.GenerateOrderNo("FS"),
+		FlashSaleID: sale.ID,
+		UserID:      userID,
+		Quantity:    quantity,
+		CreatedAt:   time.Now(),
+	}
+	result := &models.FlashSaleResult{
+		RequestNo: request.RequestNo,
+		UserID:    userID,
+		Status:    models.FlashSaleOrderPending,
+	}
+	s.saveResult(result)
+
+	if err := utils.PushQueue(models.CacheKeyFlashSaleQueue, request); err != nil {
+		s.compensate(request)
+		_ = utils.DeleteCache(fmt.Sprintf(models.CacheKeyFlashSaleResult, request.RequestNo))
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetResult 查询秒杀结果，优先读取Redis，过期后从数据库查询
+func (s *flashSaleService) GetResult(userID uint, requestNo string) (*models.FlashSaleResult, error) {
+	var result models.FlashSaleResult
+	if err := utils.GetCache(fmt.Sprintf(models.CacheKeyFlashSaleResult, requestNo), &result); err == nil && result.UserID == userID {
+		return &result, nil
+	}
+
+	var order models.FlashSaleOrder
+	if err := config.Database.Where("request_no = ? AND user_id = ?", requestNo, userID).First(&order).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("秒杀请求不存在")
+		}
+		return nil, err
+	}
+	return &models.FlashSaleResult{
+		RequestNo: order.RequestNo,
+		UserID:    order.UserID,
+		Status:    order.Status,
+		OrderNo:   order.OrderNo,
+		Reason:    order.Reason,
+	}, nil
+}
+
+// ProcessRequest 处理一条秒杀请求，同一请求号只处理一次
+func (s *flashSaleService) ProcessRequest(request *models.FlashSaleRequest) error {
+	var count int
+	if err := config.Database.Model(&models.FlashSaleOrder{}).Where("request_no = ?", request.RequestNo).Count(&count).Error; err != nil {
+		s.fail(request, err)
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var order *models.Order
+	var sale *models.FlashSale
+	err := withOptimisticRetry(func() error {
+		var writeErr error
+		order, sale, writeErr = s.writeOrder(request)
+		return writeErr
+	})
+	if err != nil {
+		s.fail(request, err)
+		return err
+	}
+
+	// 删除缓存记录
+	cacheKey := fmt.Sprintf(models.CacheKeyUserBackpack, request.UserID)
+	if err := utils.DelHashField(cacheKey, "data"); err == nil {
+		log.Printf("successful delete cacheKey: %s backpack", cacheKey)
+	}
+	clearWalletCache(request.UserID)
+
+	s.saveResult(&models.FlashSaleResult{
+		RequestNo: request.RequestNo,
+		UserID:    request.UserID,
+		Status:    models.FlashSaleOrderSuccess,
+		OrderNo:   order.OrderNo,
+	})
+	log.Printf("秒杀%d请求%s下单成功，订单号：%s", sale.ID, request.RequestNo, order.OrderNo)
+	return nil
+}
+
+// fail 写入失败，退回Redis库存并记录失败结果
+func (s *flashSaleService) fail(request *models.FlashSaleRequest, cause error) {
+	// 从处理中队列恢复的请求可能已由其他协程写入，此时不能再退回库存
+	var count int
+	if err := config.Database.Model(&models.FlashSaleOrder{}).Where("request_no = ?", request.RequestNo).Count(&count).Error; err == nil && count > 0 {
+		return
+	}
+
+	s.compensate(request)
+	failed := &models.FlashSaleOrder{
+		RequestNo:   request.RequestNo,
+		FlashSaleID: request.FlashSaleID,
+		UserID:      request.UserID,
+		Quantity:    request.Quantity,
+		Status:      models.FlashSaleOrderFailed,
+		Reason:      cause.Error(),
+	}
+	if err := config.Database.Create(failed).Error; err != nil {
+		log.Printf("记录秒杀请求%s失败结果出错: %v", request.RequestNo, err)
+	}
+	s.saveResult(&models.FlashSaleResult{
+		RequestNo: request.RequestNo,
+		UserID:    request.UserID,
+		Status:    models.FlashSaleOrderFailed,
+		Reason:    cause.Error(),
+	})
+}
+
+// writeOrder 在一个事务中扣款并生成订单，库存已在创建秒杀时划出，不修改商品库存
+func (s *flashSaleService) writeOrder(request *models.FlashSaleRequest) (*models.Order, *models.FlashSale, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	// 锁定秒杀活动，与结算互斥，已结算的活动库存已退回商品，不能再下单
+	var sale models.FlashSale
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&sale, request.FlashSaleID).Error; err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+	if sale.Status == models.FlashSaleStatusSettled {
+		SafeRollback(tx)
+		return nil, nil, errors.New("秒杀活动已结束")
+	}
+	var store models.Store
+	if err := tx.First(&store, sale.StoreID).Error; err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+
+	//1、与普通购买相同，检查购买条件并累加商品的限购数量
+	now := time.Now()
+	var user models.User
+	if err := tx.Where("uid = ?", request.UserID).First(&user).Error; err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+	if err := checkStoreRequirements(tx, &user, []*models.Store{&store}, now); err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+	if err := consumePurchaseLimit(tx, &user, &store, request.Quantity, now); err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+
+	//2、检查余额和消费限额
+	amount := sale.Price * request.Quantity
+	var wallet models.UserWallet
+	if err := tx.Where("user_id = ? and type = ?", request.UserID, store.CostType).First(&wallet).Error; err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+	if wallet.Num < amount {
+		SafeRollback(tx)
+		return nil, nil, ErrInsufficientFunds
+	}
+	// 预占消费额度，事务未提交时释放
+	reservation, err := s.spendLimitService.ReserveSpend(request.UserID, string(store.CostType), amount)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			s.spendLimitService.ReleaseSpend(reservation)
+		}
+	}()
+
+	//3、生成订单，秒杀价与原价的差额记为优惠
+	original := store.Price * request.Quantity
+	order := &models.Order{
+		OrderNo:        utils.GenerateOrderNo("SO"),
+		UserID:         request.UserID,
+		CostType:       string(store.CostType),
+		OriginalPrice:  original,
+		DiscountAmount: original - amount,
+		FinalPrice:     amount,
+		Status:         models.OrderStatusPaid,
+		PaidAt:         &now,
+		Items: []models.OrderItem{{
+			StoreID:        store.ID,
+			StoreName:      store.Name,
+			CostType:       string(store.CostType),
+			UnitPrice:      store.Price,
+			Quantity:       request.Quantity,
+			OriginalPrice:  original,
+			DiscountAmount: original - amount,
+			FinalPrice:     amount,
+		}},
+	}
+	if err := tx.Create(order).Error; err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+	if order.DiscountAmount > 0 {
+		discount := models.OrderDiscount{
+			OrderID:     order.ID,
+			OrderItemID: order.Items[0].ID,
+			Type:        models.PriceAdjustFlash,
+			Name:        sale.Name,
+			CostType:    string(store.CostType),
+			Amount:      order.DiscountAmount,
+		}
+		if err := tx.Create(&discount).Error; err != nil {
+			SafeRollback(tx)
+			return nil, nil, err
+		}
+		order.Discounts = append(order.Discounts, discount)
+	}
+
+	//4、扣减余额，记录交易流水，增加背包和经验值
+	if err := debitOrderPayment(tx, &wallet, order.ID, amount); err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+	if err := deliverPurchase(tx, s.levelService, order, &store, request.Quantity, amount); err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+
+	//5、记录秒杀结果并累加已售数量
+	if err := tx.Create(&models.FlashSaleOrder{
+		RequestNo:   request.RequestNo,
+		FlashSaleID: sale.ID,
+		UserID:      request.UserID,
+		Quantity:    request.Quantity,
+		Amount:      amount,
+		Status:      models.FlashSaleOrderSuccess,
+		OrderID:     order.ID,
+		OrderNo:     order.OrderNo,
+	}).Error; err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+	if err := tx.Model(&models.FlashSale{}).Where("id = ?", sale.ID).
+		Update("sold_count", gorm.Expr("sold_count + ?", request.Quantity)).Error; err != nil {
+		SafeRollback(tx)
+		return nil, nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, nil, err
+	}
+	committed = true
+
+	order.FillTotals()
+	return order, &sale, nil
+}
+
+// SettleEnded 结算结束超过等待时间的秒杀活动，将Redis中剩余的库存退回商品
+func (s *flashSaleService) SettleEnded() (int, error) {
+	deadline := time.Now().Add(-config.GetFlashSaleConfig().SettleDelay)
+
+	var sales []*models.FlashSale
+	if err := config.Database.
+		Where("status IN (?) AND end_at <= ?", []models.FlashSaleStatus{models.FlashSaleStatusActive, models.FlashSaleStatusCanceled}, deadline).
+		Find(&sales).Error; err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, sale := range sales {
+		if err := s.settle(sale); err != nil {
+			log.Printf("结算秒杀%d失败: %v", sale.ID, err)
+			continue
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// settle 结算一个秒杀活动，此时队列中的请求已处理完毕，以数据库中的已售数量为准退回剩余库存
+func (s *flashSaleService) settle(sale *models.FlashSale) error {
+	err := withOptimisticRetry(func() error {
+		tx := config.Database.Begin()
+		defer func() {
+			if r := recover(); r != nil {
+				SafeRollback(tx)
+			}
+		}()
+
+		var current models.FlashSale
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&current, sale.ID).Error; err != nil {
+			SafeRollback(tx)
+			return err
+		}
+		if current.Status == models.FlashSaleStatusSettled {
+			// 已被其他实例结算
+			SafeRollback(tx)
+			return nil
+		}
+
+		if remaining := current.TotalStock - current.SoldCount; remaining > 0 {
+			var store models.Store
+			if err := tx.First(&store, current.StoreID).Error; err != nil {
+				SafeRollback(tx)
+				return err
+			}
+			if err := updateStoreStockWithVersion(tx, &store, remaining); err != nil {
+				SafeRollback(tx)
+				return err
+			}
+		}
+
+		if err := tx.Model(&current).Update("status", models.FlashSaleStatusSettled).Error; err != nil {
+			SafeRollback(tx)
+			return err
+		}
+		return tx.Commit().Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.clearStock(sale.ID)
+	return nil
+}
+
+// clearStock 删除Redis中的秒杀库存和已购数量，之后的请求无法再扣减库存
+func (s *flashSaleService) clearStock(saleID uint) {
+	for _, key := range []string{
+		fmt.Sprintf(models.CacheKeyFlashSaleStock, saleID),
+		fmt.Sprintf(models.CacheKeyFlashSaleBought, saleID),
+	} {
+		if err := utils.DeleteCache(key); err != nil {
+			log.Printf("删除秒杀%d缓存失败: %v", saleID, err)
+		}
+	}
+}
+
+// reloadStock 库存键丢失时重新加载，已结算或已取消的活动库存已删除，不能重新加载
+func (s *flashSaleService) reloadStock(saleID uint) error {
+	var sale models.FlashSale
+	if err := config.Database.First(&sale, saleID).Error; err != nil {
+		return err
+	}
+	if sale.Status != models.FlashSaleStatusActive {
+		return nil
+	}
+	if err := s.loadStock(&sale); err != nil {
+		return err
+	}
+
+	// 加载期间活动被结算或取消时删除刚加载的库存
+	if err := config.Database.First(&sale, saleID).Error; err != nil {
+		return err
+	}
+	if sale.Status != models.FlashSaleStatusActive {
+		s.clearStock(saleID)
+	}
+	return nil
+}
+
+// loadStock 将秒杀库存和用户已购数量加载到Redis，已存在时不覆盖
+func (s *flashSaleService) loadStock(sale *models.FlashSale) error {
+	expiration := time.Until(sale.EndAt) + config.GetFlashSaleConfig().SettleDelay + 24*time.Hour
+
+	var orders []*models.FlashSaleOrder
+	if err := config.Database.Where("flash_sale_id = ? AND status = ?", sale.ID, models.FlashSaleOrderSuccess).Find(&orders).Error; err != nil {
+		return err
+	}
+	boughtKey := fmt.Sprintf(models.CacheKeyFlashSaleBought, sale.ID)
+	exists, err := utils.ExistsCache(boughtKey)
+	if err != nil {
+		return err
+	}
+	if !exists && len(orders) > 0 {
+		bought := make(map[uint]int64)
+		for _, order := range orders {
+			bought[order.UserID] += order.Quantity
+		}
+		for userID, quantity := range bought {
+			if _, err := utils.IncrHashField(boughtKey, strconv.FormatUint(uint64(userID), 10), quantity, expiration); err != nil {
+				return err
+			}
+		}
+	}
+
+	return utils.InitCounter(fmt.Sprintf(models.CacheKeyFlashSaleStock, sale.ID), sale.TotalStock-sale.SoldCount, expiration)
+}
+
+// deduct 执行Redis库存扣减脚本
+func (s *flashSaleService) deduct(sale *models.FlashSale, userID uint, quantity int64) (int64, error) {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+	keys := []string{
+		fmt.Sprintf(models.CacheKeyFlashSaleStock, sale.ID),
+		fmt.Sprintf(models.CacheKeyFlashSaleBought, sale.ID),
+	}
+	return flashSaleDeductScript.Run(ctx, rdb, keys, userID, quantity, sale.PerUserLimit).Int64()
+}
+
+// compensate 退回请求占用的Redis库存和用户已购数量，活动已结算时库存已按数据库退回商品，无需处理
+func (s *flashSaleService) compensate(request *models.FlashSaleRequest) {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+	keys := []string{
+		fmt.Sprintf(models.CacheKeyFlashSaleStock, request.FlashSaleID),
+		fmt.Sprintf(models.CacheKeyFlashSaleBought, request.FlashSaleID),
+	}
+	if err := flashSaleCompensateScript.Run(ctx, rdb, keys, request.UserID, request.Quantity).Err(); err != nil {
+		log.Printf("退回秒杀请求%s的库存失败: %v", request.RequestNo, err)
+	}
+}
+
+// saveResult 保存秒杀结果供客户端轮询
+func (s *flashSaleService) saveResult(result *models.FlashSaleResult) {
+	key := fmt.Sprintf(models.CacheKeyFlashSaleResult, result.RequestNo)
+	if err := utils.SetCache(key, result, config.GetFlashSaleConfig().ResultTTL); err != nil {
+		log.Printf("保存秒杀请求%s结果失败: %v", result.RequestNo, err)
+	}
+}
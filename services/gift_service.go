@@ -0,0 +1,227 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/utils"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jinzhu/gorm"
+)
+
+// giftMessageMaxLength 礼物留言的最大字数，与gifts.message字段长度一致
+const giftMessageMaxLength = 200
+
+// GiftRequest 购买商品赠送给其他玩家的参数
+type GiftRequest struct {
+	RecipientID uint
+	Message     string
+}
+
+// GiftService 礼物服务接口
+type GiftService interface {
+	// 分页获取送出或收到的礼物
+	GetUserGifts(userID uint, box models.GiftBox, page, pageSize int) ([]*models.Gift, int64, error)
+	// 接收方接受礼物，物品放入接收方背包
+	AcceptGift(userID uint, giftNo string) (*models.Gift, error)
+	// 接收方拒绝礼物，物品退回赠送方背包
+	DeclineGift(userID uint, giftNo string) (*models.Gift, error)
+}
+
+type giftService struct{}
+
+// NewGiftService 创建礼物服务实例
+func NewGiftService() GiftService {
+	return &giftService{}
+}
+
+// GetUserGifts 分页获取玩家送出或收到的礼物
+func (s *giftService) GetUserGifts(userID uint, box models.GiftBox, page, pageSize int) ([]*models.Gift, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	db := config.Database.Model(&models.Gift{})
+	if box == models.GiftBoxSent {
+		db = db.Where("sender_id = ?", userID)
+	} else {
+		db = db.Where("recipient_id = ?", userID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var gifts []*models.Gift
+	if err := db.Order("id desc").Offset(offset).Limit(pageSize).Find(&gifts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return gifts, total, nil
+}
+
+// AcceptGift 接受礼物
+func (s *giftService) AcceptGift(userID uint, giftNo string) (*models.Gift, error) {
+	return s.handleGift(userID, giftNo, models.GiftStatusAccepted)
+}
+
+// DeclineGift 拒绝礼物
+func (s *giftService) DeclineGift(userID uint, giftNo string) (*models.Gift, error) {
+	return s.handleGift(userID, giftNo, models.GiftStatusDeclined)
+}
+
+// handleGift 处理待接受的礼物：接受时物品放入接收方背包，拒绝时退回赠送方背包，并通知赠送方
+func (s *giftService) handleGift(userID uint, giftNo string, status models.GiftStatus) (*models.Gift, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var gift models.Gift
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("gift_no = ? AND recipient_id = ?", giftNo, userID).First(&gift).Error; err != nil {
+		SafeRollback(tx)
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("礼物不存在")
+		}
+		return nil, err
+	}
+	if gift.Status != models.GiftStatusPending {
+		SafeRollback(tx)
+		return nil, errors.New("礼物已处理")
+	}
+
+	owner := gift.RecipientID
+	notificationType, title := models.NotificationGiftAccepted, "礼物已被接受"
+	if status == models.GiftStatusDeclined {
+		owner = gift.SenderID
+		notificationType, title = models.NotificationGiftDeclined, "礼物被拒绝"
+	}
+	if err := addBackpackItem(tx, owner, gift.StoreID, gift.Quantity); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	now := time.Now()
+	gift.Status = status
+	gift.HandledAt = &now
+	if err := tx.Save(&gift).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	content := fmt.Sprintf("您送给玩家%d的%s x%d已被接受", gift.RecipientID, gift.StoreName, gift.Quantity)
+	if status == models.GiftStatusDeclined {
+		content = fmt.Sprintf("您送给玩家%d的%s x%d被拒绝，物品已退回您的背包", gift.RecipientID, gift.StoreName, gift.Quantity)
+	}
+	if err := createNotification(tx, gift.SenderID, notificationType, title, content, gift.StoreID); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	clearBackpackCache(owner)
+	return &gift, nil
+}
+
+// prepareGift 校验赠送请求：只能赠送一种礼物类型的商品，不能送给自己，并检查赠送方当日赠送次数。
+// 调用前需要锁定赠送方用户记录，保证并发赠送时次数校验准确。返回接收方用户
+func prepareGift(tx *gorm.DB, sender *models.User, request *GiftRequest, items []*PricingLine) (*models.User, error) {
+	if len(items) != 1 {
+		return nil, errors.New("每次只能赠送一种商品")
+	}
+	if items[0].Store.StoreType != models.StoreTypeGift {
+		return nil, errors.New("该商品不能赠送")
+	}
+	if request.RecipientID == sender.UID {
+		return nil, errors.New("不能赠送给自己")
+	}
+	if utf8.RuneCountInString(request.Message) > giftMessageMaxLength {
+		return nil, fmt.Errorf("留言不能超过%d个字", giftMessageMaxLength)
+	}
+
+	var recipient models.User
+	if err := tx.Where("uid = ? AND is_deleted = ?", request.RecipientID, "0").First(&recipient).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("接收方用户不存在")
+		}
+		return nil, err
+	}
+
+	if dailyCount := config.GetGiftConfig().DailyCount; dailyCount > 0 {
+		now := time.Now()
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		var count int
+		if err := tx.Model(&models.Gift{}).Where("sender_id = ? AND created_at >= ?", sender.UID, startOfDay).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count >= dailyCount {
+			return nil, fmt.Errorf("每日最多赠送%d次", dailyCount)
+		}
+	}
+	return &recipient, nil
+}
+
+// createGift 为赠送订单创建礼物记录并通知双方，配置为自动接受时直接放入接收方背包
+func createGift(tx *gorm.DB, order *models.Order, request *GiftRequest, item *PricingLine, now time.Time) (*models.Gift, error) {
+	gift := &models.Gift{
+		GiftNo:      utils.GenerateOrderNo("GF"),
+		OrderID:     order.ID,
+		SenderID:    order.UserID,
+		RecipientID: request.RecipientID,
+		StoreID:     item.Store.ID,
+		StoreName:   item.Store.Name,
+		Quantity:    item.Quantity,
+		Message:     request.Message,
+		Status:      models.GiftStatusPending,
+	}
+	if config.GetGiftConfig().AutoAccept {
+		if err := addBackpackItem(tx, gift.RecipientID, gift.StoreID, gift.Quantity); err != nil {
+			return nil, err
+		}
+		gift.Status = models.GiftStatusAccepted
+		gift.HandledAt = &now
+	}
+	if err := tx.Create(gift).Error; err != nil {
+		return nil, err
+	}
+
+	sentContent := fmt.Sprintf("您已将%s x%d赠送给玩家%d", gift.StoreName, gift.Quantity, gift.RecipientID)
+	if err := createNotification(tx, gift.SenderID, models.NotificationGiftSent, "礼物已送出", sentContent, gift.StoreID); err != nil {
+		return nil, err
+	}
+	receivedContent := fmt.Sprintf("玩家%d送给您%s x%d", gift.SenderID, gift.StoreName, gift.Quantity)
+	if gift.Message != "" {
+		receivedContent += "，留言：" + gift.Message
+	}
+	if gift.Status == models.GiftStatusPending {
+		receivedContent += "，请前往礼物列表接受"
+	}
+	if err := createNotification(tx, gift.RecipientID, models.NotificationGiftReceived, "收到礼物", receivedContent, gift.StoreID); err != nil {
+		return nil, err
+	}
+	return gift, nil
+}
+
+// clearBackpackCache 删除用户背包缓存
+func clearBackpackCache(userID uint) {
+	cacheKey := fmt.Sprintf(models.CacheKeyUserBackpack, userID)
+	if err := utils.DelHashField(cacheKey, "data"); err == nil {
+		log.Printf("successful delete cacheKey: %s backpack", cacheKey)
+	}
+}
@@ -13,6 +13,8 @@ type LevelService interface {
 	GetUserLevel(userID uint) (*models.User, error)
 	// 增加用户经验值，检查是否升级
 	AddExpeirence(tx *gorm.DB, userID uint, exp uint, description string) (*models.LevelHistory, error)
+	// 扣减用户经验值，policy决定经验不足当前等级时是否降级
+	DeductExperience(tx *gorm.DB, userID uint, exp uint, policy string, description string) (*models.LevelHistory, error)
 	// 获取用户等级历史记录
 	GetLevelHistory(userID uint) ([]*models.LevelHistory, error)
 	// 获取等级配置
@@ -70,6 +72,10 @@ func (s *levelService) AddExpeirence(tx *gorm.DB, userID uint, exp uint, descrip
 	var totalCoinReward uint = 0
 	var totalDiamondReward uint = 0
 	var newLevel = user.Level
+	rewardedLevel := user.RewardedLevel
+	if rewardedLevel < user.Level {
+		rewardedLevel = user.Level
+	}
 
 	// 检查是否升级，处理可能的多级跳升
 	for level := user.Level + 1; level <= maxLevel; level++ {
@@ -81,8 +87,11 @@ func (s *levelService) AddExpeirence(tx *gorm.DB, userID uint, exp uint, descrip
 		// 如果经验值达到要求，则升级并累加奖励
 		if user.Experience >= config.RequiredExp {
 			newLevel = level
-			totalCoinReward += config.CoinReward
-			totalDiamondReward += config.DiamondReward
+			// 每个等级的升级奖励只发放一次，降级后再次升级不重复发放
+			if level > rewardedLevel {
+				totalCoinReward += config.CoinReward
+				totalDiamondReward += config.DiamondReward
+			}
 		} else {
 			// 经验不足以升到下一级，终止检查
 			break
@@ -92,17 +101,20 @@ func (s *levelService) AddExpeirence(tx *gorm.DB, userID uint, exp uint, descrip
 	// 如果有升级，更新用户等级并发放奖励
 	if newLevel > user.Level {
 		user.Level = newLevel
+		if newLevel > rewardedLevel {
+			user.RewardedLevel = newLevel
+		}
 
 		// 发放金币奖励
 		if totalCoinReward > 0 {
-			if err := s.userWalletService.UpdateWalletBalanceWithTx(tx, userID, "coin", int64(totalCoinReward), fmt.Sprintf("升级奖励%s", user.Level)); err != nil {
+			if err := s.userWalletService.UpdateWalletBalanceWithTx(tx, userID, "coin", int64(totalCoinReward), fmt.Sprintf("升级奖励%d", user.Level)); err != nil {
 				return nil, err
 			}
 		}
 
 		// 发放钻石奖励
 		if totalDiamondReward > 0 {
-			if err := s.userWalletService.UpdateWalletBalanceWithTx(tx, userID, "diamond", int64(totalDiamondReward), fmt.Sprintf("升级奖励%s", user.Level)); err != nil {
+			if err := s.userWalletService.UpdateWalletBalanceWithTx(tx, userID, "diamond", int64(totalDiamondReward), fmt.Sprintf("升级奖励%d", user.Level)); err != nil {
 				return nil, err
 			}
 		}
@@ -132,6 +144,60 @@ func (s *levelService) AddExpeirence(tx *gorm.DB, userID uint, exp uint, descrip
 	return history, nil
 }
 
+// DeductExperience 扣减经验值，不在内部提交事务。
+// 降级时已发放的升级奖励不会扣回，但会记录已发放奖励的最高等级，再次升级到同一等级时不会重复发放。
+func (s *levelService) DeductExperience(tx *gorm.DB, userID uint, exp uint, policy string, description string) (*models.LevelHistory, error) {
+	var user models.User
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("uid=?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	oldLevel := user.Level
+	if exp > user.Experience {
+		exp = user.Experience
+	}
+	user.Experience -= exp
+
+	if policy == config.RefundLevelDowngrade {
+		var levelConfigs []models.LevelConfig
+		if err := tx.Where("level <= ?", user.Level).Order("level asc").Find(&levelConfigs).Error; err != nil {
+			return nil, err
+		}
+
+		// 取经验值满足要求的最高等级，最低为1级
+		var newLevel uint = 1
+		for _, levelConfig := range levelConfigs {
+			if user.Experience >= levelConfig.RequiredExp && levelConfig.Level > newLevel {
+				newLevel = levelConfig.Level
+			}
+		}
+		if newLevel < user.Level {
+			if user.RewardedLevel < user.Level {
+				user.RewardedLevel = user.Level
+			}
+			user.Level = newLevel
+		}
+	}
+
+	if err := tx.Save(&user).Error; err != nil {
+		return nil, err
+	}
+
+	history := &models.LevelHistory{
+		UserID:      userID,
+		OldLevel:    oldLevel,
+		NewLevel:    user.Level,
+		ExpDeducted: exp,
+		Experience:  user.Experience,
+		Description: description,
+	}
+	if err := tx.Create(history).Error; err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
 // GetLevelConfig 获取等级配置
 func (s *levelService) GetLevelConfig(level uint) (*models.LevelConfig, error) {
 	var levelConfig models.LevelConfig
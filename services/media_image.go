@@ -0,0 +1,148 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// 支持上传的图片类型及对应的文件扩展名
+var imageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// processedImage 校验并生成缩略图后的图片
+type processedImage struct {
+	ContentType   string
+	Extension     string
+	Width, Height int
+	Thumbnail     []byte
+	ThumbnailType string
+	ThumbnailExt  string
+}
+
+// processImage 根据文件内容（而不是文件名或请求头）识别图片类型，校验像素数后解码并生成缩略图。
+// 缩略图等比缩放到最长边不超过thumbSize，JPEG图片的缩略图仍为JPEG，其他类型为PNG以保留透明度
+func processImage(data []byte, maxPixels int, thumbSize int) (*processedImage, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, errors.New("只支持JPEG、PNG和GIF格式的图片")
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("图片文件已损坏")
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, errors.New("图片尺寸无效")
+	}
+	if maxPixels > 0 && cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("图片像素数不能超过%d", maxPixels)
+	}
+
+	var src image.Image
+	switch contentType {
+	case "image/jpeg":
+		src, err = jpeg.Decode(bytes.NewReader(data))
+	case "image/png":
+		src, err = png.Decode(bytes.NewReader(data))
+	default:
+		src, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, errors.New("图片文件已损坏")
+	}
+
+	result := &processedImage{
+		ContentType:   contentType,
+		Extension:     ext,
+		Width:         cfg.Width,
+		Height:        cfg.Height,
+		ThumbnailType: "image/png",
+		ThumbnailExt:  "png",
+	}
+
+	thumb := resizeImage(src, thumbSize)
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		result.ThumbnailType, result.ThumbnailExt = "image/jpeg", "jpg"
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, thumb)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Thumbnail = buf.Bytes()
+	return result, nil
+}
+
+// resizeImage 将图片等比缩小到最长边不超过size，每个目标像素取原图对应区域的平均值；图片本身足够小时只转换格式不缩放
+func resizeImage(src image.Image, size int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := srcW, srcH
+	if size > 0 && (srcW > size || srcH > size) {
+		if srcW >= srcH {
+			dstW, dstH = size, srcH*size/srcW
+		} else {
+			dstW, dstH = srcW*size/srcH, size
+		}
+		if dstW < 1 {
+			dstW = 1
+		}
+		if dstH < 1 {
+			dstH = 1
+		}
+	}
+
+	rgba := image.NewNRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	if dstW == srcW && dstH == srcH {
+		return rgba
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, (y+1)*srcH/dstH
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, (x+1)*srcW/dstW
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			// 按透明度加权求平均，避免透明像素的颜色渗入边缘
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := rgba.NRGBAAt(sx, sy)
+					r += uint64(c.R) * uint64(c.A)
+					g += uint64(c.G) * uint64(c.A)
+					b += uint64(c.B) * uint64(c.A)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			pixel := color.NRGBA{A: uint8(a / n)}
+			if a > 0 {
+				pixel.R, pixel.G, pixel.B = uint8(r/a), uint8(g/a), uint8(b/a)
+			}
+			dst.SetNRGBA(x, y, pixel)
+		}
+	}
+	return dst
+}
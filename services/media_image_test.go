@@ -0,0 +1,53 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestProcessImage 测试图片类型识别、像素数限制和缩略图生成
+func TestProcessImage(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 600, 300))
+	for x := 0; x < 600; x++ {
+		for y := 0; y < 300; y++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, src))
+
+	img, err := processImage(buf.Bytes(), 0, 200)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+	assert.Equal(t, 600, img.Width)
+	assert.Equal(t, 300, img.Height)
+
+	thumb, err := png.Decode(bytes.NewReader(img.Thumbnail))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 200, 100), thumb.Bounds())
+	assert.Equal(t, color.NRGBA{R: 200, G: 100, B: 50, A: 255}, color.NRGBAModel.Convert(thumb.At(50, 50)))
+
+	_, err = processImage(buf.Bytes(), 1000, 200)
+	assert.Error(t, err)
+
+	_, err = processImage([]byte("<html>not an image</html>"), 0, 200)
+	assert.Error(t, err)
+}
+
+// TestLocalStoragePath 测试本地存储拒绝访问存储目录之外的路径
+func TestLocalStoragePath(t *testing.T) {
+	storage := &localStorage{root: "uploads", publicPath: "/media"}
+	for _, key := range []string{"", "/etc/passwd", "../secret", "store/../../secret", "store//a.png", `store\a.png`} {
+		_, err := storage.path(key)
+		assert.Error(t, err, key)
+	}
+
+	_, err := storage.path("store/ab/abc.png")
+	assert.NoError(t, err)
+	assert.Equal(t, "/media/store/ab/abc.png", storage.URL("store/ab/abc.png"))
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MediaService 商品图片服务接口
+type MediaService interface {
+	// 上传图片并生成缩略图，内容相同的图片直接返回已有记录
+	UploadImage(uploaderID uint, data []byte) (*models.MediaFile, error)
+	// 设置商品图片，mediaID为0表示移除图片
+	SetStoreImage(storeID uint, mediaID uint) (*models.Store, error)
+	// 读取存储中的文件
+	Open(key string) (*StorageObject, error)
+}
+
+type mediaService struct {
+	storage Storage
+}
+
+// NewMediaService 创建商品图片服务实例
+func NewMediaService() MediaService {
+	return &mediaService{
+		storage: NewStorage(),
+	}
+}
+
+// UploadImage 校验图片内容并生成缩略图，原图和缩略图以内容校验和命名保存，文件内容不会再变化，可以长期缓存
+func (s *mediaService) UploadImage(uploaderID uint, data []byte) (*models.MediaFile, error) {
+	cfg := config.GetMediaConfig()
+	if len(data) == 0 {
+		return nil, errors.New("图片文件为空")
+	}
+	if int64(len(data)) > cfg.MaxUploadSize {
+		return nil, fmt.Errorf("图片大小不能超过%dMB", cfg.MaxUploadSize>>20)
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	var existing models.MediaFile
+	err := config.Database.Where("checksum = ?", checksum).First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	img, err := processImage(data, cfg.MaxPixels, cfg.ThumbnailSize)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := fmt.Sprintf("store/%s/%s", checksum[:2], checksum)
+	media := &models.MediaFile{
+		Checksum:     checksum,
+		Key:          prefix + "." + img.Extension,
+		ThumbnailKey: prefix + "_thumb." + img.ThumbnailExt,
+		ContentType:  img.ContentType,
+		Size:         int64(len(data)),
+		Width:        img.Width,
+		Height:       img.Height,
+		UploaderID:   uploaderID,
+	}
+	media.URL = s.storage.URL(media.Key)
+	media.ThumbnailURL = s.storage.URL(media.ThumbnailKey)
+
+	if err := s.storage.Put(media.Key, data, img.ContentType); err != nil {
+		return nil, err
+	}
+	if err := s.storage.Put(media.ThumbnailKey, img.Thumbnail, img.ThumbnailType); err != nil {
+		return nil, err
+	}
+
+	if err := config.Database.Create(media).Error; err != nil {
+		// 同一张图片被并发上传，使用先保存的记录
+		if isDuplicateKeyError(err) {
+			if err := config.Database.Where("checksum = ?", checksum).First(&existing).Error; err != nil {
+				return nil, err
+			}
+			return &existing, nil
+		}
+		return nil, err
+	}
+	return media, nil
+}
+
+// SetStoreImage 设置商品图片并使商品列表缓存失效，原图片文件保留（可能被其他商品使用）
+func (s *mediaService) SetStoreImage(storeID uint, mediaID uint) (*models.Store, error) {
+	imageURL, thumbnailURL := "", ""
+	if mediaID != 0 {
+		var media models.MediaFile
+		if err := config.Database.First(&media, mediaID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return nil, errors.New("图片不存在")
+			}
+			return nil, err
+		}
+		imageURL, thumbnailURL = media.URL, media.ThumbnailURL
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var store models.Store
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&store, storeID).Error; err != nil {
+		SafeRollback(tx)
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("商品不存在")
+		}
+		return nil, err
+	}
+
+	store.ImageURL, store.ThumbnailURL = imageURL, thumbnailURL
+	store.Version++
+	if err := tx.Model(&store).UpdateColumns(map[string]interface{}{
+		"image_url":     store.ImageURL,
+		"thumbnail_url": store.ThumbnailURL,
+		"version":       store.Version,
+	}).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	invalidateStoreCatalog()
+	return &store, nil
+}
+
+// Open 读取存储中的文件
+func (s *mediaService) Open(key string) (*StorageObject, error) {
+	return s.storage.Open(key)
+}
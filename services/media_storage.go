@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"goDDD1/config"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrMediaNotFound 存储中不存在指定的文件
+var ErrMediaNotFound = errors.New("文件不存在")
+
+// StorageObject 从存储中读取的文件
+type StorageObject struct {
+	Body    io.ReadSeekCloser
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage 图片等媒体文件的存储接口，key为以/分隔的相对路径。
+// 目前只有本地文件系统实现，后续可以增加兼容S3的对象存储实现
+type Storage interface {
+	// 保存文件，已存在时覆盖
+	Put(key string, data []byte, contentType string) error
+	// 读取文件，不存在时返回ErrMediaNotFound
+	Open(key string) (*StorageObject, error)
+	// 删除文件，不存在时忽略
+	Delete(key string) error
+	// 文件的访问地址
+	URL(key string) string
+}
+
+// NewStorage 根据配置创建媒体存储
+func NewStorage() Storage {
+	cfg := config.GetMediaConfig()
+	return NewLocalStorage(cfg.LocalDir, cfg.PublicPath)
+}
+
+type localStorage struct {
+	root       string
+	publicPath string
+}
+
+// NewLocalStorage 创建本地文件系统存储，文件保存在root目录下，通过publicPath路径访问
+func NewLocalStorage(root string, publicPath string) Storage {
+	return &localStorage{root: root, publicPath: publicPath}
+}
+
+// Put 先写入临时文件再重命名，避免读取到写了一半的文件
+func (s *localStorage) Put(key string, data []byte, contentType string) error {
+	filename, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+func (s *localStorage) Open(key string) (*StorageObject, error) {
+	filename, err := s.path(key)
+	if err != nil {
+		return nil, ErrMediaNotFound
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrMediaNotFound
+		}
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, ErrMediaNotFound
+	}
+	return &StorageObject{Body: file, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (s *localStorage) Delete(key string) error {
+	filename, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *localStorage) URL(key string) string {
+	return s.publicPath + "/" + key
+}
+
+// path 将key转换为本地文件路径，拒绝绝对路径和包含..的路径，防止访问存储目录之外的文件
+func (s *localStorage) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return "", errors.New("无效的文件路径")
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goDDD1/models"
+	"net/http"
+)
+
+// MockPaymentProviderName 本地模拟支付渠道名称
+const MockPaymentProviderName = "mock"
+
+// mockSignatureHeader 模拟支付回调签名请求头
+const mockSignatureHeader = "X-Mock-Signature"
+
+// mockPaymentProvider 本地模拟支付渠道，使用HMAC-SHA256对回调内容签名
+type mockPaymentProvider struct {
+	secret []byte
+}
+
+// mockCallbackPayload 模拟支付回调内容
+type mockCallbackPayload struct {
+	OrderNo  string `json:"order_no"`
+	TradeNo  string `json:"trade_no"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Status   string `json:"status"` // success / failed
+}
+
+func newMockPaymentProvider(secret string) *mockPaymentProvider {
+	return &mockPaymentProvider{secret: []byte(secret)}
+}
+
+func (p *mockPaymentProvider) Name() string {
+	return MockPaymentProviderName
+}
+
+// CreatePayment 模拟渠道不需要真实下单，返回本地模拟支付地址
+func (p *mockPaymentProvider) CreatePayment(order *models.RechargeOrder) (*PaymentIntent, error) {
+	return &PaymentIntent{
+		Provider: p.Name(),
+		OrderNo:  order.OrderNo,
+		PayURL:   fmt.Sprintf("/api/admin/recharge/mock/pay?order_no=%s", order.OrderNo),
+	}, nil
+}
+
+// VerifyCallback 校验回调签名并解析通知内容
+func (p *mockPaymentProvider) VerifyCallback(header http.Header, body []byte) (*PaymentNotification, error) {
+	signature, err := hex.DecodeString(header.Get(mockSignatureHeader))
+	if err != nil || !hmac.Equal(signature, p.sign(body)) {
+		return nil, errors.New("回调签名校验失败")
+	}
+
+	var payload mockCallbackPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.New("回调内容格式错误")
+	}
+
+	return &PaymentNotification{
+		OrderNo:  payload.OrderNo,
+		TradeNo:  payload.TradeNo,
+		Amount:   payload.Amount,
+		Currency: payload.Currency,
+		Success:  payload.Status == "success",
+		Message:  payload.Status,
+	}, nil
+}
+
+// Refund 模拟渠道直接退款成功
+func (p *mockPaymentProvider) Refund(order *models.RechargeOrder, reason string) error {
+	return nil
+}
+
+// buildCallback 生成一个模拟支付成功的签名回调，用于本地联调
+func (p *mockPaymentProvider) buildCallback(order *models.RechargeOrder) (http.Header, []byte, error) {
+	body, err := json.Marshal(mockCallbackPayload{
+		OrderNo:  order.OrderNo,
+		TradeNo:  "MOCK" + order.OrderNo,
+		Amount:   order.Amount,
+		Currency: order.Currency,
+		Status:   "success",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set(mockSignatureHeader, hex.EncodeToString(p.sign(body)))
+	return header, body, nil
+}
+
+// sign 计算回调内容的HMAC-SHA256签名
+func (p *mockPaymentProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"goDDD1/config"
+	"goDDD1/models"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// NotificationService 站内通知服务接口
+type NotificationService interface {
+	// 分页获取通知，同时返回未读数量
+	GetUserNotifications(userID uint, unreadOnly bool, page, pageSize int) ([]*models.Notification, int64, int64, error)
+	// 将通知标记为已读，ids为空时标记全部
+	MarkRead(userID uint, ids []uint) (int64, error)
+}
+
+type notificationService struct{}
+
+// NewNotificationService 创建站内通知服务实例
+func NewNotificationService() NotificationService {
+	return &notificationService{}
+}
+
+// GetUserNotifications 分页获取玩家的通知，按时间倒序
+func (s *notificationService) GetUserNotifications(userID uint, unreadOnly bool, page, pageSize int) ([]*models.Notification, int64, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	var unread int64
+	if err := config.Database.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", userID, false).Count(&unread).Error; err != nil {
+		return nil, 0, 0, err
+	}
+
+	db := config.Database.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		db = db.Where("is_read = ?", false)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, 0, err
+	}
+
+	var notifications []*models.Notification
+	if err := db.Order("id desc").Offset(offset).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, 0, 0, err
+	}
+
+	return notifications, total, unread, nil
+}
+
+// MarkRead 将玩家的通知标记为已读，返回本次标记的数量
+func (s *notificationService) MarkRead(userID uint, ids []uint) (int64, error) {
+	db := config.Database.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", userID, false)
+	if len(ids) > 0 {
+		db = db.Where("id IN (?)", ids)
+	}
+	result := db.UpdateColumns(map[string]interface{}{
+		"is_read": true,
+		"read_at": time.Now(),
+	})
+	return result.RowsAffected, result.Error
+}
+
+// createNotification 写入一条站内通知
+func createNotification(tx *gorm.DB, userID uint, notificationType models.NotificationType, title string, content string, storeID uint) error {
+	return tx.Create(&models.Notification{
+		UserID:  userID,
+		Type:    notificationType,
+		Title:   title,
+		Content: content,
+		StoreID: storeID,
+	}).Error
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"goDDD1/models"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+)
+
+// maxOptimisticRetries 乐观锁冲突时整个事务的最大执行次数
+const maxOptimisticRetries = 3
+
+// mysqlCheckConstraintViolated MySQL CHECK约束校验失败的错误码
+const mysqlCheckConstraintViolated = 3819
+
+// mysqlDuplicateEntry MySQL唯一索引冲突的错误码
+const mysqlDuplicateEntry = 1062
+
+// mysqlDeadlock MySQL检测到死锁并回滚事务的错误码
+const mysqlDeadlock = 1213
+
+// withOptimisticRetry 执行fn，若返回版本冲突错误则退避后重新执行，最多执行maxOptimisticRetries次。
+// fn内部必须自行开启并提交/回滚事务，保证每次重试都读取到最新数据。
+func withOptimisticRetry(fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= maxOptimisticRetries; attempt++ {
+		err = fn()
+		if !errors.Is(err, ErrConcurrentModification) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt*10) * time.Millisecond)
+	}
+	return err
+}
+
+// updateWalletWithVersion 基于版本号条件更新钱包余额。
+// 余额不足返回ErrInsufficientFunds，版本不一致返回ErrConcurrentModification。
+func updateWalletWithVersion(tx *gorm.DB, wallet *models.UserWallet, amount int64) error {
+	if wallet.Num+amount < 0 {
+		return ErrInsufficientFunds
+	}
+
+	result := tx.Model(&models.UserWallet{}).
+		Where("id = ? AND version = ? AND num + ? >= 0", wallet.ID, wallet.Version, amount).
+		Updates(map[string]interface{}{
+			"num":     gorm.Expr("num + ?", amount),
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		if isCheckConstraintError(result.Error) {
+			return ErrInsufficientFunds
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConcurrentModification
+	}
+
+	wallet.Num += amount
+	wallet.Version++
+	return nil
+}
+
+// updateStoreStockWithVersion 基于版本号条件更新商品库存。
+// 库存不足返回ErrInsufficientStock，版本不一致返回ErrConcurrentModification。
+func updateStoreStockWithVersion(tx *gorm.DB, store *models.Store, amount int64) error {
+	if store.Stock+amount < 0 {
+		return ErrInsufficientStock
+	}
+
+	result := tx.Model(&models.Store{}).
+		Where("id = ? AND version = ? AND stock + ? >= 0", store.ID, store.Version, amount).
+		Updates(map[string]interface{}{
+			"stock":   gorm.Expr("stock + ?", amount),
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		if isCheckConstraintError(result.Error) {
+			return ErrInsufficientStock
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConcurrentModification
+	}
+
+	store.Stock += amount
+	store.Version++
+	return nil
+}
+
+// isCheckConstraintError 判断是否为数据库非负约束校验失败
+func isCheckConstraintError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlCheckConstraintViolated
+}
+
+// isDuplicateKeyError 判断是否为数据库唯一索引冲突
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+// isDeadlockError 判断是否为数据库死锁，此时整个事务已被回滚
+func isDeadlockError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDeadlock
+}
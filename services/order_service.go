@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"goDDD1/config"
+	"goDDD1/models"
+	"time"
+)
+
+// OrderFilter 商城订单查询条件
+type OrderFilter struct {
+	OrderNo   string
+	UserID    uint
+	StoreID   uint
+	Status    models.OrderStatus
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// OrderService 商城订单服务接口
+type OrderService interface {
+	// 根据订单号获取订单详情
+	GetOrderByNo(orderNo string) (*models.Order, error)
+	// 分页获取用户的订单
+	GetUserOrders(userID uint, page, pageSize int) ([]*models.Order, int64, error)
+	// 按条件分页查询订单
+	SearchOrders(filter *OrderFilter, page, pageSize int) ([]*models.Order, int64, error)
+}
+
+// orderService 商城订单服务实现
+type orderService struct{}
+
+// NewOrderService 创建商城订单服务实例
+func NewOrderService() OrderService {
+	return &orderService{}
+}
+
+// GetOrderByNo 根据订单号获取订单详情
+func (s *orderService) GetOrderByNo(orderNo string) (*models.Order, error) {
+	var order models.Order
+	if err := config.Database.Preload("Items").Preload("Discounts").Where("order_no = ?", orderNo).First(&order).Error; err != nil {
+		return nil, errors.New("订单不存在")
+	}
+	order.FillTotals()
+	return &order, nil
+}
+
+// GetUserOrders 分页获取用户的订单
+func (s *orderService) GetUserOrders(userID uint, page, pageSize int) ([]*models.Order, int64, error) {
+	return s.SearchOrders(&OrderFilter{UserID: userID}, page, pageSize)
+}
+
+// SearchOrders 按条件分页查询订单
+func (s *orderService) SearchOrders(filter *OrderFilter, page, pageSize int) ([]*models.Order, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := config.Database.Model(&models.Order{})
+	if filter.OrderNo != "" {
+		query = query.Where("order_no = ?", filter.OrderNo)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.StoreID != 0 {
+		query = query.Where("id IN (?)", config.Database.Model(&models.OrderItem{}).Select("order_id").Where("store_id = ?", filter.StoreID).SubQuery())
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("created_at >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("created_at < ?", *filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var orders []*models.Order
+	if err := query.Preload("Items").Preload("Discounts").Offset(offset).Limit(pageSize).Order("id desc").Find(&orders).Error; err != nil {
+		return nil, 0, err
+	}
+	for _, order := range orders {
+		order.FillTotals()
+	}
+
+	return orders, total, nil
+}
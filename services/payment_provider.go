@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"net/http"
+	"sync"
+)
+
+// PaymentIntent 支付渠道返回的支付信息，客户端据此拉起支付
+type PaymentIntent struct {
+	Provider string `json:"provider"` // 支付渠道
+	OrderNo  string `json:"order_no"` // 订单号
+	PayURL   string `json:"pay_url"`  // 支付地址
+}
+
+// PaymentNotification 支付渠道回调通知，签名校验通过后才会生成
+type PaymentNotification struct {
+	OrderNo  string `json:"order_no"` // 订单号
+	TradeNo  string `json:"trade_no"` // 渠道交易号
+	Amount   int64  `json:"amount"`   // 实际支付金额（分）
+	Currency string `json:"currency"` // 法币币种
+	Success  bool   `json:"success"`  // 是否支付成功
+	Message  string `json:"message"`  // 失败原因
+}
+
+// PaymentProvider 支付渠道接口，接入新的支付渠道只需实现该接口并注册
+type PaymentProvider interface {
+	// 渠道名称
+	Name() string
+	// 为订单创建支付
+	CreatePayment(order *models.RechargeOrder) (*PaymentIntent, error)
+	// 校验回调签名并解析通知内容
+	VerifyCallback(header http.Header, body []byte) (*PaymentNotification, error)
+	// 发起退款
+	Refund(order *models.RechargeOrder, reason string) error
+}
+
+// paymentProviders 已注册的支付渠道
+var (
+	paymentProviders    = map[string]PaymentProvider{}
+	paymentProvidersMu  sync.RWMutex
+	defaultProviderOnce sync.Once
+)
+
+// registerDefaultProviders 注册内置支付渠道，需在环境变量加载后调用
+func registerDefaultProviders() {
+	defaultProviderOnce.Do(func() {
+		paymentConfig := config.GetPaymentConfig()
+		if paymentConfig.MockEnabled {
+			RegisterPaymentProvider(newMockPaymentProvider(paymentConfig.MockSecret))
+		}
+	})
+}
+
+// RegisterPaymentProvider 注册支付渠道
+func RegisterPaymentProvider(provider PaymentProvider) {
+	paymentProvidersMu.Lock()
+	defer paymentProvidersMu.Unlock()
+	paymentProviders[provider.Name()] = provider
+}
+
+// GetPaymentProvider 根据名称获取支付渠道
+func GetPaymentProvider(name string) (PaymentProvider, error) {
+	registerDefaultProviders()
+
+	paymentProvidersMu.RLock()
+	defer paymentProvidersMu.RUnlock()
+	provider, ok := paymentProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的支付渠道: %s", name)
+	}
+	return provider, nil
+}
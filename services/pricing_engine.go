@@ -0,0 +1,323 @@
+package services
+
+import (
+	"fmt"
+	"goDDD1/models"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 价格规则的执行优先级，数值越小越先执行，后执行的规则在前面规则的结果上计算：
+// 1、促销价：商品处于促销期时按促销价计价
+// 2、组合优惠：同时购买指定的一组商品时按套数打折，每行商品只参与一个组合优惠
+// 3、等级折扣：按LevelConfig.DiscountPercent打折，不与促销价叠加
+// 4、优惠券：在以上优惠之后的价格上计算
+const (
+	pricePrioritySale   = 10
+	pricePriorityCombo  = 20
+	pricePriorityLevel  = 30
+	pricePriorityCoupon = 40
+)
+
+// PricingLine 计价过程中的一行商品
+type PricingLine struct {
+	Store       *models.Store
+	Quantity    int64
+	Original    int64 // 原价小计
+	Final       int64 // 当前价格小计，随规则执行不断减少
+	OnSale      bool  // 是否按促销价计价
+	InCombo     bool  // 是否已参与组合优惠
+	Adjustments []models.PriceAdjustment
+}
+
+// addDiscount 为该行增加一条优惠，优惠后价格不会低于0，返回实际生效的优惠金额
+func (l *PricingLine) addDiscount(adjustType string, name string, amount int64) int64 {
+	if amount > l.Final {
+		amount = l.Final
+	}
+	if amount <= 0 {
+		return 0
+	}
+	l.Final -= amount
+	l.Adjustments = append(l.Adjustments, models.PriceAdjustment{
+		Type:   adjustType,
+		Name:   name,
+		Amount: amount,
+	})
+	return amount
+}
+
+// PricingContext 一次计价的上下文
+type PricingContext struct {
+	DB     *gorm.DB // 当前事务或数据库连接
+	User   *models.User
+	Now    time.Time
+	Lines  []*PricingLine
+	Coupon *models.UserCoupon // 本次使用的优惠券，需预加载Campaign
+}
+
+// PriceRule 价格规则
+type PriceRule interface {
+	// 执行优先级，数值越小越先执行
+	Priority() int
+	// 在上下文中的商品行上应用优惠
+	Apply(ctx *PricingContext) error
+}
+
+// PricingEngine 价格引擎接口
+type PricingEngine interface {
+	// 按优先级依次执行价格规则，返回逐行的价格明细
+	Price(ctx *PricingContext) (*models.PriceQuote, error)
+}
+
+// pricingEngine 价格引擎实现
+type pricingEngine struct {
+	rules []PriceRule
+}
+
+// NewPricingEngine 创建价格引擎实例，包含全部默认价格规则
+func NewPricingEngine() PricingEngine {
+	return newPricingEngine(&salePriceRule{}, &comboDiscountRule{}, &levelDiscountRule{}, &couponRule{})
+}
+
+// newPricingEngine 使用指定的规则创建价格引擎
+func newPricingEngine(rules ...PriceRule) *pricingEngine {
+	sorted := make([]PriceRule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority() < sorted[j].Priority()
+	})
+	return &pricingEngine{rules: sorted}
+}
+
+// Price 计算价格明细
+func (e *pricingEngine) Price(ctx *PricingContext) (*models.PriceQuote, error) {
+	if ctx.Now.IsZero() {
+		ctx.Now = time.Now()
+	}
+
+	for _, line := range ctx.Lines {
+		line.Original = line.Store.Price * line.Quantity
+		line.Final = line.Original
+		line.OnSale = false
+		line.InCombo = false
+		line.Adjustments = make([]models.PriceAdjustment, 0)
+	}
+
+	for _, rule := range e.rules {
+		if err := rule.Apply(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	quote := &models.PriceQuote{
+		Lines: make([]*models.PriceQuoteLine, 0, len(ctx.Lines)),
+	}
+	order := &models.Order{}
+	for _, line := range ctx.Lines {
+		quote.Lines = append(quote.Lines, &models.PriceQuoteLine{
+			StoreID:        line.Store.ID,
+			Name:           line.Store.Name,
+			CostType:       string(line.Store.CostType),
+			UnitPrice:      line.Store.Price,
+			Quantity:       line.Quantity,
+			OriginalPrice:  line.Original,
+			DiscountAmount: line.Original - line.Final,
+			FinalPrice:     line.Final,
+			Adjustments:    line.Adjustments,
+		})
+		order.Items = append(order.Items, models.OrderItem{
+			CostType:       string(line.Store.CostType),
+			OriginalPrice:  line.Original,
+			DiscountAmount: line.Original - line.Final,
+			FinalPrice:     line.Final,
+		})
+	}
+	order.FillTotals()
+	quote.Totals = order.Totals
+
+	return quote, nil
+}
+
+// salePriceRule 促销价规则
+type salePriceRule struct{}
+
+func (r *salePriceRule) Priority() int {
+	return pricePrioritySale
+}
+
+func (r *salePriceRule) Apply(ctx *PricingContext) error {
+	for _, line := range ctx.Lines {
+		if !line.Store.IsOnSale(ctx.Now) {
+			continue
+		}
+		amount := (line.Store.Price - line.Store.SalePrice) * line.Quantity
+		if line.addDiscount(models.PriceAdjustSale, "限时促销", amount) > 0 {
+			line.OnSale = true
+		}
+	}
+	return nil
+}
+
+// comboDiscountRule 组合购买优惠规则
+type comboDiscountRule struct{}
+
+func (r *comboDiscountRule) Priority() int {
+	return pricePriorityCombo
+}
+
+func (r *comboDiscountRule) Apply(ctx *PricingContext) error {
+	if len(ctx.Lines) < 2 {
+		return nil
+	}
+
+	lineByStore := make(map[uint]*PricingLine, len(ctx.Lines))
+	storeIDs := make([]uint, 0, len(ctx.Lines))
+	for _, line := range ctx.Lines {
+		lineByStore[line.Store.ID] = line
+		storeIDs = append(storeIDs, line.Store.ID)
+	}
+
+	var comboIDs []uint
+	if err := ctx.DB.Model(&models.ComboDiscountItem{}).Where("store_id IN (?)", storeIDs).Pluck("DISTINCT combo_discount_id", &comboIDs).Error; err != nil {
+		return err
+	}
+	if len(comboIDs) == 0 {
+		return nil
+	}
+
+	// 优惠力度大的组合优先匹配
+	var combos []*models.ComboDiscount
+	if err := ctx.DB.Preload("Items").Where("id IN (?) AND status = 1", comboIDs).Order("discount_percent desc, id asc").Find(&combos).Error; err != nil {
+		return err
+	}
+
+	for _, combo := range combos {
+		if !combo.IsActive(ctx.Now) || len(combo.Items) == 0 || combo.DiscountPercent == 0 {
+			continue
+		}
+
+		// 计算可以组成的套数
+		var sets int64 = -1
+		for _, item := range combo.Items {
+			line, ok := lineByStore[item.StoreID]
+			if !ok || line.InCombo || item.Quantity <= 0 {
+				sets = 0
+				break
+			}
+			if count := line.Quantity / item.Quantity; sets < 0 || count < sets {
+				sets = count
+			}
+		}
+		if sets <= 0 {
+			continue
+		}
+
+		percent := int64(combo.DiscountPercent)
+		if percent > 100 {
+			percent = 100
+		}
+		for _, item := range combo.Items {
+			line := lineByStore[item.StoreID]
+			units := sets * item.Quantity
+			amount := line.Final * units / line.Quantity * percent / 100
+			line.addDiscount(models.PriceAdjustCombo, combo.Name, amount)
+			line.InCombo = true
+		}
+	}
+	return nil
+}
+
+// levelDiscountRule 等级折扣规则
+type levelDiscountRule struct{}
+
+func (r *levelDiscountRule) Priority() int {
+	return pricePriorityLevel
+}
+
+func (r *levelDiscountRule) Apply(ctx *PricingContext) error {
+	if ctx.User == nil {
+		return nil
+	}
+
+	var levelConfig models.LevelConfig
+	if err := ctx.DB.Where("level = ?", ctx.User.Level).First(&levelConfig).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+	if levelConfig.DiscountPercent >= 100 {
+		return nil
+	}
+
+	name := fmt.Sprintf("等级%d折扣", ctx.User.Level)
+	for _, line := range ctx.Lines {
+		if line.OnSale {
+			continue
+		}
+		discounted := line.Final * int64(levelConfig.DiscountPercent) / 100
+		line.addDiscount(models.PriceAdjustLevel, name, line.Final-discounted)
+	}
+	return nil
+}
+
+// couponRule 优惠券规则，优惠金额按适用商品的当前价格比例分摊到各行
+type couponRule struct{}
+
+func (r *couponRule) Priority() int {
+	return pricePriorityCoupon
+}
+
+func (r *couponRule) Apply(ctx *PricingContext) error {
+	if ctx.Coupon == nil {
+		return nil
+	}
+
+	campaign := &ctx.Coupon.Campaign
+	if !campaign.IsActive(ctx.Now) {
+		return fmt.Errorf("%w：优惠券未生效或已过期", ErrCouponNotApplicable)
+	}
+
+	eligible := make([]*PricingLine, 0, len(ctx.Lines))
+	var subtotal int64
+	for _, line := range ctx.Lines {
+		if line.Final > 0 && campaign.Applies(line.Store) {
+			eligible = append(eligible, line)
+			subtotal += line.Final
+		}
+	}
+	if len(eligible) == 0 {
+		return fmt.Errorf("%w：没有适用该优惠券的商品", ErrCouponNotApplicable)
+	}
+	if subtotal < campaign.MinSpend {
+		return fmt.Errorf("%w：适用商品未达到最低消费%d", ErrCouponNotApplicable, campaign.MinSpend)
+	}
+
+	var discount int64
+	switch campaign.DiscountType {
+	case models.CouponDiscountFixed:
+		discount = campaign.DiscountValue
+	case models.CouponDiscountPercent:
+		discount = subtotal * campaign.DiscountValue / 100
+		if campaign.MaxDiscount > 0 && discount > campaign.MaxDiscount {
+			discount = campaign.MaxDiscount
+		}
+	}
+	if discount > subtotal {
+		discount = subtotal
+	}
+
+	// 按比例分摊，最后一行补齐余数
+	var allocated int64
+	for i, line := range eligible {
+		share := discount * line.Final / subtotal
+		if i == len(eligible)-1 {
+			share = discount - allocated
+		}
+		allocated += line.addDiscount(models.PriceAdjustCoupon, campaign.Name, share)
+	}
+	return nil
+}
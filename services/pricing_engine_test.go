@@ -0,0 +1,115 @@
+package services
+
+import (
+	"goDDD1/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedDiscountRule 测试用规则：每行减免固定金额
+type fixedDiscountRule struct {
+	priority int
+	name     string
+	amount   int64
+}
+
+func (r *fixedDiscountRule) Priority() int {
+	return r.priority
+}
+
+func (r *fixedDiscountRule) Apply(ctx *PricingContext) error {
+	for _, line := range ctx.Lines {
+		line.addDiscount(models.PriceAdjustCoupon, r.name, r.amount)
+	}
+	return nil
+}
+
+// TestPricingEngineSaleAndOrder 测试促销价和规则执行顺序
+func TestPricingEngineSaleAndOrder(t *testing.T) {
+	now := time.Now()
+	end := now.Add(time.Hour)
+	onSale := &models.Store{ID: 1, Name: "sword", Price: 100, SalePrice: 80, SaleEndAt: &end, CostType: models.CostTypeCoin}
+	normal := &models.Store{ID: 2, Name: "shield", Price: 50, CostType: models.CostTypeCoin}
+
+	engine := newPricingEngine(
+		&fixedDiscountRule{priority: pricePriorityCoupon, name: "second", amount: 1000},
+		&salePriceRule{},
+		&fixedDiscountRule{priority: pricePriorityCombo, name: "first", amount: 10},
+	)
+	quote, err := engine.Price(&PricingContext{
+		Now: now,
+		Lines: []*PricingLine{
+			{Store: onSale, Quantity: 2},
+			{Store: normal, Quantity: 1},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, quote.Lines, 2)
+
+	// 促销价先生效，随后依次执行其他规则，优惠后价格不低于0
+	sword := quote.Lines[0]
+	assert.Equal(t, int64(200), sword.OriginalPrice)
+	assert.Equal(t, int64(0), sword.FinalPrice)
+	assert.Equal(t, []models.PriceAdjustment{
+		{Type: models.PriceAdjustSale, Name: "限时促销", Amount: 40},
+		{Type: models.PriceAdjustCoupon, Name: "first", Amount: 10},
+		{Type: models.PriceAdjustCoupon, Name: "second", Amount: 150},
+	}, sword.Adjustments)
+
+	shield := quote.Lines[1]
+	assert.Equal(t, int64(50), shield.DiscountAmount)
+	assert.Len(t, shield.Adjustments, 2)
+
+	assert.Len(t, quote.Totals, 1)
+	assert.Equal(t, int64(250), quote.Totals[0].OriginalPrice)
+	assert.Equal(t, int64(0), quote.Totals[0].FinalPrice)
+}
+
+// TestSalePriceRuleExpired 测试促销结束后按原价计价
+func TestSalePriceRuleExpired(t *testing.T) {
+	now := time.Now()
+	end := now.Add(-time.Minute)
+	store := &models.Store{ID: 1, Price: 100, SalePrice: 80, SaleEndAt: &end, CostType: models.CostTypeCoin}
+
+	quote, err := newPricingEngine(&salePriceRule{}).Price(&PricingContext{
+		Now:   now,
+		Lines: []*PricingLine{{Store: store, Quantity: 1}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), quote.Lines[0].FinalPrice)
+	assert.Empty(t, quote.Lines[0].Adjustments)
+}
+
+// TestCouponRuleAllocation 测试优惠券只作用于适用商品，并按比例分摊
+func TestCouponRuleAllocation(t *testing.T) {
+	sword := &models.Store{ID: 1, Price: 300, Tag: models.TagWeapon, CostType: models.CostTypeCoin}
+	shield := &models.Store{ID: 2, Price: 100, Tag: models.TagWeapon, CostType: models.CostTypeCoin}
+	hat := &models.Store{ID: 3, Price: 50, Tag: models.TagClothes, CostType: models.CostTypeCoin}
+	coupon := &models.UserCoupon{Campaign: models.CouponCampaign{
+		Name:          "weapon",
+		DiscountType:  models.CouponDiscountFixed,
+		DiscountValue: 101,
+		CostType:      string(models.CostTypeCoin),
+		Tags:          "Weapon",
+		MinSpend:      400,
+		Status:        1,
+	}}
+
+	quote, err := newPricingEngine(&couponRule{}).Price(&PricingContext{
+		Lines:  []*PricingLine{{Store: sword, Quantity: 1}, {Store: shield, Quantity: 1}, {Store: hat, Quantity: 1}},
+		Coupon: coupon,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(75), quote.Lines[0].DiscountAmount)
+	assert.Equal(t, int64(26), quote.Lines[1].DiscountAmount)
+	assert.Equal(t, int64(0), quote.Lines[2].DiscountAmount)
+
+	// 未达到最低消费时不可用
+	_, err = newPricingEngine(&couponRule{}).Price(&PricingContext{
+		Lines:  []*PricingLine{{Store: sword, Quantity: 1}},
+		Coupon: coupon,
+	})
+	assert.ErrorIs(t, err, ErrCouponNotApplicable)
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// purchaseLimitKey 计算限购周期标识，每日和每周按用户时区划分
+func purchaseLimitKey(period models.PurchaseLimitPeriod, now time.Time, loc *time.Location) string {
+	switch period {
+	case models.PurchaseLimitDaily:
+		start, _ := periodWindow(models.SpendPeriodDaily, now, loc)
+		return "d" + start.Format("20060102")
+	case models.PurchaseLimitWeekly:
+		start, _ := periodWindow(models.SpendPeriodWeekly, now, loc)
+		return "w" + start.Format("20060102")
+	default:
+		return string(models.PurchaseLimitLifetime)
+	}
+}
+
+// consumePurchaseLimit 在事务中检查并累加用户本周期的购买数量，超出限购时返回ErrPurchaseLimitReached
+func consumePurchaseLimit(tx *gorm.DB, user *models.User, store *models.Store, quantity int64, now time.Time) error {
+	if !store.HasPurchaseLimit() {
+		return nil
+	}
+
+	// 计数记录不存在时，并发的首次购买会同时持有间隙锁后插入，MySQL以死锁回滚其中一个事务；
+	// 死锁和唯一索引冲突都返回ErrConcurrentModification，由调用方重试整个事务
+	periodKey := purchaseLimitKey(store.LimitPeriod, now, timezoneLocation(user.Timezone))
+	var counter models.PurchaseCounter
+	err := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("user_id = ? AND store_id = ? AND period_key = ?", user.UID, store.ID, periodKey).
+		First(&counter).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		if isDeadlockError(err) {
+			return ErrConcurrentModification
+		}
+		return err
+	}
+
+	if counter.Count+quantity > store.LimitCount {
+		remaining := store.LimitCount - counter.Count
+		if remaining < 0 {
+			remaining = 0
+		}
+		return fmt.Errorf("%w：%s剩余可购买%d件", ErrPurchaseLimitReached, store.Name, remaining)
+	}
+
+	if counter.ID == 0 {
+		counter = models.PurchaseCounter{
+			UserID:    user.UID,
+			StoreID:   store.ID,
+			PeriodKey: periodKey,
+			Count:     quantity,
+		}
+		if err := tx.Create(&counter).Error; err != nil {
+			if isDuplicateKeyError(err) || isDeadlockError(err) {
+				return ErrConcurrentModification
+			}
+			return err
+		}
+		return nil
+	}
+
+	return tx.Model(&counter).Update("count", gorm.Expr("count + ?", quantity)).Error
+}
+
+// releasePurchaseLimit 退款时扣回购买所在周期的已购数量
+func releasePurchaseLimit(tx *gorm.DB, user *models.User, store *models.Store, quantity int64, purchasedAt time.Time) error {
+	if !store.HasPurchaseLimit() {
+		return nil
+	}
+
+	periodKey := purchaseLimitKey(store.LimitPeriod, purchasedAt, timezoneLocation(user.Timezone))
+	return tx.Model(&models.PurchaseCounter{}).
+		Where("user_id = ? AND store_id = ? AND period_key = ?", user.UID, store.ID, periodKey).
+		Update("count", gorm.Expr("GREATEST(count - ?, 0)", quantity)).Error
+}
+
+// FillRemainingPurchases 为限购商品填充用户本周期剩余可购买数量
+func (s *storeService) FillRemainingPurchases(userID uint, stores ...*models.StoreDTO) error {
+	limited := make([]*models.StoreDTO, 0)
+	for _, store := range stores {
+		if store != nil && store.LimitPeriod != models.PurchaseLimitNone && store.LimitCount > 0 {
+			limited = append(limited, store)
+		}
+	}
+	if len(limited) == 0 {
+		return nil
+	}
+
+	var user models.User
+	if err := config.Database.Select("uid, timezone").Where("uid = ?", userID).First(&user).Error; err != nil {
+		return err
+	}
+	loc := timezoneLocation(user.Timezone)
+	now := time.Now()
+
+	storeIDs := make([]uint, 0, len(limited))
+	periodKeys := make([]string, 0, len(limited))
+	for _, store := range limited {
+		storeIDs = append(storeIDs, store.ID)
+		periodKeys = append(periodKeys, purchaseLimitKey(store.LimitPeriod, now, loc))
+	}
+
+	var counters []*models.PurchaseCounter
+	if err := config.Database.
+		Where("user_id = ? AND store_id IN (?) AND period_key IN (?)", userID, storeIDs, periodKeys).
+		Find(&counters).Error; err != nil {
+		return err
+	}
+	bought := make(map[string]int64, len(counters))
+	for _, counter := range counters {
+		bought[fmt.Sprintf("%d:%s", counter.StoreID, counter.PeriodKey)] = counter.Count
+	}
+
+	for i, store := range limited {
+		remaining := store.LimitCount - bought[fmt.Sprintf("%d:%s", store.ID, periodKeys[i])]
+		if remaining < 0 {
+			remaining = 0
+		}
+		store.RemainingPurchases = &remaining
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/utils"
+	"log"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PurchaseRefundRequest 购买退款请求
+type PurchaseRefundRequest struct {
+	FlowID       uint   // 购买流水ID
+	Quantity     int64  // 退款数量，0表示退还剩余全部数量
+	AllowPartial bool   // 背包数量不足时是否按剩余数量部分退款
+	Reason       string // 退款原因
+	OperatorID   uint   // 操作人UID
+}
+
+// PurchaseRefundService 商城购买退款服务接口
+type PurchaseRefundService interface {
+	// 对一笔购买进行退款
+	RefundPurchase(request *PurchaseRefundRequest) (*models.PurchaseRefund, error)
+	// 分页查询退款记录
+	ListRefunds(userID uint, flowID uint, page, pageSize int) ([]*models.PurchaseRefund, int64, error)
+}
+
+// purchaseRefundService 商城购买退款服务实现
+type purchaseRefundService struct {
+	levelService      LevelService
+	rewardFlowService RewardFlowService
+	spendLimitService SpendLimitService
+}
+
+// NewPurchaseRefundService 创建商城购买退款服务实例
+func NewPurchaseRefundService() PurchaseRefundService {
+	return &purchaseRefundService{
+		levelService:      NewLevelService(),
+		rewardFlowService: NewRewardFlowService(),
+		spendLimitService: NewSpendLimitService(),
+	}
+}
+
+// refundedTotal 一笔购买已退款的累计数据
+type refundedTotal struct {
+	Quantity int64
+	Amount   int64
+	Exp      int64
+}
+
+// RefundPurchase 退款：退还货币、扣回背包物品、恢复库存、扣回经验并记录补偿流水，全部在一个事务中完成
+func (s *purchaseRefundService) RefundPurchase(request *PurchaseRefundRequest) (*models.PurchaseRefund, error) {
+	if request.Reason == "" {
+		return nil, errors.New("退款原因不能为空")
+	}
+	if request.Quantity < 0 {
+		return nil, errors.New("退款数量不能为负数")
+	}
+
+	var refund *models.PurchaseRefund
+	err := withOptimisticRetry(func() error {
+		var refundErr error
+		refund, refundErr = s.refundPurchase(request)
+		return refundErr
+	})
+	return refund, err
+}
+
+func (s *purchaseRefundService) refundPurchase(request *PurchaseRefundRequest) (*models.PurchaseRefund, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	//1、锁定购买流水，同一笔购买的退款串行执行
+	var flow models.UserCurrencyFlow
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&flow, request.FlowID).Error; err != nil {
+		SafeRollback(tx)
+		return nil, errors.New("购买记录不存在")
+	}
+	if flow.StoreID == 0 || flow.Price >= 0 || (flow.RefType != "" && flow.RefType != models.FlowRefPurchase) {
+		SafeRollback(tx)
+		return nil, errors.New("该流水不是商城购买记录")
+	}
+
+	var store models.Store
+	if err := tx.First(&store, flow.StoreID).Error; err != nil {
+		SafeRollback(tx)
+		return nil, errors.New("商品不存在")
+	}
+	if store.StoreType == models.StoreTypeBundle {
+		// 礼包内容已拆分发放到背包和钱包，无法按礼包扣回
+		SafeRollback(tx)
+		return nil, errors.New("礼包商品不支持退款")
+	}
+	if flow.RefID != 0 {
+		// 赠送的商品已归属接收方，不能由赠送方退款
+		var giftCount int
+		if err := tx.Model(&models.Gift{}).Where("order_id = ?", flow.RefID).Count(&giftCount).Error; err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+		if giftCount > 0 {
+			SafeRollback(tx)
+			return nil, errors.New("赠送的商品不支持退款")
+		}
+	}
+
+	paid := -flow.Price
+	purchased := flow.Quantity
+	if purchased == 0 {
+		// 早期的购买流水没有记录数量，按商品单价推算
+		if store.Price <= 0 || paid%store.Price != 0 {
+			SafeRollback(tx)
+			return nil, errors.New("无法确定该购买记录的商品数量")
+		}
+		purchased = paid / store.Price
+	}
+
+	//2、计算可退数量
+	var refunded refundedTotal
+	if err := tx.Model(&models.PurchaseRefund{}).
+		Select("COALESCE(SUM(quantity), 0) AS quantity, COALESCE(SUM(amount), 0) AS amount, COALESCE(SUM(exp_reverted), 0) AS exp").
+		Where("flow_id = ?", flow.ID).
+		Scan(&refunded).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	remaining := purchased - refunded.Quantity
+	if remaining <= 0 {
+		SafeRollback(tx)
+		return nil, errors.New("该购买已全部退款")
+	}
+
+	quantity := request.Quantity
+	if quantity == 0 {
+		quantity = remaining
+	}
+	if quantity > remaining {
+		SafeRollback(tx)
+		return nil, fmt.Errorf("退款数量超过可退数量%d", remaining)
+	}
+
+	//3、检查背包中的物品是否足够扣回
+	var bag models.Backpack
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("user_id = ? AND store_id = ?", flow.UserID, flow.StoreID).
+		First(&bag).Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if bag.Quantity < quantity {
+		if !request.AllowPartial || bag.Quantity <= 0 {
+			SafeRollback(tx)
+			return nil, ErrItemsConsumed
+		}
+		quantity = bag.Quantity
+	}
+
+	//4、按数量比例计算退还的货币和经验，最后一次退款补齐余数
+	totalExp := int64(purchaseExp(flow.CostType, paid))
+	amount := paid * quantity / purchased
+	exp := totalExp * quantity / purchased
+	if refunded.Quantity+quantity == purchased {
+		amount = paid - refunded.Amount
+		exp = totalExp - refunded.Exp
+	}
+
+	//5、扣回经验值
+	description := fmt.Sprintf("购买退款，商品:%s，数量:%d，原因:%s", store.Name, quantity, request.Reason)
+	history, err := s.levelService.DeductExperience(tx, flow.UserID, uint(exp), config.GetRefundConfig().LevelPolicy, description)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	refund := &models.PurchaseRefund{
+		FlowID:      flow.ID,
+		UserID:      flow.UserID,
+		StoreID:     flow.StoreID,
+		CostType:    flow.CostType,
+		Quantity:    quantity,
+		Amount:      amount,
+		ExpReverted: uint(exp),
+		OldLevel:    history.OldLevel,
+		NewLevel:    history.NewLevel,
+		Reason:      request.Reason,
+		OperatorID:  request.OperatorID,
+	}
+	if err := tx.Create(refund).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//6、退还货币并记录补偿流水，退还的货币保留付款时消耗的货币桶的过期时间
+	var wallet models.UserWallet
+	if err := tx.Where("user_id = ? AND type = ?", flow.UserID, flow.CostType).First(&wallet).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	var orderID uint
+	if flow.RefType == models.FlowRefPurchase {
+		orderID = flow.RefID
+	}
+	if err := restoreOrderPayment(tx, &wallet, orderID, amount, "购买退款"); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := tx.Create(&models.UserCurrencyFlow{
+		UserID:      flow.UserID,
+		StoreID:     flow.StoreID,
+		CostType:    flow.CostType,
+		Description: description,
+		Price:       amount,
+		Quantity:    quantity,
+		RefType:     models.FlowRefPurchaseRefund,
+		RefID:       refund.ID,
+	}).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//7、恢复库存并扣回销量
+	if err := updateStoreStockWithVersion(tx, &store, quantity); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := addStoreSales(tx, store.ID, -quantity); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//7.1、扣回购买所在周期的限购数量
+	var user models.User
+	if err := tx.Where("uid = ?", flow.UserID).First(&user).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := releasePurchaseLimit(tx, &user, &store, quantity, flow.Ctime); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//7.2、更新订单的退款状态
+	if flow.RefType == models.FlowRefPurchase && flow.RefID != 0 {
+		if err := s.markOrderRefunded(tx, flow.RefID, flow.StoreID, quantity, amount); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	//8、扣回背包物品
+	bag.Quantity -= quantity
+	if err := tx.Save(&bag).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := s.rewardFlowService.CreateRewardFlow(tx, flow.UserID, models.RewardTypeItem, flow.StoreID, -quantity, "购买退款"); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	// 删除缓存记录
+	cacheKey := fmt.Sprintf(models.CacheKeyUserBackpack, flow.UserID)
+	if err := utils.DelHashField(cacheKey, "data"); err == nil {
+		log.Printf("successful delete cacheKey: %s backpack", cacheKey)
+	}
+	clearWalletCache(flow.UserID)
+	s.spendLimitService.RefundSpend(flow.UserID, flow.CostType, amount, flow.Ctime)
+
+	return refund, nil
+}
+
+// markOrderRefunded 累加订单和订单商品的退款数据，并更新订单状态。
+// 按订单商品的退款数量判断是否全部退款，不比较金额：多种货币的订单金额不能相加，全额优惠的订单实付为0
+func (s *purchaseRefundService) markOrderRefunded(tx *gorm.DB, orderID uint, storeID uint, quantity int64, amount int64) error {
+	var order models.Order
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&order, orderID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+
+	if err := tx.Model(&models.OrderItem{}).
+		Where("order_id = ? AND store_id = ?", orderID, storeID).
+		Updates(map[string]interface{}{
+			"refunded_quantity": gorm.Expr("refunded_quantity + ?", quantity),
+			"refunded_amount":   gorm.Expr("refunded_amount + ?", amount),
+		}).Error; err != nil {
+		return err
+	}
+
+	var remaining int
+	if err := tx.Model(&models.OrderItem{}).
+		Where("order_id = ? AND refunded_quantity < quantity", orderID).
+		Count(&remaining).Error; err != nil {
+		return err
+	}
+
+	if order.CostType != models.OrderCostTypeMixed {
+		order.RefundedAmount += amount
+	}
+	order.Status = models.OrderStatusPartiallyRefunded
+	if remaining == 0 {
+		order.Status = models.OrderStatusRefunded
+	}
+	return tx.Model(&order).Updates(map[string]interface{}{
+		"refunded_amount": order.RefundedAmount,
+		"status":          order.Status,
+	}).Error
+}
+
+// ListRefunds 分页查询退款记录，userID和flowID为0时不作为筛选条件
+func (s *purchaseRefundService) ListRefunds(userID uint, flowID uint, page, pageSize int) ([]*models.PurchaseRefund, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := config.Database.Model(&models.PurchaseRefund{})
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if flowID != 0 {
+		query = query.Where("flow_id = ?", flowID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var refunds []*models.PurchaseRefund
+	if err := query.Offset(offset).Limit(pageSize).Order("id desc").Find(&refunds).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return refunds, total, nil
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// requirementChecker 检查玩家是否满足商品的购买条件，前置商品的拥有情况和名称在创建时一次查出
+type requirementChecker struct {
+	user  *models.User
+	now   time.Time
+	owned map[uint]bool
+	names map[uint]string
+}
+
+// newRequirementChecker 创建购买条件检查器，prerequisites为需要检查的前置商品ID
+func newRequirementChecker(db *gorm.DB, user *models.User, prerequisites []uint, now time.Time) (*requirementChecker, error) {
+	checker := &requirementChecker{user: user, now: now, names: make(map[uint]string)}
+	if len(prerequisites) == 0 {
+		checker.owned = make(map[uint]bool)
+		return checker, nil
+	}
+
+	owned, err := ownedStores(db, user.UID, prerequisites)
+	if err != nil {
+		return nil, err
+	}
+	checker.owned = owned
+
+	var stores []*models.Store
+	if err := db.Select("id, name").Where("id IN (?)", prerequisites).Find(&stores).Error; err != nil {
+		return nil, err
+	}
+	for _, store := range stores {
+		checker.names[store.ID] = store.Name
+	}
+	return checker, nil
+}
+
+// check 按等级、VIP等级、前置商品的顺序检查，返回第一个未满足的条件
+func (c *requirementChecker) check(level, vipLevel, storeID uint) error {
+	if level > 0 && c.user.Level < level {
+		return fmt.Errorf("%w：需要达到%d级", ErrLevelRequired, level)
+	}
+	if vipLevel > 0 && c.user.CurrentVipLevel(c.now) < vipLevel {
+		return fmt.Errorf("%w：需要VIP%d及以上", ErrVipRequired, vipLevel)
+	}
+	if storeID > 0 && !c.owned[storeID] {
+		name := c.names[storeID]
+		if name == "" {
+			name = fmt.Sprintf("%d", storeID)
+		}
+		return fmt.Errorf("%w：需要先拥有商品%s", ErrPrerequisiteRequired, name)
+	}
+	return nil
+}
+
+// checkStoreRequirements 检查玩家是否满足所有商品的购买条件
+func checkStoreRequirements(db *gorm.DB, user *models.User, stores []*models.Store, now time.Time) error {
+	prerequisites := make([]uint, 0)
+	for _, store := range stores {
+		if store.RequiredStoreID > 0 {
+			prerequisites = append(prerequisites, store.RequiredStoreID)
+		}
+	}
+
+	checker, err := newRequirementChecker(db, user, prerequisites, now)
+	if err != nil {
+		return err
+	}
+	for _, store := range stores {
+		if err := checker.check(store.RequiredLevel, store.RequiredVipLevel, store.RequiredStoreID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FillLockStatus 为设置了购买条件的商品填充当前用户是否未满足条件及原因
+func (s *storeService) FillLockStatus(userID uint, stores ...*models.StoreDTO) error {
+	gated := make([]*models.StoreDTO, 0)
+	prerequisites := make([]uint, 0)
+	for _, store := range stores {
+		if store == nil || (store.RequiredLevel == 0 && store.RequiredVipLevel == 0 && store.RequiredStoreID == 0) {
+			continue
+		}
+		gated = append(gated, store)
+		if store.RequiredStoreID > 0 {
+			prerequisites = append(prerequisites, store.RequiredStoreID)
+		}
+	}
+	if len(gated) == 0 {
+		return nil
+	}
+
+	var user models.User
+	if err := config.Database.Select("uid, level, vip_level, vip_expire_at").Where("uid = ?", userID).First(&user).Error; err != nil {
+		return err
+	}
+
+	checker, err := newRequirementChecker(config.Database, &user, prerequisites, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, store := range gated {
+		if err := checker.check(store.RequiredLevel, store.RequiredVipLevel, store.RequiredStoreID); err != nil {
+			store.Locked = true
+			store.LockReason = err.Error()
+		}
+	}
+	return nil
+}
+
+// ownedStores 查询玩家拥有或购买过的商品：背包中该商品的数量大于0，或有该商品未全部退款的商城购买流水。
+// 退款后背包记录保留为0、购买流水也不会删除，因此需要排除，避免购买后退款仍被视为拥有
+func ownedStores(db *gorm.DB, userID uint, storeIDs []uint) (map[uint]bool, error) {
+	owned := make(map[uint]bool, len(storeIDs))
+
+	var ids []uint
+	if err := db.Model(&models.Backpack{}).Where("user_id = ? AND store_id IN (?) AND quantity > 0", userID, storeIDs).Pluck("store_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		owned[id] = true
+	}
+
+	// 没有记录数量的老流水无法判断退款数量，有退款时即视为已退款
+	ids = nil
+	if err := db.Table("user_currency_flow AS f").
+		Joins("LEFT JOIN (SELECT flow_id, SUM(quantity) AS quantity FROM purchase_refunds GROUP BY flow_id) AS r ON r.flow_id = f.id").
+		Where("f.user_id = ? AND f.store_id IN (?) AND f.price < 0 AND f.ref_type IN (?)", userID, storeIDs, []string{"", models.FlowRefPurchase}).
+		Where("(f.quantity > 0 AND COALESCE(r.quantity, 0) < f.quantity) OR (f.quantity = 0 AND r.flow_id IS NULL)").
+		Pluck("DISTINCT f.store_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		owned[id] = true
+	}
+	return owned, nil
+}
@@ -0,0 +1,488 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RechargeOrderFilter 充值订单查询条件
+type RechargeOrderFilter struct {
+	OrderNo   string
+	UserID    uint
+	Status    models.RechargeOrderStatus
+	Provider  string
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// RechargeService 充值服务接口
+type RechargeService interface {
+	// 充值商品管理
+	CreateProduct(product *models.RechargeProduct) error
+	UpdateProduct(product *models.RechargeProduct) error
+	ListProducts(onlyOnSale bool) ([]*models.RechargeProduct, error)
+
+	// 订单流程
+	CreateOrder(userID uint, productID uint, providerName string) (*models.RechargeOrder, *PaymentIntent, error)
+	HandleCallback(providerName string, header http.Header, body []byte) (*models.RechargeOrder, error)
+	MockPay(orderNo string) (*models.RechargeOrder, error)
+	RefundOrder(orderNo string, reason string) (*models.RechargeOrder, error)
+	// 重试等待支付渠道退款的订单
+	RetryRefunds() (int, error)
+
+	// 订单查询
+	GetOrderByNo(orderNo string) (*models.RechargeOrder, error)
+	GetUserOrders(userID uint, page, pageSize int) ([]*models.RechargeOrder, int64, error)
+	SearchOrders(filter *RechargeOrderFilter, page, pageSize int) ([]*models.RechargeOrder, int64, error)
+}
+
+// rechargeService 充值服务实现
+type rechargeService struct {
+	spendLimitService SpendLimitService
+}
+
+// NewRechargeService 创建充值服务实例
+func NewRechargeService() RechargeService {
+	return &rechargeService{
+		spendLimitService: NewSpendLimitService(),
+	}
+}
+
+// CreateProduct 创建充值商品
+func (s *rechargeService) CreateProduct(product *models.RechargeProduct) error {
+	return config.Database.Create(product).Error
+}
+
+// UpdateProduct 更新充值商品
+func (s *rechargeService) UpdateProduct(product *models.RechargeProduct) error {
+	return config.Database.Save(product).Error
+}
+
+// ListProducts 获取充值商品列表
+func (s *rechargeService) ListProducts(onlyOnSale bool) ([]*models.RechargeProduct, error) {
+	var products []*models.RechargeProduct
+	query := config.Database.Order("sort_order asc, id asc")
+	if onlyOnSale {
+		query = query.Where("status = 1")
+	}
+	if err := query.Find(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// CreateOrder 创建充值订单并向支付渠道发起支付
+func (s *rechargeService) CreateOrder(userID uint, productID uint, providerName string) (*models.RechargeOrder, *PaymentIntent, error) {
+	provider, err := GetPaymentProvider(providerName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var product models.RechargeProduct
+	if err := config.Database.Where("id = ? AND status = 1", productID).First(&product).Error; err != nil {
+		return nil, nil, errors.New("充值商品不存在或已下架")
+	}
+
+	// 充值金额计入法币消费限额
+	if err := s.spendLimitService.CheckSpend(userID, product.Currency, product.Price); err != nil {
+		return nil, nil, err
+	}
+
+	order := &models.RechargeOrder{
+		OrderNo:     utils.GenerateOrderNo("RC"),
+		UserID:      userID,
+		ProductID:   product.ID,
+		ProductName: product.Name,
+		Amount:      product.Price,
+		Currency:    product.Currency,
+		Diamonds:    product.Diamonds,
+		Status:      models.RechargeStatusCreated,
+		Provider:    provider.Name(),
+	}
+	if err := config.Database.Create(order).Error; err != nil {
+		return nil, nil, err
+	}
+
+	intent, err := provider.CreatePayment(order)
+	if err != nil {
+		s.markFailed(order.OrderNo, fmt.Sprintf("创建支付失败: %v", err))
+		return nil, nil, err
+	}
+
+	return order, intent, nil
+}
+
+// HandleCallback 处理支付渠道回调：校验签名后将订单标记为已支付并发放钻石，重复回调不会重复发放
+func (s *rechargeService) HandleCallback(providerName string, header http.Header, body []byte) (*models.RechargeOrder, error) {
+	provider, err := GetPaymentProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	notification, err := provider.VerifyCallback(header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if !notification.Success {
+		s.markFailed(notification.OrderNo, notification.Message)
+		return s.GetOrderByNo(notification.OrderNo)
+	}
+
+	var order *models.RechargeOrder
+	err = withOptimisticRetry(func() error {
+		var fulfillErr error
+		order, fulfillErr = s.fulfill(provider.Name(), notification)
+		return fulfillErr
+	})
+	return order, err
+}
+
+// MockPay 模拟支付成功，仅在启用模拟支付渠道时可用
+func (s *rechargeService) MockPay(orderNo string) (*models.RechargeOrder, error) {
+	provider, err := GetPaymentProvider(MockPaymentProviderName)
+	if err != nil {
+		return nil, err
+	}
+	mockProvider, ok := provider.(*mockPaymentProvider)
+	if !ok {
+		return nil, errors.New("模拟支付不可用")
+	}
+
+	order, err := s.GetOrderByNo(orderNo)
+	if err != nil {
+		return nil, err
+	}
+	if order.Provider != MockPaymentProviderName {
+		return nil, errors.New("该订单不是模拟支付订单")
+	}
+
+	header, body, err := mockProvider.buildCallback(order)
+	if err != nil {
+		return nil, err
+	}
+	return s.HandleCallback(MockPaymentProviderName, header, body)
+}
+
+// fulfill 在一个事务中完成"已支付 -> 已发放"，通过行锁和状态判断保证只发放一次
+func (s *rechargeService) fulfill(providerName string, notification *PaymentNotification) (*models.RechargeOrder, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var order models.RechargeOrder
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("order_no = ?", notification.OrderNo).First(&order).Error; err != nil {
+		SafeRollback(tx)
+		return nil, errors.New("订单不存在")
+	}
+
+	// 已发放或已退款的订单直接返回，保证重复回调幂等
+	if order.Status == models.RechargeStatusFulfilled || order.Status == models.RechargeStatusRefunding || order.Status == models.RechargeStatusRefunded {
+		SafeRollback(tx)
+		return &order, nil
+	}
+	if order.Status != models.RechargeStatusCreated && order.Status != models.RechargeStatusPaid {
+		SafeRollback(tx)
+		return nil, fmt.Errorf("订单状态为%s，无法发放", order.Status)
+	}
+	if order.Provider != providerName {
+		SafeRollback(tx)
+		return nil, errors.New("支付渠道与订单不一致")
+	}
+	if notification.Amount != order.Amount || notification.Currency != order.Currency {
+		SafeRollback(tx)
+		return nil, errors.New("支付金额与订单金额不一致")
+	}
+
+	// 1、标记为已支付
+	now := time.Now()
+	order.Status = models.RechargeStatusPaid
+	order.ProviderTradeNo = notification.TradeNo
+	order.PaidAt = &now
+
+	// 2、重新检查并预占法币消费限额，下单后其他订单可能已用掉额度；超出限额时不发放钻石，原路退款
+	reservation, err := s.spendLimitService.ReserveSpend(order.UserID, order.Currency, order.Amount)
+	if errors.Is(err, ErrSpendLimitExceeded) {
+		return s.refundOverLimit(tx, &order, err.Error())
+	}
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			s.spendLimitService.ReleaseSpend(reservation)
+		}
+	}()
+
+	// 3、首次购买该商品时发放额外赠送。先锁定用户行，保证同一用户的订单串行判断首充，
+	// 避免并发支付的多个订单都查到0条已发放订单而重复赠送。因超出限额被退款的订单没有发放过钻石，不计入
+	var user models.User
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("uid = ?", order.UserID).First(&user).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	var fulfilledCount int
+	if err := tx.Model(&models.RechargeOrder{}).
+		Where("user_id = ? AND product_id = ? AND status IN (?) AND fulfilled_at IS NOT NULL", order.UserID, order.ProductID,
+			[]models.RechargeOrderStatus{models.RechargeStatusFulfilled, models.RechargeStatusRefunding, models.RechargeStatusRefunded}).
+		Count(&fulfilledCount).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if fulfilledCount == 0 {
+		var product models.RechargeProduct
+		if err := tx.First(&product, order.ProductID).Error; err == nil {
+			order.Bonus = product.FirstBonus
+		}
+	}
+
+	// 4、发放钻石
+	if err := s.changeDiamonds(tx, &order, order.Diamonds+order.Bonus, models.FlowRefRecharge,
+		fmt.Sprintf("充值到账，订单号：%s", order.OrderNo)); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	// 5、标记为已发放
+	order.Status = models.RechargeStatusFulfilled
+	order.FulfilledAt = &now
+	if err := tx.Save(&order).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	committed = true
+
+	clearWalletCache(order.UserID)
+	log.Printf("充值订单%s已发放钻石%d", order.OrderNo, order.Diamonds+order.Bonus)
+	return &order, nil
+}
+
+// refundOverLimit 支付完成时已超出消费限额，不发放钻石，提交后通知支付渠道退款
+func (s *rechargeService) refundOverLimit(tx *gorm.DB, order *models.RechargeOrder, reason string) (*models.RechargeOrder, error) {
+	order.Status = models.RechargeStatusRefunding
+	order.FailReason = reason
+	if err := tx.Save(order).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("充值订单%s超出消费限额，未发放钻石: %s", order.OrderNo, reason)
+	if err := s.completeRefund(order); err != nil {
+		// 渠道退款失败时订单保持等待退款，由定时任务重试
+		log.Printf("充值订单%s渠道退款失败，等待重试: %v", order.OrderNo, err)
+	}
+	return order, nil
+}
+
+// RefundOrder 退款：扣回已发放的钻石并通知支付渠道退款
+func (s *rechargeService) RefundOrder(orderNo string, reason string) (*models.RechargeOrder, error) {
+	var order *models.RechargeOrder
+	err := withOptimisticRetry(func() error {
+		var refundErr error
+		order, refundErr = s.refund(orderNo, reason)
+		return refundErr
+	})
+	return order, err
+}
+
+func (s *rechargeService) refund(orderNo string, reason string) (*models.RechargeOrder, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var order models.RechargeOrder
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("order_no = ?", orderNo).First(&order).Error; err != nil {
+		SafeRollback(tx)
+		return nil, errors.New("订单不存在")
+	}
+	if order.Status != models.RechargeStatusFulfilled {
+		SafeRollback(tx)
+		return nil, errors.New("只有已发放的订单可以退款")
+	}
+
+	if _, err := GetPaymentProvider(order.Provider); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	// 扣回发放的钻石，余额不足时不允许退款
+	if err := s.changeDiamonds(tx, &order, -(order.Diamonds + order.Bonus), models.FlowRefRechargeRefund,
+		fmt.Sprintf("充值退款，订单号：%s", order.OrderNo)); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	// 先提交为等待退款再通知支付渠道，避免渠道已退款而事务提交失败
+	order.Status = models.RechargeStatusRefunding
+	order.FailReason = reason
+	if err := tx.Save(&order).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	clearWalletCache(order.UserID)
+	if err := s.completeRefund(&order); err != nil {
+		return nil, fmt.Errorf("支付渠道退款失败，将由定时任务重试: %w", err)
+	}
+	return &order, nil
+}
+
+// completeRefund 通知支付渠道退款，成功后将等待退款的订单标记为已退款
+func (s *rechargeService) completeRefund(order *models.RechargeOrder) error {
+	provider, err := GetPaymentProvider(order.Provider)
+	if err != nil {
+		return err
+	}
+	if err := provider.Refund(order, order.FailReason); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := config.Database.Model(&models.RechargeOrder{}).
+		Where("id = ? AND status = ?", order.ID, models.RechargeStatusRefunding).
+		Updates(map[string]interface{}{
+			"status":      models.RechargeStatusRefunded,
+			"refunded_at": now,
+		}).Error; err != nil {
+		return err
+	}
+	order.Status = models.RechargeStatusRefunded
+	order.RefundedAt = &now
+	return nil
+}
+
+// RetryRefunds 重试等待支付渠道退款的订单，返回退款成功的数量
+func (s *rechargeService) RetryRefunds() (int, error) {
+	var orders []*models.RechargeOrder
+	if err := config.Database.Where("status = ?", models.RechargeStatusRefunding).Order("id asc").Find(&orders).Error; err != nil {
+		return 0, err
+	}
+
+	refunded := 0
+	for _, order := range orders {
+		if err := s.completeRefund(order); err != nil {
+			log.Printf("充值订单%s渠道退款失败: %v", order.OrderNo, err)
+			continue
+		}
+		refunded++
+	}
+	return refunded, nil
+}
+
+// changeDiamonds 变更订单用户的钻石余额并记录关联订单的货币流水
+func (s *rechargeService) changeDiamonds(tx *gorm.DB, order *models.RechargeOrder, amount int64, refType string, description string) error {
+	var wallet models.UserWallet
+	if err := tx.Where("user_id = ? AND type = ?", order.UserID, models.Diamond).First(&wallet).Error; err != nil {
+		return err
+	}
+	if err := changeWalletBalance(tx, &wallet, amount, nil, description); err != nil {
+		return err
+	}
+	return tx.Create(&models.UserCurrencyFlow{
+		UserID:      order.UserID,
+		CostType:    string(models.Diamond),
+		Price:       amount,
+		Description: description,
+		RefType:     refType,
+		RefID:       order.ID,
+	}).Error
+}
+
+// markFailed 将未支付的订单标记为失败
+func (s *rechargeService) markFailed(orderNo string, reason string) {
+	if err := config.Database.Model(&models.RechargeOrder{}).
+		Where("order_no = ? AND status = ?", orderNo, models.RechargeStatusCreated).
+		Updates(map[string]interface{}{
+			"status":      models.RechargeStatusFailed,
+			"fail_reason": reason,
+		}).Error; err != nil {
+		log.Printf("充值订单%s标记失败出错: %v", orderNo, err)
+	}
+}
+
+// GetOrderByNo 根据订单号获取充值订单
+func (s *rechargeService) GetOrderByNo(orderNo string) (*models.RechargeOrder, error) {
+	var order models.RechargeOrder
+	if err := config.Database.Where("order_no = ?", orderNo).First(&order).Error; err != nil {
+		return nil, errors.New("订单不存在")
+	}
+	return &order, nil
+}
+
+// GetUserOrders 分页获取用户的充值订单
+func (s *rechargeService) GetUserOrders(userID uint, page, pageSize int) ([]*models.RechargeOrder, int64, error) {
+	return s.SearchOrders(&RechargeOrderFilter{UserID: userID}, page, pageSize)
+}
+
+// SearchOrders 按条件分页查询充值订单
+func (s *rechargeService) SearchOrders(filter *RechargeOrderFilter, page, pageSize int) ([]*models.RechargeOrder, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := config.Database.Model(&models.RechargeOrder{})
+	if filter.OrderNo != "" {
+		query = query.Where("order_no = ?", filter.OrderNo)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Provider != "" {
+		query = query.Where("provider = ?", filter.Provider)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("created_at >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("created_at < ?", *filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var orders []*models.RechargeOrder
+	if err := query.Offset(offset).Limit(pageSize).Order("id desc").Find(&orders).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return orders, total, nil
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/utils"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// reconcileBatchSize 每批对账的钱包数量
+const reconcileBatchSize = 500
+
+// ReconcileService 钱包对账服务接口
+type ReconcileService interface {
+	// 执行一次全量对账，返回批次号和差异数量
+	RunReconciliation() (string, int, error)
+	// 分页查询对账差异
+	ListDrifts(status models.DriftStatus, batchNo string, page, pageSize int) ([]*models.WalletDrift, int64, error)
+	// 对差异执行修正
+	ApplyCorrection(driftID uint, mode models.DriftFixMode, operatorID uint) (*models.WalletDrift, error)
+}
+
+// reconcileService 钱包对账服务实现
+type reconcileService struct{}
+
+// NewReconcileService 创建钱包对账服务实例
+func NewReconcileService() ReconcileService {
+	return &reconcileService{}
+}
+
+// walletFlowSum 按用户和货币类型汇总的流水金额
+type walletFlowSum struct {
+	UserID   uint
+	CostType string
+	Total    int64
+}
+
+// RunReconciliation 根据初始赠送额度、货币流水和奖励流水推算每个钱包的期望余额，并记录差异
+func (s *reconcileService) RunReconciliation() (string, int, error) {
+	// 批次号带随机后缀，同一秒内多次对账不会重复
+	batchNo := utils.GenerateOrderNo("R")
+
+	// 旧的待处理差异由本轮结果取代
+	if err := config.Database.Model(&models.WalletDrift{}).
+		Where("status = ?", models.DriftStatusOpen).
+		Update("status", models.DriftStatusSuperseded).Error; err != nil {
+		return "", 0, err
+	}
+
+	driftCount := 0
+	var lastID uint
+	for {
+		var wallets []*models.UserWallet
+		if err := config.Database.Where("id > ?", lastID).Order("id asc").Limit(reconcileBatchSize).Find(&wallets).Error; err != nil {
+			return batchNo, driftCount, err
+		}
+		if len(wallets) == 0 {
+			break
+		}
+		lastID = wallets[len(wallets)-1].ID
+
+		drifts, err := s.reconcileBatch(wallets)
+		if err != nil {
+			return batchNo, driftCount, err
+		}
+
+		for _, drift := range drifts {
+			drift.BatchNo = batchNo
+			if err := config.Database.Create(drift).Error; err != nil {
+				return batchNo, driftCount, err
+			}
+		}
+		driftCount += len(drifts)
+	}
+
+	log.Printf("钱包对账完成，批次号：%s，差异数量：%d", batchNo, driftCount)
+	return batchNo, driftCount, nil
+}
+
+// reconcileBatch 对一批钱包进行对账，返回存在差异的记录
+func (s *reconcileService) reconcileBatch(wallets []*models.UserWallet) ([]*models.WalletDrift, error) {
+	userIDs := make([]uint, 0, len(wallets))
+	for _, wallet := range wallets {
+		userIDs = append(userIDs, wallet.UserID)
+	}
+
+	// 系统账户等没有用户记录的钱包不享受初始赠送，已删除的用户仍按注册时的赠送计算
+	var existingUIDs []uint
+	if err := config.Database.Unscoped().Model(&models.User{}).Where("uid IN (?)", userIDs).Pluck("uid", &existingUIDs).Error; err != nil {
+		return nil, err
+	}
+	userExists := make(map[uint]bool, len(existingUIDs))
+	for _, uid := range existingUIDs {
+		userExists[uid] = true
+	}
+
+	var sums []walletFlowSum
+	if err := config.Database.Model(&models.UserCurrencyFlow{}).
+		Select("user_id, cost_type, COALESCE(SUM(price), 0) AS total").
+		Where("user_id IN (?)", userIDs).
+		Group("user_id, cost_type").
+		Scan(&sums).Error; err != nil {
+		return nil, err
+	}
+	rewardSums, err := rewardOnlySums(config.Database, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	flowTotals := make(map[string]int64, len(sums))
+	for _, sum := range append(sums, rewardSums...) {
+		flowTotals[fmt.Sprintf("%d:%s", sum.UserID, sum.CostType)] += sum.Total
+	}
+
+	drifts := make([]*models.WalletDrift, 0)
+	for _, wallet := range wallets {
+		expected := flowTotals[fmt.Sprintf("%d:%s", wallet.UserID, wallet.Type)]
+		if userExists[wallet.UserID] {
+			expected += models.InitialGrant(wallet.Type)
+		}
+		if expected == wallet.Num {
+			continue
+		}
+
+		drifts = append(drifts, &models.WalletDrift{
+			UserID:   wallet.UserID,
+			Type:     wallet.Type,
+			Expected: expected,
+			Actual:   wallet.Num,
+			Drift:    wallet.Num - expected,
+			Status:   models.DriftStatusOpen,
+		})
+	}
+
+	return drifts, nil
+}
+
+// ListDrifts 分页查询对账差异
+func (s *reconcileService) ListDrifts(status models.DriftStatus, batchNo string, page, pageSize int) ([]*models.WalletDrift, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	query := config.Database.Model(&models.WalletDrift{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if batchNo != "" {
+		query = query.Where("batch_no = ?", batchNo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var drifts []*models.WalletDrift
+	if err := query.Offset(offset).Limit(pageSize).Order("id desc").Find(&drifts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return drifts, total, nil
+}
+
+// ApplyCorrection 修正对账差异，修正前会在锁定钱包后重新计算差额
+func (s *reconcileService) ApplyCorrection(driftID uint, mode models.DriftFixMode, operatorID uint) (*models.WalletDrift, error) {
+	if mode != models.DriftFixLedger && mode != models.DriftFixWallet {
+		return nil, errors.New("无效的修正方式")
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var drift models.WalletDrift
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&drift, driftID).Error; err != nil {
+		SafeRollback(tx)
+		return nil, errors.New("对账差异记录不存在")
+	}
+	if drift.Status != models.DriftStatusOpen {
+		SafeRollback(tx)
+		return nil, errors.New("该差异已处理或已失效")
+	}
+
+	wallets, err := lockWallets(tx, drift.Type, drift.UserID)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	wallet := wallets[drift.UserID]
+
+	expected, err := s.expectedBalance(tx, drift.UserID, drift.Type)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	diff := wallet.Num - expected
+
+	if diff != 0 {
+		switch mode {
+		case models.DriftFixLedger:
+			// 以钱包余额为准，补记修正流水使流水与余额一致
+			if err := tx.Create(&models.UserCurrencyFlow{
+				UserID:      drift.UserID,
+				CostType:    string(drift.Type),
+				Price:       diff,
+				Description: fmt.Sprintf("对账修正，批次号：%s", drift.BatchNo),
+				RefType:     models.FlowRefReconcile,
+				RefID:       drift.ID,
+			}).Error; err != nil {
+				SafeRollback(tx)
+				return nil, err
+			}
+		case models.DriftFixWallet:
+			// 以流水为准，将钱包余额恢复为期望值
+			if expected < 0 {
+				SafeRollback(tx)
+				return nil, errors.New("期望余额为负数，无法按流水修正钱包")
+			}
+			if err := changeWalletBalance(tx, wallet, expected-wallet.Num, nil, "对账修正"); err != nil {
+				SafeRollback(tx)
+				return nil, err
+			}
+		}
+	}
+
+	now := time.Now()
+	drift.Status = models.DriftStatusFixed
+	drift.FixMode = mode
+	drift.FixedBy = operatorID
+	drift.FixedAt = &now
+	if err := tx.Save(&drift).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	clearWalletCache(drift.UserID)
+	return &drift, nil
+}
+
+// expectedBalance 计算单个钱包的期望余额
+func (s *reconcileService) expectedBalance(tx *gorm.DB, userID uint, walletType models.WalletType) (int64, error) {
+	var result struct {
+		Total int64
+	}
+	if err := tx.Model(&models.UserCurrencyFlow{}).
+		Select("COALESCE(SUM(price), 0) AS total").
+		Where("user_id = ? AND cost_type = ?", userID, walletType).
+		Scan(&result).Error; err != nil {
+		return 0, err
+	}
+
+	rewardSums, err := rewardOnlySums(tx, []uint{userID})
+	if err != nil {
+		return 0, err
+	}
+	for _, sum := range rewardSums {
+		if sum.CostType == string(walletType) {
+			result.Total += sum.Total
+		}
+	}
+
+	var count int
+	if err := tx.Unscoped().Model(&models.User{}).Where("uid = ?", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		result.Total += models.InitialGrant(walletType)
+	}
+
+	return result.Total, nil
+}
+
+// rewardOnlySums 按用户和货币类型汇总关联的货币流水缺失的金币、钻石奖励流水。
+// 余额变动写入的奖励流水通过RefType/RefID关联对应的货币流水，关联存在时只按货币流水计算，避免重复。
+// 未记录关联的历史奖励流水无法可靠判断是否已有对应货币流水，不计入期望余额，由此产生的差异通过对账修正补记
+func rewardOnlySums(db *gorm.DB, userIDs []uint) ([]walletFlowSum, error) {
+	var sums []walletFlowSum
+	err := db.Table("reward_flows AS rf").
+		Select("rf.user_id, rf.item_type AS cost_type, COALESCE(SUM(rf.quantity), 0) AS total").
+		Where("rf.user_id IN (?) AND rf.item_type IN (?)", userIDs, []models.RewardFlowType{models.RewardTypeCoin, models.RewardTypeDiamond}).
+		Where("rf.ref_type = ?", models.RewardFlowRefCurrencyFlow).
+		Where("NOT EXISTS (SELECT 1 FROM user_currency_flow f WHERE f.id = rf.ref_id)").
+		Group("rf.user_id, rf.item_type").
+		Scan(&sums).Error
+	return sums, err
+}
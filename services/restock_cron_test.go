@@ -0,0 +1,52 @@
+package services
+
+import (
+	"goDDD1/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCronScheduleNext 测试cron表达式的解析和下次执行时间计算
+func TestCronScheduleNext(t *testing.T) {
+	base := time.Date(2024, 3, 15, 10, 30, 45, 0, time.UTC) // 周五
+
+	schedule, err := parseCron("*/15 * * * *")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 15, 10, 45, 0, 0, time.UTC), schedule.Next(base))
+
+	schedule, err = parseCron("0 4 * * 1-5")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 18, 4, 0, 0, 0, time.UTC), schedule.Next(base))
+
+	// 日和周都指定时满足其一即可，7表示周日
+	schedule, err = parseCron("0 0 1 * 7")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC), schedule.Next(base))
+
+	schedule, err = parseCron("30 12 29 2 *")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2028, 2, 29, 12, 30, 0, 0, time.UTC), schedule.Next(base))
+
+	schedule, err = parseCron("0 0 30 2 *")
+	assert.NoError(t, err)
+	assert.True(t, schedule.Next(base).IsZero())
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := parseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+// TestRestockAmount 测试补满和递增两种补货方式的补货数量
+func TestRestockAmount(t *testing.T) {
+	fixed := &models.RestockPolicy{Mode: models.RestockModeFixed, Amount: 100}
+	assert.Equal(t, int64(70), fixed.RestockAmount(30))
+	assert.Equal(t, int64(0), fixed.RestockAmount(120))
+
+	increment := &models.RestockPolicy{Mode: models.RestockModeIncrement, Amount: 20, MaxStock: 50}
+	assert.Equal(t, int64(20), increment.RestockAmount(10))
+	assert.Equal(t, int64(5), increment.RestockAmount(45))
+	assert.Equal(t, int64(0), increment.RestockAmount(50))
+}
@@ -0,0 +1,443 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// RestockService 商品自动补货服务接口
+type RestockService interface {
+	// 创建或修改商品的补货策略
+	SavePolicy(policy *models.RestockPolicy) (*models.RestockPolicy, error)
+	// 删除商品的补货策略
+	DeletePolicy(storeID uint) error
+	// 分页查询补货策略
+	ListPolicies(page, pageSize int) ([]*models.RestockPolicy, int64, error)
+	// 立即按策略为商品补货
+	RestockNow(storeID uint, operatorID uint) (*models.RestockLog, error)
+	// 执行所有到期的补货策略
+	RunDuePolicies() ([]*models.RestockLog, error)
+	// 分页查询补货记录，storeID为0时查询全部
+	ListLogs(storeID uint, page, pageSize int) ([]*models.RestockLog, int64, error)
+
+	// 检查低库存并生成告警，库存恢复的告警自动解决
+	CheckLowStock() ([]*models.StockAlert, error)
+	// 分页查询低库存告警
+	ListAlerts(onlyOpen bool, page, pageSize int) ([]*models.StockAlert, int64, error)
+}
+
+type restockService struct{}
+
+// NewRestockService 创建自动补货服务实例
+func NewRestockService() RestockService {
+	return &restockService{}
+}
+
+// SavePolicy 创建或修改商品的补货策略，保存后按新的执行计划重新计算下次执行时间
+func (s *restockService) SavePolicy(policy *models.RestockPolicy) (*models.RestockPolicy, error) {
+	if err := validateRestockPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	var store models.Store
+	if err := config.Database.First(&store, policy.StoreID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("商品不存在")
+		}
+		return nil, err
+	}
+	if store.StoreType == models.StoreTypeBundle {
+		return nil, errors.New("礼包商品没有独立库存，不支持自动补货")
+	}
+
+	policy.NextRunAt = nil
+	if policy.Enabled {
+		next, err := nextRestockTime(policy, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		policy.NextRunAt = &next
+	}
+
+	var existing models.RestockPolicy
+	err := config.Database.Where("store_id = ?", policy.StoreID).First(&existing).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	if err == nil {
+		policy.ID = existing.ID
+		policy.CreatedAt = existing.CreatedAt
+		policy.LastRunAt = existing.LastRunAt
+		if err := config.Database.Save(policy).Error; err != nil {
+			return nil, err
+		}
+		return policy, nil
+	}
+
+	policy.ID = 0
+	if err := config.Database.Create(policy).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrConcurrentModification
+		}
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeletePolicy 删除商品的补货策略，补货记录和告警保留
+func (s *restockService) DeletePolicy(storeID uint) error {
+	result := config.Database.Where("store_id = ?", storeID).Delete(&models.RestockPolicy{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("补货策略不存在")
+	}
+	return nil
+}
+
+// ListPolicies 分页查询补货策略
+func (s *restockService) ListPolicies(page, pageSize int) ([]*models.RestockPolicy, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	var total int64
+	if err := config.Database.Model(&models.RestockPolicy{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var policies []*models.RestockPolicy
+	if err := config.Database.Order("id desc").Offset(offset).Limit(pageSize).Find(&policies).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return policies, total, nil
+}
+
+// RestockNow 管理员立即按策略为商品补货，不影响策略的下次执行时间
+func (s *restockService) RestockNow(storeID uint, operatorID uint) (*models.RestockLog, error) {
+	var policy models.RestockPolicy
+	if err := config.Database.Where("store_id = ?", storeID).First(&policy).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("补货策略不存在")
+		}
+		return nil, err
+	}
+
+	var restockLog *models.RestockLog
+	err := withOptimisticRetry(func() error {
+		var restockErr error
+		restockLog, restockErr = s.restock(policy.ID, models.RestockTriggerManual, operatorID, time.Now())
+		return restockErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restockLog, nil
+}
+
+// RunDuePolicies 执行所有到期的补货策略，单个策略失败不影响其他策略，只返回实际补货的记录
+func (s *restockService) RunDuePolicies() ([]*models.RestockLog, error) {
+	now := time.Now()
+
+	var policyIDs []uint
+	if err := config.Database.Model(&models.RestockPolicy{}).
+		Where("enabled = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at").
+		Limit(config.GetRestockConfig().BatchSize).
+		Pluck("id", &policyIDs).Error; err != nil {
+		return nil, err
+	}
+
+	logs := make([]*models.RestockLog, 0)
+	for _, policyID := range policyIDs {
+		var restockLog *models.RestockLog
+		err := withOptimisticRetry(func() error {
+			var restockErr error
+			restockLog, restockErr = s.restock(policyID, models.RestockTriggerSchedule, 0, now)
+			return restockErr
+		})
+		if err != nil {
+			log.Printf("补货策略%d执行失败: %v", policyID, err)
+			continue
+		}
+		if restockLog != nil {
+			logs = append(logs, restockLog)
+		}
+	}
+	return logs, nil
+}
+
+// restock 在一个事务中执行一次补货：锁定策略和商品，按策略增加库存并记录补货记录。
+// 定时执行时会顺延下次执行时间，库存无需补充时不写补货记录并返回nil
+func (s *restockService) restock(policyID uint, trigger models.RestockTrigger, operatorID uint, now time.Time) (*models.RestockLog, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var policy models.RestockPolicy
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&policy, policyID).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if trigger == models.RestockTriggerSchedule && (!policy.Enabled || policy.NextRunAt == nil || policy.NextRunAt.After(now)) {
+		// 已被其他任务执行或策略已修改
+		SafeRollback(tx)
+		return nil, nil
+	}
+
+	var store models.Store
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&store, policy.StoreID).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	before := store
+
+	added := policy.RestockAmount(store.Stock)
+	if added == 0 && trigger == models.RestockTriggerManual {
+		SafeRollback(tx)
+		return nil, errors.New("当前库存无需补货")
+	}
+
+	var restockLog *models.RestockLog
+	if added > 0 {
+		if err := updateStoreStockWithVersion(tx, &store, added); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+		// 从无货补到有货时通知收藏了该商品的玩家
+		if err := notifyWishlist(tx, &before, &store, now); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+		if err := resolveStockAlerts(tx, &policy, store.Stock, now); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+		restockLog = &models.RestockLog{
+			PolicyID:    policy.ID,
+			StoreID:     store.ID,
+			Mode:        policy.Mode,
+			Trigger:     trigger,
+			StockBefore: before.Stock,
+			StockAfter:  store.Stock,
+			Added:       added,
+			OperatorID:  operatorID,
+		}
+		if err := tx.Create(restockLog).Error; err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	updates := map[string]interface{}{"last_run_at": now}
+	if trigger == models.RestockTriggerSchedule {
+		next, err := nextRestockTime(&policy, now)
+		if err != nil {
+			// 执行计划已失效，停用策略避免反复执行
+			log.Printf("补货策略%d执行计划无效，已停用: %v", policy.ID, err)
+			updates["enabled"] = false
+			updates["next_run_at"] = gorm.Expr("NULL")
+		} else {
+			updates["next_run_at"] = next
+		}
+	}
+	if err := tx.Model(&policy).UpdateColumns(updates).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	// 库存变化后清除商品目录缓存
+	if restockLog != nil {
+		invalidateStoreCatalog()
+	}
+	return restockLog, nil
+}
+
+// ListLogs 分页查询补货记录
+func (s *restockService) ListLogs(storeID uint, page, pageSize int) ([]*models.RestockLog, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	db := config.Database.Model(&models.RestockLog{})
+	if storeID != 0 {
+		db = db.Where("store_id = ?", storeID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []*models.RestockLog
+	if err := db.Order("id desc").Offset(offset).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// lowStockRow 低库存检查的查询结果
+type lowStockRow struct {
+	StoreID   uint
+	StoreName string
+	Stock     int64
+	Threshold int64
+}
+
+// CheckLowStock 为库存低于阈值且没有未解决告警的商品生成告警，并解决库存已恢复（包括手动修改库存）的告警
+func (s *restockService) CheckLowStock() ([]*models.StockAlert, error) {
+	now := time.Now()
+
+	if err := config.Database.Exec("UPDATE stock_alerts a JOIN stores s ON s.id = a.store_id "+
+		"LEFT JOIN restock_policies p ON p.store_id = a.store_id "+
+		"SET a.resolved = true, a.resolved_at = ? "+
+		"WHERE a.resolved = false AND (p.id IS NULL OR p.low_stock_threshold = 0 OR s.stock >= p.low_stock_threshold)", now).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []lowStockRow
+	if err := config.Database.Table("restock_policies p").
+		Select("s.id AS store_id, s.name AS store_name, s.stock, p.low_stock_threshold AS threshold").
+		Joins("JOIN stores s ON s.id = p.store_id").
+		Where("p.low_stock_threshold > 0 AND s.stock < p.low_stock_threshold").
+		Where("NOT EXISTS (SELECT 1 FROM stock_alerts a WHERE a.store_id = p.store_id AND a.resolved = false)").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*models.StockAlert, 0, len(rows))
+	for _, row := range rows {
+		alert := &models.StockAlert{
+			StoreID:   row.StoreID,
+			StoreName: row.StoreName,
+			Stock:     row.Stock,
+			Threshold: row.Threshold,
+		}
+		if err := config.Database.Create(alert).Error; err != nil {
+			return alerts, err
+		}
+		log.Printf("商品%d(%s)库存不足：当前库存%d，告警阈值%d", alert.StoreID, alert.StoreName, alert.Stock, alert.Threshold)
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// ListAlerts 分页查询低库存告警，onlyOpen为true时只查询未解决的告警
+func (s *restockService) ListAlerts(onlyOpen bool, page, pageSize int) ([]*models.StockAlert, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	db := config.Database.Model(&models.StockAlert{})
+	if onlyOpen {
+		db = db.Where("resolved = ?", false)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var alerts []*models.StockAlert
+	if err := db.Order("id desc").Offset(offset).Limit(pageSize).Find(&alerts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return alerts, total, nil
+}
+
+// validateRestockPolicy 校验补货策略：补货方式和数量，执行计划只能配置一种
+func validateRestockPolicy(policy *models.RestockPolicy) error {
+	if policy.Amount <= 0 {
+		return errors.New("amount必须大于0")
+	}
+	switch policy.Mode {
+	case models.RestockModeFixed:
+		policy.MaxStock = 0
+	case models.RestockModeIncrement:
+		if policy.MaxStock <= 0 {
+			return errors.New("递增补货必须设置max_stock库存上限")
+		}
+	default:
+		return errors.New("mode必须是fixed或increment")
+	}
+
+	if policy.IntervalMinutes < 0 || policy.LowStockThreshold < 0 {
+		return errors.New("interval_minutes和low_stock_threshold不能小于0")
+	}
+	if (policy.IntervalMinutes > 0) == (policy.Cron != "") {
+		return errors.New("interval_minutes和cron必须且只能设置一个")
+	}
+	if policy.Cron != "" {
+		if _, err := parseCron(policy.Cron); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextRestockTime 计算补货策略在from之后的下次执行时间
+func nextRestockTime(policy *models.RestockPolicy, from time.Time) (time.Time, error) {
+	if policy.Cron == "" {
+		if policy.IntervalMinutes <= 0 {
+			return time.Time{}, errors.New("补货策略没有设置执行计划")
+		}
+		return from.Add(time.Duration(policy.IntervalMinutes) * time.Minute), nil
+	}
+
+	schedule, err := parseCron(policy.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := schedule.Next(from)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron表达式%s不会触发", policy.Cron)
+	}
+	return next, nil
+}
+
+// resolveStockAlerts 库存恢复到告警阈值以上时解决该商品未解决的告警
+func resolveStockAlerts(tx *gorm.DB, policy *models.RestockPolicy, stock int64, now time.Time) error {
+	if policy.LowStockThreshold > 0 && stock < policy.LowStockThreshold {
+		return nil
+	}
+	return tx.Model(&models.StockAlert{}).
+		Where("store_id = ? AND resolved = ?", policy.StoreID, false).
+		UpdateColumns(map[string]interface{}{"resolved": true, "resolved_at": now}).Error
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ReviewService 商品评价服务接口
+type ReviewService interface {
+	// 发表或修改评价，只有拥有或购买过该商品的玩家可以评价
+	SubmitReview(userID uint, storeID uint, rating int, content string) (*models.StoreReview, error)
+	// 分页获取商品已通过审核的评价
+	ListStoreReviews(storeID uint, sort models.ReviewSort, page, pageSize int) ([]*models.StoreReview, int64, error)
+	// 标记评价有帮助
+	MarkHelpful(userID uint, reviewID uint) (*models.StoreReview, error)
+	// 举报评价，举报次数达到阈值时自动隐藏
+	ReportReview(userID uint, reviewID uint, reason string) (*models.StoreReview, error)
+
+	// 评价管理
+	ListReviews(storeID uint, status models.ReviewStatus, page, pageSize int) ([]*models.StoreReview, int64, error)
+	ModerateReview(reviewID uint, status models.ReviewStatus, note string) (*models.StoreReview, error)
+}
+
+type reviewService struct{}
+
+// NewReviewService 创建商品评价服务实例
+func NewReviewService() ReviewService {
+	return &reviewService{}
+}
+
+// SubmitReview 发表评价，已评价过时修改原评价。修改后按配置重新进入待审核或直接展示，被隐藏的评价修改后需要重新审核
+func (s *reviewService) SubmitReview(userID uint, storeID uint, rating int, content string) (*models.StoreReview, error) {
+	if rating < 1 || rating > 5 {
+		return nil, errors.New("评分必须是1-5星")
+	}
+
+	var store models.Store
+	if err := config.Database.First(&store, storeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("商品不存在")
+		}
+		return nil, err
+	}
+	owned, err := hasOwnedStore(config.Database, userID, storeID)
+	if err != nil {
+		return nil, err
+	}
+	if !owned {
+		return nil, errors.New("只有拥有或购买过该商品的玩家才能评价")
+	}
+
+	status := models.ReviewStatusApproved
+	if config.GetReviewConfig().RequireModeration {
+		status = models.ReviewStatusPending
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var review models.StoreReview
+	err = tx.Set("gorm:query_option", "FOR UPDATE").Where("store_id = ? AND user_id = ?", storeID, userID).First(&review).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err != nil {
+		review = models.StoreReview{StoreID: storeID, UserID: userID, Rating: rating, Content: content, Status: status}
+		if err := tx.Create(&review).Error; err != nil {
+			SafeRollback(tx)
+			if isDuplicateKeyError(err) {
+				return nil, ErrConcurrentModification
+			}
+			return nil, err
+		}
+		if err := changeReviewRating(tx, nil, &review); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	} else {
+		before := review
+		// 被隐藏或审核未通过的评价修改后需要重新审核，不能因为关闭了审核而直接通过
+		if review.Status == models.ReviewStatusHidden || review.Status == models.ReviewStatusRejected {
+			status = models.ReviewStatusPending
+		}
+		review.Rating = rating
+		review.Content = content
+		review.Status = status
+		if err := tx.Save(&review).Error; err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+		if err := changeReviewRating(tx, &before, &review); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// ListStoreReviews 分页获取商品已通过审核的评价
+func (s *reviewService) ListStoreReviews(storeID uint, sort models.ReviewSort, page, pageSize int) ([]*models.StoreReview, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	db := config.Database.Model(&models.StoreReview{}).Where("store_id = ? AND status = ?", storeID, models.ReviewStatusApproved)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if sort == models.ReviewSortHelpful {
+		db = db.Order("helpful_count desc")
+	}
+
+	var reviews []*models.StoreReview
+	if err := db.Order("id desc").Offset(offset).Limit(pageSize).Find(&reviews).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return reviews, total, nil
+}
+
+// MarkHelpful 标记评价有帮助，每个玩家对每条评价只能标记一次
+func (s *reviewService) MarkHelpful(userID uint, reviewID uint) (*models.StoreReview, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	review, err := lockReview(tx, reviewID)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if review.Status != models.ReviewStatusApproved {
+		SafeRollback(tx)
+		return nil, errors.New("评价不存在")
+	}
+	if review.UserID == userID {
+		SafeRollback(tx)
+		return nil, errors.New("不能标记自己的评价")
+	}
+
+	if err := tx.Create(&models.ReviewVote{ReviewID: reviewID, UserID: userID}).Error; err != nil {
+		SafeRollback(tx)
+		if isDuplicateKeyError(err) {
+			return nil, errors.New("已经标记过该评价")
+		}
+		return nil, err
+	}
+	review.HelpfulCount++
+	if err := tx.Model(review).UpdateColumn("helpful_count", review.HelpfulCount).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// ReportReview 举报评价，每个玩家对每条评价只能举报一次，举报次数达到配置的阈值时自动隐藏并从评分中扣除
+func (s *reviewService) ReportReview(userID uint, reviewID uint, reason string) (*models.StoreReview, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	review, err := lockReview(tx, reviewID)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if review.Status != models.ReviewStatusApproved {
+		SafeRollback(tx)
+		return nil, errors.New("评价不存在")
+	}
+	if review.UserID == userID {
+		SafeRollback(tx)
+		return nil, errors.New("不能举报自己的评价")
+	}
+
+	if err := tx.Create(&models.ReviewReport{ReviewID: reviewID, UserID: userID, Reason: reason}).Error; err != nil {
+		SafeRollback(tx)
+		if isDuplicateKeyError(err) {
+			return nil, errors.New("已经举报过该评价")
+		}
+		return nil, err
+	}
+
+	before := *review
+	review.ReportCount++
+	if threshold := config.GetReviewConfig().HideReportCount; threshold > 0 && review.ReportCount >= int64(threshold) {
+		review.Status = models.ReviewStatusHidden
+		review.ModerateNote = "举报次数过多自动隐藏"
+	}
+	if err := tx.Save(review).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := changeReviewRating(tx, &before, review); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// ListReviews 管理员分页查询评价，storeID为0或status为空时不按该条件过滤
+func (s *reviewService) ListReviews(storeID uint, status models.ReviewStatus, page, pageSize int) ([]*models.StoreReview, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	db := config.Database.Model(&models.StoreReview{})
+	if storeID != 0 {
+		db = db.Where("store_id = ?", storeID)
+	}
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reviews []*models.StoreReview
+	if err := db.Order("id desc").Offset(offset).Limit(pageSize).Find(&reviews).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return reviews, total, nil
+}
+
+// ModerateReview 管理员审核评价：通过、拒绝或隐藏，评分统计随状态同步调整
+func (s *reviewService) ModerateReview(reviewID uint, status models.ReviewStatus, note string) (*models.StoreReview, error) {
+	switch status {
+	case models.ReviewStatusApproved, models.ReviewStatusRejected, models.ReviewStatusHidden:
+	default:
+		return nil, errors.New("status必须是approved、rejected或hidden")
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	review, err := lockReview(tx, reviewID)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	before := *review
+	review.Status = status
+	review.ModerateNote = note
+	if err := tx.Save(review).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := changeReviewRating(tx, &before, review); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// lockReview 加锁读取评价
+func lockReview(tx *gorm.DB, reviewID uint) (*models.StoreReview, error) {
+	var review models.StoreReview
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&review, reviewID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("评价不存在")
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+// hasOwnedStore 判断玩家是否拥有或购买过商品，已全部退款的购买不计入，退款后不能再评价
+func hasOwnedStore(db *gorm.DB, userID uint, storeID uint) (bool, error) {
+	owned, err := ownedStores(db, userID, []uint{storeID})
+	if err != nil {
+		return false, err
+	}
+	return owned[storeID], nil
+}
+
+// changeReviewRating 根据评价修改前后的状态和评分增量更新商品的评分统计，只有已通过的评价计入统计
+func changeReviewRating(tx *gorm.DB, before *models.StoreReview, after *models.StoreReview) error {
+	if before != nil && before.Status == models.ReviewStatusApproved {
+		if err := addStoreRating(tx, before.StoreID, before.Rating, -1); err != nil {
+			return err
+		}
+	}
+	if after.Status == models.ReviewStatusApproved {
+		if err := addStoreRating(tx, after.StoreID, after.Rating, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addStoreRating 增减商品的评分统计，不修改版本号
+func addStoreRating(tx *gorm.DB, storeID uint, rating int, delta int64) error {
+	return tx.Model(&models.Store{}).Where("id = ?", storeID).UpdateColumns(map[string]interface{}{
+		"rating_count":                        gorm.Expr("rating_count + ?", delta),
+		"rating_total":                        gorm.Expr("rating_total + ?", delta*int64(rating)),
+		fmt.Sprintf("rating%d_count", rating): gorm.Expr(fmt.Sprintf("rating%d_count + ?", rating), delta),
+	}).Error
+}
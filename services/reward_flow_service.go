@@ -10,6 +10,7 @@ import (
 // RewardFlowService 奖励流水服务接口
 type RewardFlowService interface {
 	CreateRewardFlow(tx *gorm.DB, userID uint, itemType models.RewardFlowType, itemID uint, quantity int64, source string) error
+	CreateCurrencyRewardFlow(tx *gorm.DB, currencyFlow *models.UserCurrencyFlow, itemID uint) error
 	GetUserRewardFlows(userID uint, page, pageSize int) ([]*models.RewardFlow, int64, error)
 }
 
@@ -38,6 +39,24 @@ func (s *rewardFlowService) CreateRewardFlow(tx *gorm.DB, userID uint, itemType
 	return config.Database.Create(flow).Error
 }
 
+// CreateCurrencyRewardFlow 为一条金币、钻石货币流水创建对应的奖励流水，并通过RefType/RefID关联该货币流水
+func (s *rewardFlowService) CreateCurrencyRewardFlow(tx *gorm.DB, currencyFlow *models.UserCurrencyFlow, itemID uint) error {
+	itemType := models.RewardTypeCoin
+	if currencyFlow.CostType == string(models.Diamond) {
+		itemType = models.RewardTypeDiamond
+	}
+
+	return tx.Create(&models.RewardFlow{
+		UserID:   currencyFlow.UserID,
+		ItemType: itemType,
+		ItemID:   itemID,
+		Quantity: currencyFlow.Price,
+		Source:   currencyFlow.Description,
+		RefType:  models.RewardFlowRefCurrencyFlow,
+		RefID:    currencyFlow.ID,
+	}).Error
+}
+
 // GetUserRewardFlows 获取用户的奖励流水记录
 func (s *rewardFlowService) GetUserRewardFlows(userID uint, page, pageSize int) ([]*models.RewardFlow, int64, error) {
 	if page <= 0 {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"goDDD1/config"
 	"goDDD1/models"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
@@ -277,9 +278,15 @@ func (s *rewardPackageService) GrantReward(tx *gorm.DB, userID uint, packageID u
 				return nil, err
 			}
 		case models.ItemTypeCurrency: // 货币
+			// 配置了有效期的货币奖励放入带过期时间的货币桶
+			var expiresAt *time.Time
+			if item.ExpireHours > 0 {
+				expireTime := time.Now().Add(time.Duration(item.ExpireHours) * time.Hour)
+				expiresAt = &expireTime
+			}
 			if item.ItemID == 0 {
 				// 更新钱包 - 使用正确的models.WalletType类型参数
-				if err := s.userWalletService.UpdateWalletBalanceWithTx(tx, userID, models.Diamond, int64(item.Num), fmt.Sprintf("奖励包发放，奖励包ID：%d", item.PackageID)); err != nil {
+				if err := s.userWalletService.GrantWalletBalanceWithTx(tx, userID, models.Diamond, int64(item.Num), expiresAt, fmt.Sprintf("奖励包发放，奖励包ID：%d", item.PackageID)); err != nil {
 					if localTx != nil {
 						localTx.Rollback()
 					}
@@ -288,7 +295,7 @@ func (s *rewardPackageService) GrantReward(tx *gorm.DB, userID uint, packageID u
 			}
 			if item.ItemID == 1 {
 				// 更新钱包 - 使用正确的models.WalletType类型参数
-				if err := s.userWalletService.UpdateWalletBalanceWithTx(tx, userID, models.Coin, int64(item.Num), fmt.Sprintf("奖励包发放，奖励包ID：%d", item.PackageID)); err != nil {
+				if err := s.userWalletService.GrantWalletBalanceWithTx(tx, userID, models.Coin, int64(item.Num), expiresAt, fmt.Sprintf("奖励包发放，奖励包ID：%d", item.PackageID)); err != nil {
 					if localTx != nil {
 						localTx.Rollback()
 					}
@@ -0,0 +1,505 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/utils"
+	"log"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jinzhu/gorm"
+)
+
+// spendCounterKey 消费计数器缓存键：用户ID、货币类型、周期标识
+const spendCounterKey = "spend_counter:%d:%s:%s"
+
+// spendPeriods 参与限额检查的统计周期
+var spendPeriods = []models.SpendPeriod{models.SpendPeriodDaily, models.SpendPeriodWeekly, models.SpendPeriodMonthly}
+
+// fiatCurrencyPattern 法币币种格式，如CNY、USD
+var fiatCurrencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// SpendLimitService 消费限额服务接口
+type SpendLimitService interface {
+	// 检查本次消费是否超出限额，只用于下单前的提示，不预占额度
+	CheckSpend(userID uint, currency string, amount int64) error
+	// 检查并预占消费额度，消费的事务未提交时需要调用ReleaseSpend释放
+	ReserveSpend(userID uint, currency string, amount int64) (*SpendReservation, error)
+	// 释放未完成消费的预占额度
+	ReleaseSpend(reservation *SpendReservation)
+	// 退款后扣回消费发生时所在周期的消费计数
+	RefundSpend(userID uint, currency string, amount int64, spentAt time.Time)
+	// 获取玩家各货币和周期的限额使用情况
+	GetUserLimits(userID uint) ([]*models.SpendLimitStatus, error)
+	// 玩家设置自我限额，收紧立即生效，放宽需经过冷静期
+	SetSelfLimit(userID uint, currency string, period models.SpendPeriod, amount int64) (*models.SpendLimit, error)
+	// 管理员设置全局或玩家限额，amount为0表示移除
+	SetLimit(userID uint, currency string, period models.SpendPeriod, amount int64) (*models.SpendLimit, error)
+	// 查询玩家限额配置，userID为0时查询全局限额
+	ListLimits(userID uint) ([]*models.SpendLimit, error)
+}
+
+// spendLimitService 消费限额服务实现
+type spendLimitService struct{}
+
+// NewSpendLimitService 创建消费限额服务实例
+func NewSpendLimitService() SpendLimitService {
+	return &spendLimitService{}
+}
+
+// CheckSpend 检查本次消费是否超出任一周期的限额
+func (s *spendLimitService) CheckSpend(userID uint, currency string, amount int64) error {
+	limits, err := s.loadLimits(userID)
+	if err != nil {
+		return err
+	}
+
+	loc := s.userLocation(userID)
+	now := time.Now()
+	for _, period := range spendPeriods {
+		limit, ok := effectiveLimit(limits, currency, period)
+		if !ok {
+			continue
+		}
+
+		spent, err := s.getSpent(userID, currency, period, now, loc)
+		if err != nil {
+			return err
+		}
+		if spent+amount > limit {
+			return fmt.Errorf("%w：%s%s上限%d，已消费%d", ErrSpendLimitExceeded, periodName(period), currency, limit, spent)
+		}
+	}
+	return nil
+}
+
+// SpendReservation 预占的消费额度，记录已累加的计数器，释放时扣回
+type SpendReservation struct {
+	UserID   uint
+	Currency string
+	Amount   int64
+	keys     []string
+}
+
+// ReserveSpend 检查并预占消费额度。设置了限额的周期在Redis中原子地检查并累加计数器，
+// 并发消费不会同时通过检查；未设置限额的周期只累加已初始化的计数器。
+// Redis不可用时退回数据库统计，此时无法预占
+func (s *spendLimitService) ReserveSpend(userID uint, currency string, amount int64) (*SpendReservation, error) {
+	limits, err := s.loadLimits(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := s.userLocation(userID)
+	now := time.Now()
+	reservation := &SpendReservation{UserID: userID, Currency: currency, Amount: amount}
+	for _, period := range spendPeriods {
+		start, _ := periodWindow(period, now, loc)
+		key := fmt.Sprintf(spendCounterKey, userID, currency, periodKey(period, start))
+
+		limit, ok := effectiveLimit(limits, currency, period)
+		if !ok {
+			if err := utils.IncrByIfExists(key, amount); err != nil {
+				// 计数器更新失败时删除，下次读取时从数据库重新统计
+				log.Printf("更新消费计数器%s失败: %v", key, err)
+				_ = utils.DeleteCache(key)
+				continue
+			}
+			reservation.keys = append(reservation.keys, key)
+			continue
+		}
+
+		// 读取一次已消费金额，计数器不存在时从数据库统计并初始化
+		spent, err := s.getSpent(userID, currency, period, now, loc)
+		if err != nil {
+			s.ReleaseSpend(reservation)
+			return nil, err
+		}
+		applied, current, err := utils.IncrByWithCap(key, amount, limit)
+		if err != nil {
+			log.Printf("预占消费额度%s失败，使用数据库统计检查: %v", key, err)
+			applied, current = spent+amount <= limit, spent
+		} else if applied {
+			reservation.keys = append(reservation.keys, key)
+		}
+		if !applied {
+			s.ReleaseSpend(reservation)
+			return nil, fmt.Errorf("%w：%s%s上限%d，已消费%d", ErrSpendLimitExceeded, periodName(period), currency, limit, current)
+		}
+	}
+	return reservation, nil
+}
+
+// ReleaseSpend 扣回预占时累加的计数器
+func (s *spendLimitService) ReleaseSpend(reservation *SpendReservation) {
+	if reservation == nil {
+		return
+	}
+	for _, key := range reservation.keys {
+		if err := utils.IncrByIfExists(key, -reservation.Amount); err != nil {
+			log.Printf("释放消费额度%s失败: %v", key, err)
+			_ = utils.DeleteCache(key)
+		}
+	}
+	reservation.keys = nil
+}
+
+// RefundSpend 扣回消费发生时所在周期已初始化的计数器，未初始化的计数器下次读取时从数据库统计
+func (s *spendLimitService) RefundSpend(userID uint, currency string, amount int64, spentAt time.Time) {
+	loc := s.userLocation(userID)
+	for _, period := range spendPeriods {
+		start, _ := periodWindow(period, spentAt, loc)
+		key := fmt.Sprintf(spendCounterKey, userID, currency, periodKey(period, start))
+		if err := utils.IncrByIfExists(key, -amount); err != nil {
+			log.Printf("扣回消费计数器%s失败: %v", key, err)
+			_ = utils.DeleteCache(key)
+		}
+	}
+}
+
+// GetUserLimits 获取玩家各货币和周期的限额使用情况
+func (s *spendLimitService) GetUserLimits(userID uint) ([]*models.SpendLimitStatus, error) {
+	limits, err := s.loadLimits(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 汇总所有设置了限额的货币和周期
+	type scope struct {
+		currency string
+		period   models.SpendPeriod
+	}
+	scopes := make([]scope, 0)
+	seen := make(map[scope]bool)
+	for _, limit := range limits {
+		item := scope{limit.Currency, limit.Period}
+		if !seen[item] {
+			seen[item] = true
+			scopes = append(scopes, item)
+		}
+	}
+	sort.Slice(scopes, func(i, j int) bool {
+		if scopes[i].currency != scopes[j].currency {
+			return scopes[i].currency < scopes[j].currency
+		}
+		return periodOrder(scopes[i].period) < periodOrder(scopes[j].period)
+	})
+
+	loc := s.userLocation(userID)
+	now := time.Now()
+	statuses := make([]*models.SpendLimitStatus, 0, len(scopes))
+	for _, item := range scopes {
+		status := &models.SpendLimitStatus{
+			Currency: item.currency,
+			Period:   item.period,
+		}
+
+		for _, limit := range limits {
+			if limit.Currency == item.currency && limit.Period == item.period && limit.Source == models.SpendLimitSourceSelf {
+				status.SelfLimit = limit.Amount
+				status.PendingAt = limit.PendingAt
+				if limit.PendingAt != nil {
+					status.PendingLimit = limit.PendingAmount
+				}
+			}
+		}
+
+		status.Limit, _ = effectiveLimit(limits, item.currency, item.period)
+		spent, err := s.getSpent(userID, item.currency, item.period, now, loc)
+		if err != nil {
+			return nil, err
+		}
+		status.Spent = spent
+		if remaining := status.Limit - spent; remaining > 0 {
+			status.Remaining = remaining
+		}
+		_, status.ResetAt = periodWindow(item.period, now, loc)
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// SetSelfLimit 玩家设置自我限额：新上限更严格时立即生效，放宽或移除需等待冷静期结束
+func (s *spendLimitService) SetSelfLimit(userID uint, currency string, period models.SpendPeriod, amount int64) (*models.SpendLimit, error) {
+	if err := validateSpendLimit(currency, period, amount); err != nil {
+		return nil, err
+	}
+
+	// 先让已到期的待生效变更落地，再与当前限额比较
+	if _, err := s.loadLimits(userID); err != nil {
+		return nil, err
+	}
+
+	var limit models.SpendLimit
+	err := config.Database.Where("user_id = ? AND currency = ? AND period = ? AND source = ?",
+		userID, currency, period, models.SpendLimitSourceSelf).First(&limit).Error
+	if err != nil {
+		if !gorm.IsRecordNotFoundError(err) {
+			return nil, err
+		}
+		if amount == 0 {
+			return nil, errors.New("未设置该自我限额")
+		}
+
+		limit = models.SpendLimit{
+			UserID:   userID,
+			Currency: currency,
+			Period:   period,
+			Source:   models.SpendLimitSourceSelf,
+			Amount:   amount,
+		}
+		if err := config.Database.Create(&limit).Error; err != nil {
+			return nil, err
+		}
+		return &limit, nil
+	}
+
+	if amount > 0 && amount <= limit.Amount {
+		// 收紧限额立即生效，同时取消尚未生效的放宽
+		limit.Amount = amount
+		limit.PendingAmount = 0
+		limit.PendingAt = nil
+	} else {
+		pendingAt := time.Now().Add(config.GetSpendLimitConfig().CoolingOff)
+		limit.PendingAmount = amount
+		limit.PendingAt = &pendingAt
+	}
+
+	if err := config.Database.Save(&limit).Error; err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+// SetLimit 管理员设置限额，立即生效
+func (s *spendLimitService) SetLimit(userID uint, currency string, period models.SpendPeriod, amount int64) (*models.SpendLimit, error) {
+	if err := validateSpendLimit(currency, period, amount); err != nil {
+		return nil, err
+	}
+
+	query := config.Database.Where("user_id = ? AND currency = ? AND period = ? AND source = ?",
+		userID, currency, period, models.SpendLimitSourceAdmin)
+
+	if amount == 0 {
+		if err := query.Delete(&models.SpendLimit{}).Error; err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	var limit models.SpendLimit
+	if err := query.Assign(models.SpendLimit{Amount: amount}).FirstOrCreate(&limit, models.SpendLimit{
+		UserID:   userID,
+		Currency: currency,
+		Period:   period,
+		Source:   models.SpendLimitSourceAdmin,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+// ListLimits 查询玩家自身的限额配置，userID为0时查询全局限额
+func (s *spendLimitService) ListLimits(userID uint) ([]*models.SpendLimit, error) {
+	var limits []*models.SpendLimit
+	if err := config.Database.Where("user_id = ?", userID).Order("currency asc, period asc, source asc").Find(&limits).Error; err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// loadLimits 加载对玩家生效的全局和个人限额，并应用已过冷静期的自我限额变更
+func (s *spendLimitService) loadLimits(userID uint) ([]*models.SpendLimit, error) {
+	var limits []*models.SpendLimit
+	if err := config.Database.Where("user_id IN (?)", []uint{0, userID}).Find(&limits).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]*models.SpendLimit, 0, len(limits))
+	for _, limit := range limits {
+		if limit.PendingAt != nil && !limit.PendingAt.After(now) {
+			if limit.PendingAmount == 0 {
+				if err := config.Database.Delete(limit).Error; err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			limit.Amount = limit.PendingAmount
+			limit.PendingAmount = 0
+			limit.PendingAt = nil
+			if err := config.Database.Save(limit).Error; err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, limit)
+	}
+	return result, nil
+}
+
+// getSpent 获取本周期已消费金额，优先读取Redis计数器，不可用时从数据库统计
+func (s *spendLimitService) getSpent(userID uint, currency string, period models.SpendPeriod, now time.Time, loc *time.Location) (int64, error) {
+	start, end := periodWindow(period, now, loc)
+	key := fmt.Sprintf(spendCounterKey, userID, currency, periodKey(period, start))
+
+	spent, err := utils.GetCounter(key)
+	if err == nil {
+		return spent, nil
+	}
+
+	spent, dbErr := s.sumSpent(userID, currency, start, end)
+	if dbErr != nil {
+		return 0, dbErr
+	}
+
+	if err == redis.Nil {
+		// 计数器保留到周期结束后一小时
+		if err := utils.InitCounter(key, spent, end.Sub(now)+time.Hour); err != nil {
+			log.Printf("初始化消费计数器%s失败: %v", key, err)
+		}
+	}
+	return spent, nil
+}
+
+// sumSpent 从数据库统计周期内的消费：游戏货币统计商城购买流水并减去这些购买的退款，法币统计已支付的充值订单
+func (s *spendLimitService) sumSpent(userID uint, currency string, start, end time.Time) (int64, error) {
+	var result struct {
+		Total int64
+	}
+
+	if currency == string(models.Coin) || currency == string(models.Diamond) {
+		if err := config.Database.Model(&models.UserCurrencyFlow{}).
+			Select("COALESCE(SUM(-price), 0) AS total").
+			Where("user_id = ? AND cost_type = ? AND price < 0 AND ref_type IN (?) AND ctime >= ? AND ctime < ?",
+				userID, currency, []string{"", models.FlowRefPurchase}, start, end).
+			Scan(&result).Error; err != nil {
+			return 0, err
+		}
+		var refunded struct {
+			Total int64
+		}
+		if err := config.Database.Table("purchase_refunds AS r").
+			Joins("JOIN user_currency_flow AS f ON f.id = r.flow_id").
+			Select("COALESCE(SUM(r.amount), 0) AS total").
+			Where("f.user_id = ? AND f.cost_type = ? AND f.ctime >= ? AND f.ctime < ?", userID, currency, start, end).
+			Scan(&refunded).Error; err != nil {
+			return 0, err
+		}
+		return result.Total - refunded.Total, nil
+	}
+
+	if err := config.Database.Model(&models.RechargeOrder{}).
+		Select("COALESCE(SUM(amount), 0) AS total").
+		Where("user_id = ? AND currency = ? AND status IN (?) AND paid_at >= ? AND paid_at < ?",
+			userID, currency, []models.RechargeOrderStatus{models.RechargeStatusPaid, models.RechargeStatusFulfilled}, start, end).
+		Scan(&result).Error; err != nil {
+		return 0, err
+	}
+	return result.Total, nil
+}
+
+// userLocation 获取玩家时区，未设置或无效时使用默认时区
+func (s *spendLimitService) userLocation(userID uint) *time.Location {
+	var user models.User
+	if err := config.Database.Select("timezone").Where("uid = ?", userID).First(&user).Error; err != nil {
+		return timezoneLocation("")
+	}
+	return timezoneLocation(user.Timezone)
+}
+
+// timezoneLocation 解析玩家时区，为空或无效时使用默认时区
+func timezoneLocation(timezone string) *time.Location {
+	if timezone != "" {
+		if loc, err := time.LoadLocation(timezone); err == nil {
+			return loc
+		}
+	}
+
+	if loc, err := time.LoadLocation(config.GetSpendLimitConfig().DefaultTimezone); err == nil {
+		return loc
+	}
+	return time.Local
+}
+
+// effectiveLimit 取全局、管理员和自我限额中最严格的一个
+func effectiveLimit(limits []*models.SpendLimit, currency string, period models.SpendPeriod) (int64, bool) {
+	var result int64
+	found := false
+	for _, limit := range limits {
+		if limit.Currency != currency || limit.Period != period {
+			continue
+		}
+		if !found || limit.Amount < result {
+			result = limit.Amount
+			found = true
+		}
+	}
+	return result, found
+}
+
+// periodWindow 计算now所在周期的起止时间
+func periodWindow(period models.SpendPeriod, now time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := now.In(loc)
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	switch period {
+	case models.SpendPeriodWeekly:
+		offset := (int(dayStart.Weekday()) + 6) % 7 // 周一为一周的第一天
+		start := dayStart.AddDate(0, 0, -offset)
+		return start, start.AddDate(0, 0, 7)
+	case models.SpendPeriodMonthly:
+		start := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return dayStart, dayStart.AddDate(0, 0, 1)
+	}
+}
+
+// periodKey 周期标识，用于区分不同周期的计数器
+func periodKey(period models.SpendPeriod, start time.Time) string {
+	return fmt.Sprintf("%s%s", period, start.Format("20060102"))
+}
+
+// periodName 周期的中文名称
+func periodName(period models.SpendPeriod) string {
+	switch period {
+	case models.SpendPeriodWeekly:
+		return "每周"
+	case models.SpendPeriodMonthly:
+		return "每月"
+	default:
+		return "每日"
+	}
+}
+
+// periodOrder 周期排序
+func periodOrder(period models.SpendPeriod) int {
+	for i, item := range spendPeriods {
+		if item == period {
+			return i
+		}
+	}
+	return len(spendPeriods)
+}
+
+// validateSpendLimit 校验限额参数
+func validateSpendLimit(currency string, period models.SpendPeriod, amount int64) error {
+	if currency != string(models.Coin) && currency != string(models.Diamond) && !fiatCurrencyPattern.MatchString(currency) {
+		return errors.New("无效的货币类型")
+	}
+	if periodOrder(period) == len(spendPeriods) {
+		return errors.New("无效的统计周期")
+	}
+	if amount < 0 {
+		return errors.New("限额不能为负数")
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"sort"
+
+	"github.com/jinzhu/gorm"
+)
+
+// loadBundleItems 加载礼包内容
+func loadBundleItems(db *gorm.DB, bundleID uint) ([]*models.StoreBundleItem, error) {
+	var items []*models.StoreBundleItem
+	if err := db.Where("bundle_id = ?", bundleID).Order("id asc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// stockDemand 一次结算需要扣减的库存，礼包同时扣减礼包本身和其中每个商品的库存
+type stockDemand struct {
+	stores   map[uint]*models.Store
+	quantity map[uint]int64
+	bundles  map[uint][]*models.StoreBundleItem
+}
+
+// planStock 汇总结算中每个商品需要的库存并检查是否充足，同一商品在多行或多个礼包中出现时共用同一份数据
+func planStock(tx *gorm.DB, lines []*PricingLine) (*stockDemand, error) {
+	demand := &stockDemand{
+		stores:   make(map[uint]*models.Store),
+		quantity: make(map[uint]int64),
+		bundles:  make(map[uint][]*models.StoreBundleItem),
+	}
+	for _, line := range lines {
+		demand.stores[line.Store.ID] = line.Store
+		demand.quantity[line.Store.ID] += line.Quantity
+	}
+
+	for _, line := range lines {
+		if line.Store.StoreType != models.StoreTypeBundle {
+			continue
+		}
+		contents, err := loadBundleItems(tx, line.Store.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(contents) == 0 {
+			return nil, fmt.Errorf("礼包%s内容为空", line.Store.Name)
+		}
+		demand.bundles[line.Store.ID] = contents
+
+		for _, content := range contents {
+			if content.ItemType != models.ItemTypeGoods {
+				continue
+			}
+			if _, ok := demand.stores[content.StoreID]; !ok {
+				var store models.Store
+				if err := tx.First(&store, content.StoreID).Error; err != nil {
+					return nil, fmt.Errorf("礼包%s中的商品%d不存在", line.Store.Name, content.StoreID)
+				}
+				demand.stores[store.ID] = &store
+			}
+			demand.quantity[content.StoreID] += content.Num * line.Quantity
+		}
+	}
+
+	for _, storeID := range demand.storeIDs() {
+		if store := demand.stores[storeID]; store.Stock < demand.quantity[storeID] {
+			return nil, fmt.Errorf("%w：%s", ErrInsufficientStock, store.Name)
+		}
+	}
+	return demand, nil
+}
+
+// storeIDs 按商品ID排序，保证扣减库存的顺序一致
+func (d *stockDemand) storeIDs() []uint {
+	ids := make([]uint, 0, len(d.quantity))
+	for id := range d.quantity {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// apply 扣减库存（基于版本号条件更新）
+func (d *stockDemand) apply(tx *gorm.DB) error {
+	for _, storeID := range d.storeIDs() {
+		if err := updateStoreStockWithVersion(tx, d.stores[storeID], -d.quantity[storeID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grantBundleCurrency 发放礼包中的货币并记录流水。
+// 同一事务中扣款后的经验升级可能已向该钱包发放奖励并修改版本号，因此发放前重新读取钱包
+func grantBundleCurrency(tx *gorm.DB, order *models.Order, bundle *models.Store, content *models.StoreBundleItem, quantity int64) error {
+	wallet := &models.UserWallet{}
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("user_id = ? and type = ?", order.UserID, content.Currency).First(wallet).Error; err != nil {
+		return err
+	}
+
+	amount := content.Num * quantity
+	description := fmt.Sprintf("礼包%s x%d内含货币，订单号：%s", bundle.Name, quantity, order.OrderNo)
+	if err := changeWalletBalance(tx, wallet, amount, nil, "礼包"); err != nil {
+		return err
+	}
+	return tx.Create(&models.UserCurrencyFlow{
+		UserID:      order.UserID,
+		StoreID:     bundle.ID,
+		CostType:    string(content.Currency),
+		Description: description,
+		Price:       amount,
+		RefType:     models.FlowRefBundle,
+		RefID:       order.ID,
+	}).Error
+}
+
+// SetBundleItems 设置礼包内容，整体替换原有内容
+func (s *storeService) SetBundleItems(bundleID uint, items []*models.StoreBundleItem) ([]*models.StoreBundleItem, error) {
+	if len(items) == 0 {
+		return nil, errors.New("礼包内容不能为空")
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var bundle models.Store
+	if err := tx.First(&bundle, bundleID).Error; err != nil {
+		SafeRollback(tx)
+		return nil, errors.New("礼包商品不存在")
+	}
+	if bundle.StoreType != models.StoreTypeBundle {
+		SafeRollback(tx)
+		return nil, errors.New("该商品不是礼包")
+	}
+
+	for _, item := range items {
+		if item.Num <= 0 {
+			SafeRollback(tx)
+			return nil, errors.New("礼包内容数量必须大于0")
+		}
+		switch item.ItemType {
+		case models.ItemTypeGoods:
+			var store models.Store
+			if err := tx.First(&store, item.StoreID).Error; err != nil {
+				SafeRollback(tx)
+				return nil, fmt.Errorf("商品%d不存在", item.StoreID)
+			}
+			if store.StoreType == models.StoreTypeBundle {
+				SafeRollback(tx)
+				return nil, errors.New("礼包中不能包含礼包")
+			}
+			item.Currency = ""
+		case models.ItemTypeCurrency:
+			if item.Currency != models.Coin && item.Currency != models.Diamond {
+				SafeRollback(tx)
+				return nil, errors.New("货币类型必须是coin或diamond")
+			}
+			item.StoreID = 0
+		default:
+			SafeRollback(tx)
+			return nil, errors.New("item_type必须是0（商品）或1（货币）")
+		}
+	}
+
+	if err := tx.Where("bundle_id = ?", bundleID).Delete(&models.StoreBundleItem{}).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	for _, item := range items {
+		item.ID = 0
+		item.BundleID = bundleID
+		if err := tx.Create(item).Error; err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// FillBundleContents 为礼包填充内容、价值和节省数量。
+// 价值按礼包的支付货币计算：同货币商品按原价，同货币的货币内容按面值，其他货币的内容不计入。
+func (s *storeService) FillBundleContents(stores ...*models.StoreDTO) error {
+	bundles := make(map[uint]*models.StoreDTO)
+	for _, store := range stores {
+		if store != nil && store.StoreType == models.StoreTypeBundle {
+			bundles[store.ID] = store
+		}
+	}
+	if len(bundles) == 0 {
+		return nil
+	}
+
+	bundleIDs := make([]uint, 0, len(bundles))
+	for id := range bundles {
+		bundleIDs = append(bundleIDs, id)
+	}
+	var items []*models.StoreBundleItem
+	if err := config.Database.Where("bundle_id IN (?)", bundleIDs).Order("id asc").Find(&items).Error; err != nil {
+		return err
+	}
+
+	componentIDs := make([]uint, 0)
+	for _, item := range items {
+		if item.ItemType == models.ItemTypeGoods {
+			componentIDs = append(componentIDs, item.StoreID)
+		}
+	}
+	components := make(map[uint]*models.Store)
+	if len(componentIDs) > 0 {
+		var stores []*models.Store
+		if err := config.Database.Where("id IN (?)", componentIDs).Find(&stores).Error; err != nil {
+			return err
+		}
+		for _, store := range stores {
+			components[store.ID] = store
+		}
+	}
+
+	for _, bundle := range bundles {
+		bundle.Contents = make([]*models.StoreBundleItem, 0)
+		bundle.Value = 0
+	}
+	for _, item := range items {
+		bundle := bundles[item.BundleID]
+		bundle.Contents = append(bundle.Contents, item)
+		switch item.ItemType {
+		case models.ItemTypeGoods:
+			if component, ok := components[item.StoreID]; ok {
+				item.StoreName = component.Name
+				if component.CostType == bundle.CostType {
+					bundle.Value += component.Price * item.Num
+				}
+			}
+		case models.ItemTypeCurrency:
+			if string(item.Currency) == string(bundle.CostType) {
+				bundle.Value += item.Num
+			}
+		}
+	}
+	for _, bundle := range bundles {
+		if bundle.Value > bundle.Price {
+			bundle.Savings = bundle.Value - bundle.Price
+		}
+	}
+	return nil
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/utils"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PurchaseLine 一次结算中的一行商品
+type PurchaseLine struct {
+	StoreID  uint `json:"store_id"`
+	Quantity uint `json:"quantity"`
+}
+
+// checkout 在一个事务中完成多个商品的结算：
+// 1、检查用户、商品库存和限购数量 2、计算价格 3、按货币检查余额和消费限额
+// 4、生成订单 5、扣减余额并记录流水，发放商品和礼包内容，增加经验值 6、扣减库存 7、提交事务
+// gift不为空时为赠送订单：物品不放入购买者背包，而是生成礼物等待接收方接受。
+func (s *storeService) checkout(userID uint, lines []PurchaseLine, userCouponID uint, gift *GiftRequest) (*models.Order, error) {
+	lines = mergePurchaseLines(lines)
+	if len(lines) == 0 {
+		return nil, errors.New("没有需要结算的商品")
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		// 使用recover确保在panic时事务被回滚
+		if r := recover(); r != nil {
+			// 使用SafeRollback避免重复回滚错误
+			SafeRollback(tx)
+		}
+	}()
+
+	//1、检查是否有该用户，赠送时锁定用户记录保证每日赠送次数校验准确
+	userQuery := tx
+	if gift != nil {
+		userQuery = tx.Set("gorm:query_option", "FOR UPDATE")
+	}
+	var user models.User
+	if err := userQuery.Where("uid = ?", userID).First(&user).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//1.1、检查商品和库存
+	items, err := loadPricingLines(tx, lines)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	demand, err := planStock(tx, items)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//1.1、检查购买条件，赠送时检查接收方
+	owner := &user
+	if gift != nil {
+		if owner, err = prepareGift(tx, &user, gift, items); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+	stores := make([]*models.Store, len(items))
+	for i, item := range items {
+		stores[i] = item.Store
+	}
+	if err := checkStoreRequirements(tx, owner, stores, time.Now()); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//1.2、检查并累加限购数量
+	now := time.Now()
+	for _, item := range items {
+		if err := consumePurchaseLimit(tx, &user, item.Store, item.Quantity, now); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	//1.3、锁定要使用的优惠券
+	var coupon *models.UserCoupon
+	if userCouponID != 0 {
+		if coupon, err = loadUserCoupon(tx.Set("gorm:query_option", "FOR UPDATE"), userID, userCouponID); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	//2、计算价格，与报价使用同一个价格引擎，保证报价和实际扣款一致
+	if _, err := s.pricingEngine.Price(&PricingContext{DB: tx, User: &user, Now: now, Lines: items, Coupon: coupon}); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//3、按货币检查余额和消费限额
+	totals := make(map[string]int64)
+	currencies := make([]string, 0)
+	for _, item := range items {
+		costType := string(item.Store.CostType)
+		if _, ok := totals[costType]; !ok {
+			currencies = append(currencies, costType)
+		}
+		totals[costType] += item.Final
+	}
+	sort.Strings(currencies)
+
+	// 预占消费额度，事务未提交时释放
+	committed := false
+	reservations := make([]*SpendReservation, 0, len(currencies))
+	defer func() {
+		if !committed {
+			for _, reservation := range reservations {
+				s.spendLimitService.ReleaseSpend(reservation)
+			}
+		}
+	}()
+
+	wallets := make(map[string]*models.UserWallet, len(currencies))
+	for _, costType := range currencies {
+		var wallet models.UserWallet
+		if err := tx.Where("user_id = ? and type = ?", userID, costType).First(&wallet).Error; err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+		if wallet.Num < totals[costType] {
+			SafeRollback(tx)
+			return nil, ErrInsufficientFunds
+		}
+		reservation, err := s.spendLimitService.ReserveSpend(userID, costType, totals[costType])
+		if err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+		wallets[costType] = &wallet
+	}
+
+	//4、生成订单，记录商品名称和价格快照
+	order := &models.Order{
+		OrderNo:  utils.GenerateOrderNo("SO"),
+		UserID:   userID,
+		CostType: models.OrderCostTypeMixed,
+		Status:   models.OrderStatusPaid,
+		PaidAt:   &now,
+		Items:    make([]models.OrderItem, 0, len(items)),
+	}
+	if len(currencies) == 1 {
+		order.CostType = currencies[0]
+		for _, item := range items {
+			order.OriginalPrice += item.Original
+			order.DiscountAmount += item.Original - item.Final
+			order.FinalPrice += item.Final
+		}
+	}
+	for _, item := range items {
+		order.Items = append(order.Items, models.OrderItem{
+			StoreID:        item.Store.ID,
+			StoreName:      item.Store.Name,
+			CostType:       string(item.Store.CostType),
+			UnitPrice:      item.Store.Price,
+			Quantity:       item.Quantity,
+			OriginalPrice:  item.Original,
+			DiscountAmount: item.Original - item.Final,
+			FinalPrice:     item.Final,
+		})
+	}
+	if err := tx.Create(order).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//4.1、记录优惠明细
+	for i, item := range items {
+		for _, adjustment := range item.Adjustments {
+			discount := models.OrderDiscount{
+				OrderID:     order.ID,
+				OrderItemID: order.Items[i].ID,
+				Type:        adjustment.Type,
+				Name:        adjustment.Name,
+				CostType:    string(item.Store.CostType),
+				Amount:      adjustment.Amount,
+			}
+			if err := tx.Create(&discount).Error; err != nil {
+				SafeRollback(tx)
+				return nil, err
+			}
+			order.Discounts = append(order.Discounts, discount)
+		}
+	}
+
+	//4.2、核销优惠券
+	if coupon != nil {
+		if err := redeemUserCoupon(tx, coupon, order.ID, now); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	//5、扣减余额（基于版本号条件更新，余额不允许为负数）
+	for _, costType := range currencies {
+		if err := debitOrderPayment(tx, wallets[costType], order.ID, totals[costType]); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	//5.1、记录交易流水，发放商品或礼包内容，增加经验值；赠送时生成礼物
+	for _, item := range items {
+		if gift != nil {
+			if err := recordPurchase(tx, s.levelService, order, item.Store, item.Quantity, item.Final); err != nil {
+				SafeRollback(tx)
+				return nil, err
+			}
+			if order.Gift, err = createGift(tx, order, gift, item, now); err != nil {
+				SafeRollback(tx)
+				return nil, err
+			}
+			continue
+		}
+		if err := deliverPurchase(tx, s.levelService, order, item.Store, item.Quantity, item.Final); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+		for _, content := range demand.bundles[item.Store.ID] {
+			if err := deliverBundleContent(tx, order, item.Store, content, item.Quantity); err != nil {
+				SafeRollback(tx)
+				return nil, err
+			}
+		}
+	}
+
+	//6、扣减库存（基于版本号条件更新），礼包同时扣减其中商品的库存
+	if err := demand.apply(tx); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//7、提交事务
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	committed = true
+
+	// 删除缓存记录
+	clearBackpackCache(userID)
+	if order.Gift != nil {
+		clearBackpackCache(order.Gift.RecipientID)
+	}
+	clearWalletCache(userID)
+
+	order.FillTotals()
+	return order, nil
+}
+
+// deliverPurchase 为订单中的一行商品记录交易流水、销量和经验值，并增加用户背包。
+// 礼包本身不放入背包，礼包内容由deliverBundleContent发放。
+func deliverPurchase(tx *gorm.DB, levelService LevelService, order *models.Order, store *models.Store, quantity int64, paid int64) error {
+	if err := recordPurchase(tx, levelService, order, store, quantity, paid); err != nil {
+		return err
+	}
+	if store.StoreType == models.StoreTypeBundle {
+		return nil
+	}
+	return addBackpackItem(tx, order.UserID, store.ID, quantity)
+}
+
+// recordPurchase 为订单中的一行商品记录交易流水（每行一条，便于按行退款），累加销量，增加购买者经验值
+func recordPurchase(tx *gorm.DB, levelService LevelService, order *models.Order, store *models.Store, quantity int64, paid int64) error {
+	flow := models.UserCurrencyFlow{
+		UserID:      order.UserID,
+		StoreID:     store.ID,
+		CostType:    string(store.CostType),
+		Description: fmt.Sprintf("购买商品:%s x%d，订单号：%s", store.Name, quantity, order.OrderNo),
+		Price:       -paid,
+		Quantity:    quantity,
+		RefType:     models.FlowRefPurchase,
+		RefID:       order.ID,
+	}
+	if err := tx.Create(&flow).Error; err != nil {
+		return err
+	}
+
+	// 累加销量
+	if err := addStoreSales(tx, store.ID, quantity); err != nil {
+		return err
+	}
+
+	// 增加经验值，使用levelService处理经验值增加和可能的升级
+	if expToAdd := purchaseExp(string(store.CostType), paid); expToAdd > 0 {
+		description := fmt.Sprintf("购买%s商品:%s, 价格为:%d", store.CostType, store.Name, paid)
+		if _, err := levelService.AddExpeirence(tx, order.UserID, expToAdd, description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addStoreSales 累加商品销量，退款时传入负数。不修改版本号，避免与同一事务中的库存扣减冲突
+func addStoreSales(tx *gorm.DB, storeID uint, quantity int64) error {
+	return tx.Model(&models.Store{}).Where("id = ?", storeID).
+		UpdateColumn("sales_count", gorm.Expr("GREATEST(sales_count + ?, 0)", quantity)).Error
+}
+
+// deliverBundleContent 发放礼包中的一项内容
+func deliverBundleContent(tx *gorm.DB, order *models.Order, bundle *models.Store, content *models.StoreBundleItem, quantity int64) error {
+	switch content.ItemType {
+	case models.ItemTypeGoods:
+		return addBackpackItem(tx, order.UserID, content.StoreID, content.Num*quantity)
+	case models.ItemTypeCurrency:
+		return grantBundleCurrency(tx, order, bundle, content, quantity)
+	}
+	return nil
+}
+
+// addBackpackItem 增加用户背包中的物品数量
+func addBackpackItem(tx *gorm.DB, userID uint, storeID uint, quantity int64) error {
+	var bag models.Backpack
+	if err := tx.Where("user_id = ? and store_id = ?", userID, storeID).FirstOrCreate(&bag, models.Backpack{
+		UserID:   userID,
+		StoreID:  storeID,
+		Quantity: 0,
+	}).Error; err != nil {
+		return err
+	}
+	bag.Quantity += quantity
+	return tx.Save(&bag).Error
+}
+
+// loadPricingLines 加载已上架且处于可售时间内的商品并生成计价行
+func loadPricingLines(db *gorm.DB, lines []PurchaseLine) ([]*PricingLine, error) {
+	now := time.Now()
+	items := make([]*PricingLine, 0, len(lines))
+	for _, line := range lines {
+		var store models.Store
+		if err := db.Where("id = ? and status = 1", line.StoreID).First(&store).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return nil, fmt.Errorf("商品%d不存在或已下架", line.StoreID)
+			}
+			return nil, err
+		}
+		if !store.IsAvailable(now) {
+			return nil, fmt.Errorf("商品%d不在可售时间内", line.StoreID)
+		}
+		items = append(items, &PricingLine{Store: &store, Quantity: int64(line.Quantity)})
+	}
+	return items, nil
+}
+
+// mergePurchaseLines 合并同一商品的多行并按商品ID排序，保证加锁顺序一致
+func mergePurchaseLines(lines []PurchaseLine) []PurchaseLine {
+	quantities := make(map[uint]uint)
+	for _, line := range lines {
+		if line.StoreID == 0 || line.Quantity == 0 {
+			continue
+		}
+		quantities[line.StoreID] += line.Quantity
+	}
+
+	merged := make([]PurchaseLine, 0, len(quantities))
+	for storeID, quantity := range quantities {
+		merged = append(merged, PurchaseLine{StoreID: storeID, Quantity: quantity})
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].StoreID < merged[j].StoreID
+	})
+	return merged
+}
+
+// purchaseExp 计算购买商品获得的经验值：金币消费的一半，钻石消费的全部
+func purchaseExp(costType string, amount int64) uint {
+	switch costType {
+	case string(models.Coin):
+		return uint(amount) / 2
+	case string(models.Diamond):
+		return uint(amount)
+	}
+	return 0
+}
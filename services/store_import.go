@@ -0,0 +1,618 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// storeImportMaxRows 单次导入的最大行数
+const storeImportMaxRows = 5000
+
+// utf8BOM 导出的CSV带BOM，便于表格软件正确识别中文
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// StoreImportService 商品批量导入导出服务接口
+type StoreImportService interface {
+	// 导出全部商品
+	Export(format models.StoreFileFormat) ([]byte, error)
+	// 导入商品，dryRun为true时只校验并返回差异，不写入数据；operatorID记录在修改商品的版本历史中
+	Import(data []byte, format models.StoreFileFormat, dryRun bool, operatorID uint) (*models.StoreImportResult, error)
+}
+
+type storeImportService struct{}
+
+// NewStoreImportService 创建商品批量导入导出服务实例
+func NewStoreImportService() StoreImportService {
+	return &storeImportService{}
+}
+
+// storeColumnKind 导入导出列的值类型，决定JSON中的类型
+type storeColumnKind int
+
+const (
+	storeColumnText storeColumnKind = iota
+	storeColumnNumber
+	storeColumnTime
+)
+
+// storeColumnMode 导入时列的处理方式
+type storeColumnMode int
+
+const (
+	storeColumnEditable   storeColumnMode = iota // 新建和修改时都可以设置
+	storeColumnCreateOnly                        // 只能在新建时设置，与修改商品接口保持一致
+	storeColumnReadOnly                          // 只导出，导入时忽略
+)
+
+// storeColumn 导入导出的一列，值统一转换为字符串处理
+type storeColumn struct {
+	name string
+	kind storeColumnKind
+	mode storeColumnMode
+	get  func(s *models.Store) string
+	set  func(s *models.Store, value string) error
+}
+
+// storeColumns 导入导出的列，顺序即CSV的列顺序。id用于定位已有商品，没有id时按name定位
+var storeColumns = []*storeColumn{
+	uintColumn("id", storeColumnReadOnly, func(s *models.Store) *uint { return &s.ID }),
+	textColumn("name", storeColumnEditable, func(s *models.Store) *string { return &s.Name }),
+	textColumn("description", storeColumnEditable, func(s *models.Store) *string { return &s.Description }),
+	int64Column("price", storeColumnEditable, func(s *models.Store) *int64 { return &s.Price }),
+	int64Column("stock", storeColumnEditable, func(s *models.Store) *int64 { return &s.Stock }),
+	{
+		name: "store_type", mode: storeColumnCreateOnly,
+		get: func(s *models.Store) string { return string(s.StoreType) },
+		set: func(s *models.Store, value string) error { s.StoreType = models.StoreType(value); return nil },
+	},
+	{
+		name: "status", kind: storeColumnNumber, mode: storeColumnEditable,
+		get: func(s *models.Store) string { return strconv.Itoa(s.Status) },
+		set: func(s *models.Store, value string) error {
+			status, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("status必须是整数")
+			}
+			s.Status = status
+			return nil
+		},
+	},
+	{
+		name: "cost_type", mode: storeColumnEditable,
+		get: func(s *models.Store) string { return string(s.CostType) },
+		set: func(s *models.Store, value string) error { s.CostType = models.CostType(value); return nil },
+	},
+	{
+		name: "tag", mode: storeColumnCreateOnly,
+		get: func(s *models.Store) string { return string(s.Tag) },
+		set: func(s *models.Store, value string) error { s.Tag = models.Tag(value); return nil },
+	},
+	int64Column("sale_price", storeColumnEditable, func(s *models.Store) *int64 { return &s.SalePrice }),
+	timeColumn("sale_start_at", func(s *models.Store) **time.Time { return &s.SaleStartAt }),
+	timeColumn("sale_end_at", func(s *models.Store) **time.Time { return &s.SaleEndAt }),
+	{
+		name: "limit_period", mode: storeColumnEditable,
+		get: func(s *models.Store) string { return string(s.LimitPeriod) },
+		set: func(s *models.Store, value string) error {
+			s.LimitPeriod = models.PurchaseLimitPeriod(value)
+			return nil
+		},
+	},
+	int64Column("limit_count", storeColumnEditable, func(s *models.Store) *int64 { return &s.LimitCount }),
+	timeColumn("available_from", func(s *models.Store) **time.Time { return &s.AvailableFrom }),
+	timeColumn("available_until", func(s *models.Store) **time.Time { return &s.AvailableUntil }),
+	textColumn("schedule_days", storeColumnEditable, func(s *models.Store) *string { return &s.ScheduleDays }),
+	textColumn("schedule_start", storeColumnEditable, func(s *models.Store) *string { return &s.ScheduleStart }),
+	textColumn("schedule_end", storeColumnEditable, func(s *models.Store) *string { return &s.ScheduleEnd }),
+	uintColumn("required_level", storeColumnEditable, func(s *models.Store) *uint { return &s.RequiredLevel }),
+	uintColumn("required_vip_level", storeColumnEditable, func(s *models.Store) *uint { return &s.RequiredVipLevel }),
+	uintColumn("required_store_id", storeColumnEditable, func(s *models.Store) *uint { return &s.RequiredStoreID }),
+	textColumn("image_url", storeColumnReadOnly, func(s *models.Store) *string { return &s.ImageURL }),
+	textColumn("thumbnail_url", storeColumnReadOnly, func(s *models.Store) *string { return &s.ThumbnailURL }),
+	int64Column("sales_count", storeColumnReadOnly, func(s *models.Store) *int64 { return &s.SalesCount }),
+}
+
+func textColumn(name string, mode storeColumnMode, field func(s *models.Store) *string) *storeColumn {
+	return &storeColumn{
+		name: name, mode: mode,
+		get: func(s *models.Store) string { return *field(s) },
+		set: func(s *models.Store, value string) error { *field(s) = value; return nil },
+	}
+}
+
+func int64Column(name string, mode storeColumnMode, field func(s *models.Store) *int64) *storeColumn {
+	return &storeColumn{
+		name: name, kind: storeColumnNumber, mode: mode,
+		get: func(s *models.Store) string { return strconv.FormatInt(*field(s), 10) },
+		set: func(s *models.Store, value string) error {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s必须是整数", name)
+			}
+			*field(s) = n
+			return nil
+		},
+	}
+}
+
+func uintColumn(name string, mode storeColumnMode, field func(s *models.Store) *uint) *storeColumn {
+	return &storeColumn{
+		name: name, kind: storeColumnNumber, mode: mode,
+		get: func(s *models.Store) string { return strconv.FormatUint(uint64(*field(s)), 10) },
+		set: func(s *models.Store, value string) error {
+			n, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return fmt.Errorf("%s必须是非负整数", name)
+			}
+			*field(s) = uint(n)
+			return nil
+		},
+	}
+}
+
+// timeColumn 时间列导出为RFC3339格式，导入时也接受服务器时区的2006-01-02 15:04:05，空值表示不限
+func timeColumn(name string, field func(s *models.Store) **time.Time) *storeColumn {
+	return &storeColumn{
+		name: name, kind: storeColumnTime, mode: storeColumnEditable,
+		get: func(s *models.Store) string {
+			if t := *field(s); t != nil {
+				return t.Format(time.RFC3339)
+			}
+			return ""
+		},
+		set: func(s *models.Store, value string) error {
+			if value == "" {
+				*field(s) = nil
+				return nil
+			}
+			for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04"} {
+				if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+					*field(s) = &t
+					return nil
+				}
+			}
+			return fmt.Errorf("%s格式应为RFC3339或2006-01-02 15:04:05", name)
+		},
+	}
+}
+
+// diffStoreColumns 比较可修改列的差异
+func diffStoreColumns(before, after *models.Store) []*models.StoreFieldChange {
+	var changes []*models.StoreFieldChange
+	for _, column := range storeColumns {
+		if column.mode != storeColumnEditable {
+			continue
+		}
+		if old, updated := column.get(before), column.get(after); old != updated {
+			changes = append(changes, &models.StoreFieldChange{Field: column.name, Old: old, New: updated})
+		}
+	}
+	return changes
+}
+
+// findStoreColumn 按列名查找列
+func findStoreColumn(name string) *storeColumn {
+	for _, column := range storeColumns {
+		if column.name == name {
+			return column
+		}
+	}
+	return nil
+}
+
+// Export 按ID顺序导出全部商品
+func (s *storeImportService) Export(format models.StoreFileFormat) ([]byte, error) {
+	var stores []*models.Store
+	if err := config.Database.Order("id").Find(&stores).Error; err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case models.StoreFileCSV:
+		var buf bytes.Buffer
+		buf.Write(utf8BOM)
+		writer := csv.NewWriter(&buf)
+		header := make([]string, len(storeColumns))
+		for i, column := range storeColumns {
+			header[i] = column.name
+		}
+		if err := writer.Write(header); err != nil {
+			return nil, err
+		}
+		for _, store := range stores {
+			record := make([]string, len(storeColumns))
+			for i, column := range storeColumns {
+				record[i] = column.get(store)
+			}
+			if err := writer.Write(record); err != nil {
+				return nil, err
+			}
+		}
+		writer.Flush()
+		return buf.Bytes(), writer.Error()
+	case models.StoreFileJSON:
+		// 手动拼接对象，保证字段顺序与CSV的列顺序一致
+		var buf bytes.Buffer
+		buf.WriteString("[")
+		for i, store := range stores {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n  {")
+			for j, column := range storeColumns {
+				if j > 0 {
+					buf.WriteString(", ")
+				}
+				key, _ := json.Marshal(column.name)
+				buf.Write(key)
+				buf.WriteString(": ")
+				value := column.get(store)
+				switch {
+				case column.kind == storeColumnNumber:
+					buf.WriteString(value)
+				case column.kind == storeColumnTime && value == "":
+					buf.WriteString("null")
+				default:
+					encoded, err := json.Marshal(value)
+					if err != nil {
+						return nil, err
+					}
+					buf.Write(encoded)
+				}
+			}
+			buf.WriteString("}")
+		}
+		buf.WriteString("\n]\n")
+		return buf.Bytes(), nil
+	default:
+		return nil, errors.New("format必须是csv或json")
+	}
+}
+
+// storeRecord 导入文件中的一行，只包含文件中出现的列
+type storeRecord struct {
+	row    int
+	values map[string]string
+	errors []string
+}
+
+// parseStoreFile 解析导入文件。CSV的表头决定导入哪些列，没有出现的列保持商品原值
+func parseStoreFile(data []byte, format models.StoreFileFormat) ([]*storeRecord, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+
+	var records []*storeRecord
+	switch format {
+	case models.StoreFileCSV:
+		reader := csv.NewReader(bytes.NewReader(data))
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("CSV格式错误: %v", err)
+		}
+		if len(rows) == 0 {
+			return nil, errors.New("导入文件为空")
+		}
+
+		header := make([]string, len(rows[0]))
+		seen := make(map[string]bool)
+		for i, name := range rows[0] {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if findStoreColumn(name) == nil {
+				return nil, fmt.Errorf("未知的列：%s", name)
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("列%s重复", name)
+			}
+			seen[name] = true
+			header[i] = name
+		}
+		if !seen["id"] && !seen["name"] {
+			return nil, errors.New("导入文件必须包含id或name列")
+		}
+
+		for i, row := range rows[1:] {
+			record := &storeRecord{row: i + 2, values: make(map[string]string, len(header))}
+			for j, value := range row {
+				record.values[header[j]] = strings.TrimSpace(value)
+			}
+			records = append(records, record)
+		}
+	case models.StoreFileJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var objects []map[string]interface{}
+		if err := decoder.Decode(&objects); err != nil {
+			return nil, fmt.Errorf("JSON格式错误，应为商品对象数组: %v", err)
+		}
+
+		for i, object := range objects {
+			record := &storeRecord{row: i + 1, values: make(map[string]string, len(object))}
+			for key, value := range object {
+				name := strings.ToLower(strings.TrimSpace(key))
+				if findStoreColumn(name) == nil {
+					record.errors = append(record.errors, fmt.Sprintf("未知的字段：%s", key))
+					continue
+				}
+				text, ok := storeFieldValue(value)
+				if !ok {
+					record.errors = append(record.errors, fmt.Sprintf("字段%s的值必须是字符串或数字", key))
+					continue
+				}
+				record.values[name] = text
+			}
+			records = append(records, record)
+		}
+	default:
+		return nil, errors.New("format必须是csv或json")
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("导入文件中没有商品")
+	}
+	if len(records) > storeImportMaxRows {
+		return nil, fmt.Errorf("单次最多导入%d个商品", storeImportMaxRows)
+	}
+	return records, nil
+}
+
+// storeFieldValue 将JSON中的字段值转换为字符串，null表示空值，只接受字符串和数字。
+// 未启用UseNumber解码的数字为float64，按不带指数的格式转换
+func storeFieldValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", true
+	case string:
+		return strings.TrimSpace(v), true
+	case json.Number:
+		return v.String(), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// storeImportPlan 一行导入数据对应的商品修改
+type storeImportPlan struct {
+	result   *models.StoreImportRow
+	existing *models.Store // 修改前的商品，新建时为空
+	target   *models.Store // 修改后的商品
+}
+
+// Import 导入商品：先逐行定位已有商品、应用文件中的值并校验，再比较差异。
+// 有任意一行校验未通过或dryRun为true时不写入数据；否则在一个事务中写入所有新建和修改的商品
+func (s *storeImportService) Import(data []byte, format models.StoreFileFormat, dryRun bool, operatorID uint) (*models.StoreImportResult, error) {
+	records, err := parseStoreFile(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	db := config.Database
+	if !dryRun {
+		db = config.Database.Begin()
+		defer func() {
+			if r := recover(); r != nil {
+				SafeRollback(db)
+			}
+		}()
+	}
+	rollback := func() {
+		if !dryRun {
+			SafeRollback(db)
+		}
+	}
+
+	plans, err := planStoreImport(db, records, !dryRun)
+	if err != nil {
+		rollback()
+		return nil, err
+	}
+
+	result := &models.StoreImportResult{DryRun: dryRun, Rows: make([]*models.StoreImportRow, len(plans))}
+	for i, plan := range plans {
+		result.Rows[i] = plan.result
+		switch plan.result.Status {
+		case models.StoreImportCreated:
+			result.Created++
+		case models.StoreImportUpdated:
+			result.Updated++
+		case models.StoreImportUnchanged:
+			result.Unchanged++
+		case models.StoreImportInvalid:
+			result.Invalid++
+		}
+	}
+	if dryRun || result.Invalid > 0 {
+		rollback()
+		return result, nil
+	}
+
+	now := time.Now()
+	for _, plan := range plans {
+		switch plan.result.Status {
+		case models.StoreImportCreated:
+			status := plan.target.Status
+			if err := db.Create(plan.target).Error; err != nil {
+				rollback()
+				return nil, err
+			}
+			// status为0时会被数据库默认值覆盖，需要单独更新
+			if status != plan.target.Status {
+				plan.target.Status = status
+				if err := db.Model(plan.target).UpdateColumn("status", status).Error; err != nil {
+					rollback()
+					return nil, err
+				}
+			}
+			if err := linkTagCategory(db, plan.target); err != nil {
+				rollback()
+				return nil, err
+			}
+			plan.result.ID = plan.target.ID
+		case models.StoreImportUpdated:
+			plan.target.Version++
+			if err := db.Omit(append([]string{"sales_count"}, models.RatingColumns...)...).Save(plan.target).Error; err != nil {
+				rollback()
+				return nil, err
+			}
+			if err := notifyWishlist(db, plan.existing, plan.target, now); err != nil {
+				rollback()
+				return nil, err
+			}
+			if _, err := recordStoreVersion(db, plan.existing, plan.target, models.StoreVersionSourceImport, operatorID, now); err != nil {
+				rollback()
+				return nil, err
+			}
+		}
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return nil, err
+	}
+	result.Applied = true
+	if result.Created > 0 || result.Updated > 0 {
+		invalidateStoreCatalog()
+	}
+	return result, nil
+}
+
+// planStoreImport 为每一行生成商品修改计划并校验，lock为true时锁定已有商品。
+// 所有行应用完文件中的值后再统一校验，前置商品链按本次导入后的数据检查
+func planStoreImport(db *gorm.DB, records []*storeRecord, lock bool) ([]*storeImportPlan, error) {
+	query := db
+	if lock {
+		query = db.Set("gorm:query_option", "FOR UPDATE")
+	}
+
+	plans := make([]*storeImportPlan, len(records))
+	pending := make(map[uint]*models.Store)
+	rowOfStore := make(map[uint]int)
+	rowOfName := make(map[string]int)
+
+	for i, record := range records {
+		row := &models.StoreImportRow{Row: record.row, Errors: record.errors}
+		plan := &storeImportPlan{result: row}
+		plans[i] = plan
+
+		// 1、定位已有商品：有id时按id，否则按name
+		var existing models.Store
+		var findErr error
+		if value := record.values["id"]; value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || id == 0 {
+				row.Errors = append(row.Errors, "id必须是正整数")
+				continue
+			}
+			if findErr = query.First(&existing, id).Error; gorm.IsRecordNotFoundError(findErr) {
+				row.Errors = append(row.Errors, fmt.Sprintf("商品%d不存在", id))
+				continue
+			}
+		} else if name := record.values["name"]; name != "" {
+			findErr = query.Where("name = ?", name).First(&existing).Error
+		} else {
+			row.Errors = append(row.Errors, "id和name不能同时为空")
+			continue
+		}
+		if findErr != nil && !gorm.IsRecordNotFoundError(findErr) {
+			return nil, findErr
+		}
+
+		// 2、在已有商品（或新商品的默认值）上应用文件中的值
+		target := &models.Store{Status: 1}
+		if findErr == nil {
+			before := existing
+			plan.existing = &before
+			copied := existing
+			target = &copied
+			row.ID = existing.ID
+			if first, ok := rowOfStore[existing.ID]; ok {
+				row.Errors = append(row.Errors, fmt.Sprintf("与第%d行是同一个商品", first))
+				continue
+			}
+			rowOfStore[existing.ID] = record.row
+		}
+		for _, column := range storeColumns {
+			value, ok := record.values[column.name]
+			if !ok || column.mode == storeColumnReadOnly {
+				continue
+			}
+			if column.mode == storeColumnCreateOnly && plan.existing != nil {
+				if value != column.get(plan.existing) {
+					row.Errors = append(row.Errors, fmt.Sprintf("已有商品不能通过导入修改%s", column.name))
+				}
+				continue
+			}
+			if value == "" && column.kind == storeColumnNumber {
+				value = "0"
+			}
+			if err := column.set(target, value); err != nil {
+				row.Errors = append(row.Errors, err.Error())
+			}
+		}
+		row.Name = target.Name
+		plan.target = target
+
+		if first, ok := rowOfName[target.Name]; ok && target.Name != "" {
+			row.Errors = append(row.Errors, fmt.Sprintf("name与第%d行重复", first))
+		}
+		rowOfName[target.Name] = record.row
+		if plan.existing != nil {
+			pending[target.ID] = target
+		}
+	}
+
+	lookup := func(id uint) (*models.Store, error) {
+		if store, ok := pending[id]; ok {
+			return store, nil
+		}
+		var store models.Store
+		if err := db.First(&store, id).Error; err != nil {
+			return nil, err
+		}
+		return &store, nil
+	}
+
+	// 3、校验并比较差异
+	for _, plan := range plans {
+		row := plan.result
+		if len(row.Errors) == 0 {
+			if err := validateImportedStore(db, plan, lookup); err != nil {
+				row.Errors = append(row.Errors, err.Error())
+			}
+		}
+		if len(row.Errors) > 0 {
+			row.Status = models.StoreImportInvalid
+			continue
+		}
+
+		if plan.existing == nil {
+			row.Status = models.StoreImportCreated
+			continue
+		}
+		row.Changes = diffStoreColumns(plan.existing, plan.target)
+		row.Status = models.StoreImportUnchanged
+		if len(row.Changes) > 0 {
+			row.Status = models.StoreImportUpdated
+		}
+	}
+	return plans, nil
+}
+
+// validateImportedStore 校验导入后的商品：新建商品使用创建商品的规则，已有商品使用修改商品的规则
+func validateImportedStore(db *gorm.DB, plan *storeImportPlan, lookup StoreLookup) error {
+	if err := validateStoreBasics(db, plan.target); err != nil {
+		return err
+	}
+	if plan.existing == nil {
+		return ValidateStore(plan.target, lookup)
+	}
+	return validateStoreUpdate(plan.target, lookup)
+}
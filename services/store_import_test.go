@@ -0,0 +1,49 @@
+package services
+
+import (
+	"goDDD1/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseStoreFile 测试CSV和JSON导入文件的解析
+func TestParseStoreFile(t *testing.T) {
+	csvData := append(append([]byte{}, utf8BOM...), []byte("Name,price,sale_start_at\n Sword ,100,\nShield,abc,2024-05-01 10:00:00\n")...)
+	records, err := parseStoreFile(csvData, models.StoreFileCSV)
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, 2, records[0].row)
+	assert.Equal(t, map[string]string{"name": "Sword", "price": "100", "sale_start_at": ""}, records[0].values)
+
+	_, err = parseStoreFile([]byte("name,color\nSword,red\n"), models.StoreFileCSV)
+	assert.Error(t, err)
+	_, err = parseStoreFile([]byte("price\n100\n"), models.StoreFileCSV)
+	assert.Error(t, err)
+
+	records, err = parseStoreFile([]byte(`[{"id": 3, "price": 250, "sale_end_at": null, "color": "red", "status": true}]`), models.StoreFileJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"id": "3", "price": "250", "sale_end_at": ""}, records[0].values)
+	assert.Len(t, records[0].errors, 2)
+
+	_, err = parseStoreFile([]byte(`{"id": 3}`), models.StoreFileJSON)
+	assert.Error(t, err)
+}
+
+// TestStoreColumns 测试导入导出列的读写
+func TestStoreColumns(t *testing.T) {
+	store := &models.Store{}
+	assert.NoError(t, findStoreColumn("price").set(store, "120"))
+	assert.Error(t, findStoreColumn("price").set(store, "1.5"))
+	assert.Error(t, findStoreColumn("required_level").set(store, "-1"))
+	assert.Equal(t, "120", findStoreColumn("price").get(store))
+
+	column := findStoreColumn("available_from")
+	assert.NoError(t, column.set(store, "2024-05-01T10:00:00+08:00"))
+	assert.True(t, store.AvailableFrom.Equal(time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)))
+	assert.NoError(t, column.set(store, ""))
+	assert.Nil(t, store.AvailableFrom)
+	assert.Equal(t, "", column.get(store))
+	assert.Error(t, column.set(store, "tomorrow"))
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/utils"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jinzhu/gorm"
+)
+
+// StoreEventHandler 商品事件处理函数
+type StoreEventHandler func(event *models.StoreEvent)
+
+var (
+	storeEventMu       sync.RWMutex
+	storeEventHandlers []StoreEventHandler
+)
+
+// SubscribeStoreEvents 订阅商品上下架事件，处理函数在检查任务中同步调用
+func SubscribeStoreEvents(handler StoreEventHandler) {
+	storeEventMu.Lock()
+	defer storeEventMu.Unlock()
+	storeEventHandlers = append(storeEventHandlers, handler)
+}
+
+// publishStoreEvents 发布商品事件：先使商品列表缓存失效，再通知订阅者
+func publishStoreEvents(events []*models.StoreEvent) {
+	if len(events) == 0 {
+		return
+	}
+	invalidateStoreCatalog()
+
+	storeEventMu.RLock()
+	handlers := storeEventHandlers
+	storeEventMu.RUnlock()
+
+	for _, event := range events {
+		log.Printf("商品%d(%s)%s", event.StoreID, event.StoreName, storeEventDescription(event.Type))
+		for _, handler := range handlers {
+			handler(event)
+		}
+	}
+}
+
+func storeEventDescription(eventType models.StoreEventType) string {
+	if eventType == models.StoreEventLive {
+		return "进入可售时间"
+	}
+	return "离开可售时间"
+}
+
+// whereAvailable 只查询指定时间处于可售时间内的商品，条件与Store.IsAvailable保持一致
+func whereAvailable(db *gorm.DB, now time.Time) *gorm.DB {
+	clock := now.Format("15:04")
+	return db.Where("available_from IS NULL OR available_from <= ?", now).
+		Where("available_until IS NULL OR available_until > ?", now).
+		Where("schedule_days = '' OR FIND_IN_SET(?, schedule_days) > 0", models.ISOWeekday(now)).
+		Where("schedule_start = '' OR schedule_start <= ?", clock).
+		Where("schedule_end = '' OR schedule_end > ?", clock)
+}
+
+// loadStoreCatalog 读取商品列表缓存，未命中时调用load查询并写入缓存。
+// 缓存键包含版本号，商品变更或上下架时递增版本号即可使所有列表缓存失效；
+// 库存变化不会使缓存失效，列表中的库存最多延迟一个缓存周期，购买时以数据库库存为准。
+func loadStoreCatalog(variant string, dest interface{}, load func() error) error {
+	ttl := config.GetStoreConfig().CatalogCacheTTL
+	if ttl <= 0 {
+		return load()
+	}
+
+	version, err := utils.GetCounter(models.CacheKeyStoreCatalogVersion)
+	if err != nil && err != redis.Nil {
+		log.Printf("读取商品列表缓存版本失败: %v", err)
+		return load()
+	}
+
+	cacheKey := fmt.Sprintf(models.CacheKeyStoreCatalog, version, variant)
+	if err := utils.GetCache(cacheKey, dest); err == nil {
+		return nil
+	}
+
+	if err := load(); err != nil {
+		return err
+	}
+	if err := utils.SetCache(cacheKey, dest, ttl); err != nil {
+		log.Printf("写入商品列表缓存失败: %v", err)
+	}
+	return nil
+}
+
+// invalidateStoreCatalog 递增缓存版本号使商品列表缓存失效
+func invalidateStoreCatalog() {
+	if _, err := utils.IncrBy(models.CacheKeyStoreCatalogVersion, 1); err != nil {
+		log.Printf("商品列表缓存失效失败: %v", err)
+	}
+}
+
+// StoreScheduleService 商品可售时间检查服务接口
+type StoreScheduleService interface {
+	// 检查上次检查以来进入或离开可售时间的商品，并发布对应事件
+	CheckTransitions() ([]*models.StoreEvent, error)
+}
+
+type storeScheduleService struct{}
+
+// NewStoreScheduleService 创建商品可售时间检查服务实例
+func NewStoreScheduleService() StoreScheduleService {
+	return &storeScheduleService{}
+}
+
+// CheckTransitions 比较每个配置了可售时间的商品在上次检查时和当前的可售状态，状态变化时发布事件。
+// 首次运行时只记录检查时间，不补发历史事件。
+func (s *storeScheduleService) CheckTransitions() ([]*models.StoreEvent, error) {
+	now := time.Now()
+
+	var last time.Time
+	if err := utils.GetCache(models.CacheKeyStoreScheduleCheck, &last); err != nil {
+		if err != redis.Nil {
+			return nil, err
+		}
+		return nil, utils.SetCache(models.CacheKeyStoreScheduleCheck, now, 0)
+	}
+	if !now.After(last) {
+		return nil, nil
+	}
+
+	// 固定时间段只查询边界落在本次检查区间内的商品，每周循环售卖的商品每次都需要比较
+	var stores []*models.Store
+	if err := config.Database.Where("status = 1").
+		Where("(available_from > ? AND available_from <= ?) OR (available_until > ? AND available_until <= ?) OR schedule_days <> '' OR schedule_start <> '' OR schedule_end <> ''",
+			last, now, last, now).
+		Find(&stores).Error; err != nil {
+		return nil, err
+	}
+
+	events := make([]*models.StoreEvent, 0)
+	for _, store := range stores {
+		before, after := store.IsAvailable(last), store.IsAvailable(now)
+		if before == after {
+			continue
+		}
+		event := &models.StoreEvent{
+			Type:      models.StoreEventExpired,
+			StoreID:   store.ID,
+			StoreName: store.Name,
+			Tag:       store.Tag,
+			At:        now,
+		}
+		if after {
+			event.Type = models.StoreEventLive
+		}
+		events = append(events, event)
+	}
+
+	publishStoreEvents(events)
+
+	if err := utils.SetCache(models.CacheKeyStoreScheduleCheck, now, 0); err != nil {
+		return events, err
+	}
+	return events, nil
+}
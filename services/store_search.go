@@ -0,0 +1,186 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"goDDD1/config"
+	"goDDD1/models"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	storeSearchDefaultLimit = 20
+	storeSearchMaxLimit     = 100
+)
+
+// storeSearchCursor 搜索分页游标，记录上一页最后一个商品的排序值和ID
+type storeSearchCursor struct {
+	Value int64 `json:"v"`
+	ID    uint  `json:"id"`
+}
+
+// SearchStores 按关键字和过滤条件搜索已上架商品，使用游标分页，相同的查询条件共享Redis缓存
+func (s *storeService) SearchStores(query *models.StoreSearchQuery) (*models.StoreSearchResult, error) {
+	normalized, cursor, err := normalizeStoreSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha1.Sum(raw)
+
+	var result models.StoreSearchResult
+	err = loadStoreCatalog("search:"+hex.EncodeToString(hash[:]), &result, func() error {
+		return searchStores(normalized, cursor, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// normalizeStoreSearchQuery 规范化查询条件并解析游标，规范化后的条件作为缓存键的一部分
+func normalizeStoreSearchQuery(query *models.StoreSearchQuery) (*models.StoreSearchQuery, *storeSearchCursor, error) {
+	normalized := *query
+	normalized.Keyword = strings.ToLower(strings.TrimSpace(query.Keyword))
+
+	switch normalized.Sort {
+	case "":
+		normalized.Sort = models.StoreSortNewest
+	case models.StoreSortNewest, models.StoreSortPriceAsc, models.StoreSortPriceDesc, models.StoreSortPopular:
+	default:
+		return nil, nil, errors.New("sort必须是newest、price_asc、price_desc或popular")
+	}
+
+	if normalized.Limit <= 0 {
+		normalized.Limit = storeSearchDefaultLimit
+	} else if normalized.Limit > storeSearchMaxLimit {
+		normalized.Limit = storeSearchMaxLimit
+	}
+
+	if normalized.MinPrice != nil && normalized.MaxPrice != nil && *normalized.MinPrice > *normalized.MaxPrice {
+		return nil, nil, errors.New("min_price不能大于max_price")
+	}
+
+	if normalized.Cursor == "" {
+		return &normalized, nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(normalized.Cursor)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+	var cursor storeSearchCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == 0 {
+		return nil, nil, ErrInvalidCursor
+	}
+	return &normalized, &cursor, nil
+}
+
+// searchStores 查询一页商品，多查一条用于判断是否还有下一页
+func searchStores(query *models.StoreSearchQuery, cursor *storeSearchCursor, result *models.StoreSearchResult) error {
+	db := config.Database.Where("status = 1")
+	if query.AvailableOnly {
+		db = whereAvailable(db, time.Now())
+	}
+	if query.Keyword != "" {
+		keyword := "%" + escapeLike(query.Keyword) + "%"
+		db = db.Where("name LIKE ? OR description LIKE ?", keyword, keyword)
+	}
+	if query.Tag != "" {
+		db = db.Where("tag = ?", query.Tag)
+	}
+	if query.CategoryID != 0 {
+		categoryIDs, err := activeCategorySubtree(config.Database, query.CategoryID)
+		if err != nil {
+			return err
+		}
+		db = db.Where("id IN (?)", config.Database.Table("store_categories").Select("store_id").Where("category_id IN (?)", categoryIDs).SubQuery())
+	}
+	if query.CostType != "" {
+		db = db.Where("cost_type = ?", query.CostType)
+	}
+	if query.StoreType != "" {
+		db = db.Where("store_type = ?", query.StoreType)
+	}
+	if query.MinPrice != nil {
+		db = db.Where("price >= ?", *query.MinPrice)
+	}
+	if query.MaxPrice != nil {
+		db = db.Where("price <= ?", *query.MaxPrice)
+	}
+	if query.InStockOnly {
+		db = db.Where("stock > 0")
+	}
+	db = orderStoreSearch(db, query.Sort, cursor)
+
+	var stores []*models.Store
+	if err := db.Limit(query.Limit + 1).Find(&stores).Error; err != nil {
+		return err
+	}
+
+	if len(stores) > query.Limit {
+		stores = stores[:query.Limit]
+		result.HasMore = true
+	}
+	result.Stores = make([]*models.StoreDTO, len(stores))
+	for i, store := range stores {
+		result.Stores[i] = store.ToStoreDTO()
+	}
+	if result.HasMore {
+		result.NextCursor = encodeStoreSearchCursor(query.Sort, stores[len(stores)-1])
+	}
+	return nil
+}
+
+// orderStoreSearch 按排序方式添加排序和游标条件，ID作为第二排序键保证顺序稳定
+func orderStoreSearch(db *gorm.DB, sort models.StoreSortType, cursor *storeSearchCursor) *gorm.DB {
+	switch sort {
+	case models.StoreSortPriceAsc:
+		if cursor != nil {
+			db = db.Where("price > ? OR (price = ? AND id > ?)", cursor.Value, cursor.Value, cursor.ID)
+		}
+		return db.Order("price asc").Order("id asc")
+	case models.StoreSortPriceDesc:
+		if cursor != nil {
+			db = db.Where("price < ? OR (price = ? AND id < ?)", cursor.Value, cursor.Value, cursor.ID)
+		}
+		return db.Order("price desc").Order("id desc")
+	case models.StoreSortPopular:
+		if cursor != nil {
+			db = db.Where("sales_count < ? OR (sales_count = ? AND id < ?)", cursor.Value, cursor.Value, cursor.ID)
+		}
+		return db.Order("sales_count desc").Order("id desc")
+	default:
+		if cursor != nil {
+			db = db.Where("id < ?", cursor.ID)
+		}
+		return db.Order("id desc")
+	}
+}
+
+// encodeStoreSearchCursor 根据本页最后一个商品生成下一页游标
+func encodeStoreSearchCursor(sort models.StoreSortType, store *models.Store) string {
+	cursor := storeSearchCursor{ID: store.ID}
+	switch sort {
+	case models.StoreSortPriceAsc, models.StoreSortPriceDesc:
+		cursor.Value = store.Price
+	case models.StoreSortPopular:
+		cursor.Value = store.SalesCount
+	}
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// escapeLike 转义LIKE中的通配符
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
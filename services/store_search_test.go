@@ -0,0 +1,31 @@
+package services
+
+import (
+	"goDDD1/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStoreSearchCursor 测试查询条件规范化和游标的生成与解析
+func TestStoreSearchCursor(t *testing.T) {
+	normalized, cursor, err := normalizeStoreSearchQuery(&models.StoreSearchQuery{Keyword: "  Sword ", Limit: 1000})
+	assert.NoError(t, err)
+	assert.Nil(t, cursor)
+	assert.Equal(t, "sword", normalized.Keyword)
+	assert.Equal(t, models.StoreSortNewest, normalized.Sort)
+	assert.Equal(t, storeSearchMaxLimit, normalized.Limit)
+
+	next := encodeStoreSearchCursor(models.StoreSortPriceAsc, &models.Store{ID: 12, Price: 300})
+	_, cursor, err = normalizeStoreSearchQuery(&models.StoreSearchQuery{Sort: models.StoreSortPriceAsc, Cursor: next})
+	assert.NoError(t, err)
+	assert.Equal(t, &storeSearchCursor{Value: 300, ID: 12}, cursor)
+
+	_, _, err = normalizeStoreSearchQuery(&models.StoreSearchQuery{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, _, err = normalizeStoreSearchQuery(&models.StoreSearchQuery{Sort: "rating"})
+	assert.Error(t, err)
+
+	assert.Equal(t, `100\%\_off`, escapeLike("100%_off"))
+}
@@ -6,8 +6,7 @@ import (
 	"fmt"
 	"goDDD1/config"
 	"goDDD1/models"
-	"goDDD1/utils"
-	"log"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
@@ -15,34 +14,62 @@ import (
 type StoreService interface {
 	CreateStore(store *models.Store) error
 	GetStoreByID(id string) (*models.Store, error)
-	UpdateStore(store *models.Store) (*models.Store, error) // 修改方法签名
-	BuyGoods(userID uint, storeID uint, num uint) error
+	// 修改商品并立即生效，修改记录在商品的版本历史中
+	UpdateStore(storeID uint, apply func(store *models.Store) error, operatorID uint) (*models.Store, error)
+	// 购买单个商品，userCouponID为0表示不使用优惠券
+	BuyGoods(userID uint, storeID uint, num uint, userCouponID uint) (*models.Order, error)
+	// 购买礼物赠送给其他玩家
+	BuyGift(userID uint, storeID uint, num uint, userCouponID uint, gift *GiftRequest) (*models.Order, error)
+	Checkout(userID uint, lines []PurchaseLine, userCouponID uint) (*models.Order, error)
+	Quote(userID uint, lines []PurchaseLine, userCouponID uint) (*models.PriceQuote, error)
 	GetStoreByTag(tag models.Tag) ([]*models.StoreDTO, error)
 	GetStoreByTagPage(tag models.Tag, page, pageSize int) ([]*models.StoreDTO, int64, error)
 	GetAllStores() ([]*models.StoreDTO, error)
+	// 按关键字、过滤条件和排序方式搜索商品，使用游标分页
+	SearchStores(query *models.StoreSearchQuery) (*models.StoreSearchResult, error)
+	// 为限购商品填充用户本周期剩余可购买数量
+	FillRemainingPurchases(userID uint, stores ...*models.StoreDTO) error
+	// 为设置了购买条件的商品填充用户是否未满足条件及原因
+	FillLockStatus(userID uint, stores ...*models.StoreDTO) error
+	// 设置礼包内容
+	SetBundleItems(bundleID uint, items []*models.StoreBundleItem) ([]*models.StoreBundleItem, error)
+	// 为礼包填充内容、价值和节省数量
+	FillBundleContents(stores ...*models.StoreDTO) error
 }
 
 type storeService struct {
-	levelService LevelService
+	levelService      LevelService
+	spendLimitService SpendLimitService
+	pricingEngine     PricingEngine
 }
 
 func NewStoreService() StoreService {
 	return &storeService{
-		levelService: NewLevelService(),
+		levelService:      NewLevelService(),
+		spendLimitService: NewSpendLimitService(),
+		pricingEngine:     NewPricingEngine(),
 	}
 }
 
+// GetStoreByTag 查询指定标签下处于可售时间内的商品
 func (s *storeService) GetStoreByTag(tag models.Tag) ([]*models.StoreDTO, error) {
-	var stores []*models.Store
-	result := config.Database.Where("tag = ?", tag).Find(&stores)
-	if result.Error != nil {
-		return nil, result.Error
-	}
+	var storeDTOs []*models.StoreDTO
+	err := loadStoreCatalog(fmt.Sprintf("tag:%s", tag), &storeDTOs, func() error {
+		var stores []*models.Store
+		result := whereAvailable(config.Database, time.Now()).Where("tag = ?", tag).Find(&stores)
+		if result.Error != nil {
+			return result.Error
+		}
 
-	// 转换为 DTO
-	storeDTOs := make([]*models.StoreDTO, len(stores))
-	for i, store := range stores {
-		storeDTOs[i] = store.ToStoreDTO()
+		// 转换为 DTO
+		storeDTOs = make([]*models.StoreDTO, len(stores))
+		for i, store := range stores {
+			storeDTOs[i] = store.ToStoreDTO()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return storeDTOs, nil
@@ -61,7 +88,17 @@ func (s *storeService) CreateStore(store *models.Store) error {
 		return err
 	}
 
-	return tx.Commit().Error
+	// 关联到Tag对应的分类
+	if err := linkTagCategory(tx, store); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	invalidateStoreCatalog()
+	return nil
 }
 func (s *storeService) GetStoreByID(id string) (*models.Store, error) {
 	var store models.Store
@@ -77,8 +114,9 @@ func (s *storeService) GetStoreByID(id string) (*models.Store, error) {
 	return &store, nil
 }
 
-// 简化的UpdateStore方法
-func (s *storeService) UpdateStore(store *models.Store) (*models.Store, error) {
+// UpdateStore 锁定商品后在最新数据上应用apply中修改的字段，apply返回错误时不保存。
+// 并发的管理员修改和购买扣减的库存不会被整行覆盖
+func (s *storeService) UpdateStore(storeID uint, apply func(store *models.Store) error, operatorID uint) (*models.Store, error) {
 	// 开始事务
 	tx := config.Database.Begin()
 	if tx.Error != nil {
@@ -92,152 +130,106 @@ func (s *storeService) UpdateStore(store *models.Store) (*models.Store, error) {
 		}
 	}()
 
-	// 直接保存store
-	if err := tx.Save(store).Error; err != nil {
+	// 锁定商品，在最新数据上修改
+	var current models.Store
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&current, storeID).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
-
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
+	store := current
+	if err := apply(&store); err != nil {
+		tx.Rollback()
 		return nil, err
 	}
 
-	return store, nil
-}
-func (s *storeService) BuyGoods(userID uint, storeID uint, num uint) error {
-	//1、开始事务
-	//2、检查是否有该用户
-	//3、检查库存是否充足
-	//4、检查wallet是否充足
-	//5、扣减余额
-	//6、扣减库存
-	//7、增加用户背包
-	//8、提交事务
-	tx := config.Database.Begin()
-	defer func() {
-		// 使用recover确保在panic时事务被回滚
-		if r := recover(); r != nil {
-			// 使用SafeRollback避免重复回滚错误
-			SafeRollback(tx)
-		}
-	}()
-
-	//2、检查是否有该用户
-	var user models.User
-	if err := tx.Where("uid = ?", userID).First(&user).Error; err != nil {
-		SafeRollback(tx)
-		return err
+	// 销量和评分统计由购买、退款和评价累加，不随商品信息覆盖。
+	// 递增版本号使读取了旧价格或库存的购买重试
+	store.Version++
+	if err := tx.Omit(append([]string{"sales_count"}, models.RatingColumns...)...).Save(&store).Error; err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
-	//3、检查库存是否充足
-	var store models.Store
-	if err := tx.Where("id = ? and status = 1", storeID).First(&store).Error; err != nil {
-		SafeRollback(tx)
-		return err
-	}
-	if store.Stock < int64(num) {
-		SafeRollback(tx)
-		return errors.New("库存不足")
+	// 降价或补货时通知收藏了该商品的玩家
+	now := time.Now()
+	if err := notifyWishlist(tx, &current, &store, now); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
-	//4、检查wallet是否充足
-	var wallet models.UserWallet
-	if err := tx.Where("user_id = ? and type = ?", userID, store.CostType).First(&wallet).Error; err != nil {
-		SafeRollback(tx)
-		return err
+	// 记录版本历史
+	if _, err := recordStoreVersion(tx, &current, &store, models.StoreVersionSourceUpdate, operatorID, now); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
-	originalPrice := store.Price * int64(num)
-	discountPrice, err := s.levelService.CalculateDiscountPrice(user.UID, uint(originalPrice))
-	if err != nil {
-		// 如果计算折扣失败，使用原价
-		discountPrice = uint(originalPrice)
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
 	}
+	invalidateStoreCatalog()
 
-	if wallet.Num < int64(discountPrice) {
-		SafeRollback(tx)
-		return errors.New("钱包不足")
-	}
+	return &store, nil
+}
 
-	//5、扣减余额
-	wallet.Num -= store.Price * int64(num)
-	if err := tx.Save(&wallet).Error; err != nil {
-		SafeRollback(tx)
-		return err
-	}
+// BuyGoods 购买单个商品并生成订单，钱包或库存版本冲突时自动重试
+func (s *storeService) BuyGoods(userID uint, storeID uint, num uint, userCouponID uint) (*models.Order, error) {
+	var order *models.Order
+	err := withOptimisticRetry(func() error {
+		var buyErr error
+		order, buyErr = s.checkout(userID, []PurchaseLine{{StoreID: storeID, Quantity: num}}, userCouponID, nil)
+		return buyErr
+	})
+	return order, err
+}
 
-	//8、添加交易流水 - 修改为使用事务
-	var userCurrencyFlow = models.UserCurrencyFlow{
-		UserID:   userID,
-		StoreID:  storeID,
-		CostType: string(store.CostType),
-		Price:    -store.Price * int64(num),
-	}
-	if err := tx.Create(&userCurrencyFlow).Error; err != nil {
-		SafeRollback(tx)
-		return err
-	}
+// BuyGift 购买礼物类型的商品赠送给其他玩家，购买者付款并获得经验，接收方接受后获得物品
+func (s *storeService) BuyGift(userID uint, storeID uint, num uint, userCouponID uint, gift *GiftRequest) (*models.Order, error) {
+	var order *models.Order
+	err := withOptimisticRetry(func() error {
+		var buyErr error
+		order, buyErr = s.checkout(userID, []PurchaseLine{{StoreID: storeID, Quantity: num}}, userCouponID, gift)
+		return buyErr
+	})
+	return order, err
+}
 
-	//6、扣减库存
-	store.Stock -= int64(num)
-	if err := tx.Save(&store).Error; err != nil {
-		SafeRollback(tx)
-		return err
-	}
+// Checkout 一次性购买多个商品，所有商品在同一个事务中结算并生成一个订单
+func (s *storeService) Checkout(userID uint, lines []PurchaseLine, userCouponID uint) (*models.Order, error) {
+	var order *models.Order
+	err := withOptimisticRetry(func() error {
+		var checkoutErr error
+		order, checkoutErr = s.checkout(userID, lines, userCouponID, nil)
+		return checkoutErr
+	})
+	return order, err
+}
 
-	//7、增加用户背包
-	var bag models.Backpack
-	if err := tx.Where("user_id = ? and store_id = ?", userID, storeID).FirstOrCreate(&bag, models.Backpack{
-		UserID:   userID,
-		StoreID:  storeID,
-		Quantity: 0,
-	}).Error; err != nil {
-		SafeRollback(tx)
-		return err
+// Quote 计算购买指定商品的价格明细，与结算使用同一个价格引擎
+func (s *storeService) Quote(userID uint, lines []PurchaseLine, userCouponID uint) (*models.PriceQuote, error) {
+	lines = mergePurchaseLines(lines)
+	if len(lines) == 0 {
+		return nil, errors.New("没有需要报价的商品")
 	}
 
-	// 删除缓存记录
-	cacheKey := fmt.Sprintf(models.CacheKeyUserBackpack, userID)
-	err = utils.DelHashField(cacheKey, "data")
-	if err == nil {
-		log.Printf("successful delete cacheKey: %s backpack", cacheKey)
+	var user models.User
+	if err := config.Database.Where("uid = ?", userID).First(&user).Error; err != nil {
+		return nil, err
 	}
 
-	// 修改BuyGoods方法中的经验值增加部分
-	//7.1、 增加经验值
-	switch store.CostType {
-	case "coin":
-		//增加经验值 - 使用levelService处理
-		expToAdd := uint(store.Price*int64(num)) / 2
-		description := fmt.Sprintf("购买%s商品:%s, 价格为:%d", store.CostType, store.Name, store.Price*int64(num))
-
-		// 使用levelService处理经验值增加和可能的升级
-		if _, err := s.levelService.AddExpeirence(tx, userID, expToAdd, description); err != nil {
-			SafeRollback(tx)
-			return err
-		}
-
-	case "diamond":
-		//增加经验值 - 使用levelService处理
-		expToAdd := uint(store.Price * int64(num))
-		description := fmt.Sprintf("购买%s商品:%s, 价格为:%d", store.CostType, store.Name, store.Price*int64(num))
-
-		// 使用levelService处理经验值增加和可能的升级
-		if _, err := s.levelService.AddExpeirence(tx, userID, expToAdd, description); err != nil {
-			SafeRollback(tx)
-			return err
-		}
+	items, err := loadPricingLines(config.Database, lines)
+	if err != nil {
+		return nil, err
 	}
 
-	bag.Quantity += int64(num)
-	if err := tx.Save(&bag).Error; err != nil {
-		SafeRollback(tx)
-		return err
+	var coupon *models.UserCoupon
+	if userCouponID != 0 {
+		if coupon, err = loadUserCoupon(config.Database, userID, userCouponID); err != nil {
+			return nil, err
+		}
 	}
 
-	//9、提交事务
-	return tx.Commit().Error
+	return s.pricingEngine.Price(&PricingContext{DB: config.Database, User: &user, Lines: items, Coupon: coupon})
 }
 
 // SafeRollback 安全回滚事务，忽略"已回滚"错误
@@ -249,6 +241,13 @@ func SafeRollback(tx *gorm.DB) {
 	}
 }
 
+// storeCatalogPage 缓存的分页商品列表
+type storeCatalogPage struct {
+	Stores []*models.StoreDTO `json:"stores"`
+	Total  int64              `json:"total"`
+}
+
+// GetStoreByTagPage 分页查询指定标签下处于可售时间内的商品
 func (s *storeService) GetStoreByTagPage(tag models.Tag, page, pageSize int) ([]*models.StoreDTO, int64, error) {
 	if page <= 0 {
 		page = 1
@@ -261,38 +260,54 @@ func (s *storeService) GetStoreByTagPage(tag models.Tag, page, pageSize int) ([]
 
 	offset := (page - 1) * pageSize
 
-	//查询总数
-	var total int64
-	if err := config.Database.Model(&models.Store{}).Where("tag = ?", tag).Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
+	var catalog storeCatalogPage
+	err := loadStoreCatalog(fmt.Sprintf("tag:%s:%d:%d", tag, page, pageSize), &catalog, func() error {
+		now := time.Now()
 
-	var stores []*models.Store
-	result := config.Database.Where("tag = ?", tag).Offset(offset).Limit(pageSize).Find(&stores)
-	if result.Error != nil {
-		return nil, 0, result.Error
-	}
-	// 转换为 DTO
-	storeDTOs := make([]*models.StoreDTO, len(stores))
-	for i, store := range stores {
-		storeDTOs[i] = store.ToStoreDTO()
+		//查询总数
+		if err := whereAvailable(config.Database.Model(&models.Store{}), now).Where("tag = ?", tag).Count(&catalog.Total).Error; err != nil {
+			return err
+		}
+
+		var stores []*models.Store
+		result := whereAvailable(config.Database, now).Where("tag = ?", tag).Order("id").Offset(offset).Limit(pageSize).Find(&stores)
+		if result.Error != nil {
+			return result.Error
+		}
+		// 转换为 DTO
+		catalog.Stores = make([]*models.StoreDTO, len(stores))
+		for i, store := range stores {
+			catalog.Stores[i] = store.ToStoreDTO()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return storeDTOs, total, nil
+	return catalog.Stores, catalog.Total, nil
 
 }
 
+// GetAllStores 查询所有处于可售时间内的商品
 func (s *storeService) GetAllStores() ([]*models.StoreDTO, error) {
-	var stores []*models.Store
-	result := config.Database.Find(&stores)
-	if result.Error != nil {
-		return nil, result.Error
-	}
+	var storeDTOs []*models.StoreDTO
+	err := loadStoreCatalog("all", &storeDTOs, func() error {
+		var stores []*models.Store
+		result := whereAvailable(config.Database, time.Now()).Find(&stores)
+		if result.Error != nil {
+			return result.Error
+		}
 
-	// 转换为 DTO
-	storeDTOs := make([]*models.StoreDTO, len(stores))
-	for i, store := range stores {
-		storeDTOs[i] = store.ToStoreDTO()
+		// 转换为 DTO
+		storeDTOs = make([]*models.StoreDTO, len(stores))
+		for i, store := range stores {
+			storeDTOs[i] = store.ToStoreDTO()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return storeDTOs, nil
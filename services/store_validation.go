@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jinzhu/gorm"
+)
+
+// maxPrerequisiteDepth 前置商品链的最大长度
+const maxPrerequisiteDepth = 10
+
+// StoreLookup 按ID查询商品，用于校验前置商品链
+type StoreLookup func(id uint) (*models.Store, error)
+
+// ValidateStore 校验新建商品的配置：货币类型、商品类型、分类、限购、可售时间和前置商品。
+// 创建商品和批量导入使用相同的规则，校验时会将可售星期整理为去重排序后的格式
+func ValidateStore(store *models.Store, lookup StoreLookup) error {
+	if store.CostType != models.CostTypeCoin && store.CostType != models.CostTypeDiamond {
+		return errors.New("CostTpye类型错误")
+	}
+	if store.StoreType != models.StoreTypeGood && store.StoreType != models.StoreTypeGift && store.StoreType != models.StoreTypeBundle {
+		return errors.New("StoreType类型错误")
+	}
+
+	var count int
+	if err := config.Database.Model(&models.Category{}).Where("code = ?", store.Tag).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("Tag对应的分类不存在")
+	}
+
+	if err := ValidatePurchaseLimit(store.LimitPeriod, store.LimitCount); err != nil {
+		return err
+	}
+	if err := ValidateAvailability(store); err != nil {
+		return err
+	}
+	return ValidatePrerequisite(store, lookup)
+}
+
+// validateStoreBasics 校验商品的名称、描述、价格、库存、状态和促销价，名称在数据库中唯一
+func validateStoreBasics(db *gorm.DB, store *models.Store) error {
+	if store.Name == "" {
+		return errors.New("name不能为空")
+	}
+	if utf8.RuneCountInString(store.Name) > 50 {
+		return errors.New("name不能超过50个字")
+	}
+	if utf8.RuneCountInString(store.Description) > 500 {
+		return errors.New("description不能超过500个字")
+	}
+	if store.Price < 0 {
+		return errors.New("price不能小于0")
+	}
+	if store.Stock < 0 {
+		return errors.New("stock不能小于0")
+	}
+	if store.Status != 0 && store.Status != 1 {
+		return errors.New("status必须是0或1")
+	}
+	if store.SalePrice < 0 || (store.SalePrice > 0 && store.SalePrice >= store.Price) {
+		return errors.New("sale_price必须大于等于0且小于price")
+	}
+	if store.SaleStartAt != nil && store.SaleEndAt != nil && !store.SaleEndAt.After(*store.SaleStartAt) {
+		return errors.New("sale_end_at必须晚于sale_start_at")
+	}
+
+	var count int
+	if err := db.Model(&models.Store{}).Where("name = ? AND id <> ?", store.Name, store.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("name %s已被其他商品使用", store.Name)
+	}
+	return nil
+}
+
+// validateStoreUpdate 校验修改后的已有商品：货币类型、限购、可售时间和前置商品
+func validateStoreUpdate(store *models.Store, lookup StoreLookup) error {
+	if store.CostType != models.CostTypeCoin && store.CostType != models.CostTypeDiamond {
+		return errors.New("cost_type必须是coin或diamond")
+	}
+	if err := ValidatePurchaseLimit(store.LimitPeriod, store.LimitCount); err != nil {
+		return err
+	}
+	if err := ValidateAvailability(store); err != nil {
+		return err
+	}
+	return ValidatePrerequisite(store, lookup)
+}
+
+// ValidatePurchaseLimit 校验限购配置
+func ValidatePurchaseLimit(period models.PurchaseLimitPeriod, count int64) error {
+	switch period {
+	case models.PurchaseLimitNone, models.PurchaseLimitDaily, models.PurchaseLimitWeekly, models.PurchaseLimitLifetime:
+	default:
+		return errors.New("limit_period必须是daily、weekly或lifetime")
+	}
+	if count < 0 {
+		return errors.New("limit_count不能小于0")
+	}
+	if period != models.PurchaseLimitNone && count == 0 {
+		return errors.New("设置限购周期时limit_count必须大于0")
+	}
+	return nil
+}
+
+// ValidateAvailability 校验可售时间配置，并将可售星期整理为去重排序后的格式
+func ValidateAvailability(store *models.Store) error {
+	if store.AvailableFrom != nil && store.AvailableUntil != nil && !store.AvailableUntil.After(*store.AvailableFrom) {
+		return errors.New("available_until必须晚于available_from")
+	}
+
+	if strings.TrimSpace(store.ScheduleDays) != "" {
+		selected := make(map[int]bool)
+		for _, value := range strings.Split(store.ScheduleDays, ",") {
+			day, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || day < 1 || day > 7 {
+				return errors.New("schedule_days必须是1-7之间的数字，用逗号分隔")
+			}
+			selected[day] = true
+		}
+		days := make([]string, 0, len(selected))
+		for day := 1; day <= 7; day++ {
+			if selected[day] {
+				days = append(days, strconv.Itoa(day))
+			}
+		}
+		store.ScheduleDays = strings.Join(days, ",")
+	} else {
+		store.ScheduleDays = ""
+	}
+
+	for _, clock := range []string{store.ScheduleStart, store.ScheduleEnd} {
+		if clock == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", clock); err != nil {
+			return errors.New("schedule_start和schedule_end格式应为15:04")
+		}
+	}
+	// 每日可售时间不支持跨零点
+	if store.ScheduleStart != "" && store.ScheduleEnd != "" && store.ScheduleEnd <= store.ScheduleStart {
+		return errors.New("schedule_end必须晚于schedule_start")
+	}
+	return nil
+}
+
+// ValidatePrerequisite 校验前置商品存在，且沿前置商品链不会回到商品自身，避免互为前置导致都无法购买。
+// lookup为空时从数据库查询
+func ValidatePrerequisite(store *models.Store, lookup StoreLookup) error {
+	if lookup == nil {
+		lookup = findStore
+	}
+	for id, depth := store.RequiredStoreID, 0; id != 0; depth++ {
+		if id == store.ID {
+			return errors.New("前置商品不能是商品自身或形成循环")
+		}
+		if depth >= maxPrerequisiteDepth {
+			return fmt.Errorf("前置商品链不能超过%d层", maxPrerequisiteDepth)
+		}
+		prerequisite, err := lookup(id)
+		if err != nil {
+			return fmt.Errorf("前置商品%d不存在", id)
+		}
+		id = prerequisite.RequiredStoreID
+	}
+	return nil
+}
+
+// findStore 从数据库按ID查询商品
+func findStore(id uint) (*models.Store, error) {
+	var store models.Store
+	if err := config.Database.First(&store, id).Error; err != nil {
+		return nil, err
+	}
+	return &store, nil
+}
@@ -0,0 +1,690 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"log"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jinzhu/gorm"
+)
+
+// storeVersionNoteMaxLength 版本说明的最大长度
+const storeVersionNoteMaxLength = 200
+
+// StoreVersionService 商品草稿、发布和版本历史服务接口
+type StoreVersionService interface {
+	// 创建或修改草稿，返回的草稿包含与当前商品的差异
+	SaveDraft(request *StoreDraftRequest, operatorID uint) (*models.StoreVersion, error)
+	// 查询版本详情，草稿包含与当前商品的差异，已发布的版本包含发布后的完整字段值
+	GetVersion(versionID uint) (*models.StoreVersion, error)
+	// 设置草稿的定时发布时间，同一时间发布的草稿作为一批一起发布；publishAt为空表示取消定时发布
+	Schedule(versionIDs []uint, publishAt *time.Time, operatorID uint) ([]*models.StoreVersion, error)
+	// 立即发布草稿，多个草稿在一个事务中发布，任意一个失败时都不发布
+	Publish(versionIDs []uint, operatorID uint) ([]*models.StoreVersion, error)
+	// 废弃草稿
+	Discard(versionID uint) (*models.StoreVersion, error)
+	// 将商品回滚到指定的已发布版本，回滚本身作为一个新版本发布
+	Rollback(storeID uint, number uint, note string, operatorID uint) (*models.StoreVersion, error)
+	// 发布到期的定时草稿
+	RunScheduled() ([]*models.StoreVersion, error)
+	// 分页查询商品的版本，status为空时查询全部
+	ListVersions(storeID uint, status models.StoreVersionStatus, page, pageSize int) ([]*models.StoreVersion, int64, error)
+}
+
+type storeVersionService struct{}
+
+// NewStoreVersionService 创建商品草稿、发布和版本历史服务实例
+func NewStoreVersionService() StoreVersionService {
+	return &storeVersionService{}
+}
+
+// StoreDraftRequest 保存草稿的请求，Fields的键为导入导出的列名，值为字符串或数字，null表示清空
+type StoreDraftRequest struct {
+	ID      uint                   // 修改已有草稿时传草稿ID
+	StoreID uint                   // 新建草稿时的商品ID
+	Fields  map[string]interface{} // 草稿修改的字段，修改已有草稿时整体替换
+	Note    string
+}
+
+// SaveDraft 校验草稿修改的字段并保存。草稿按当前商品校验一次，发布时还会按发布时的商品重新校验
+func (s *storeVersionService) SaveDraft(request *StoreDraftRequest, operatorID uint) (*models.StoreVersion, error) {
+	if utf8.RuneCountInString(request.Note) > storeVersionNoteMaxLength {
+		return nil, fmt.Errorf("note不能超过%d个字", storeVersionNoteMaxLength)
+	}
+	values, err := draftFieldValues(request.Fields)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	version := &models.StoreVersion{
+		StoreID:   request.StoreID,
+		Status:    models.StoreVersionDraft,
+		Source:    models.StoreVersionSourceDraft,
+		CreatedBy: operatorID,
+	}
+	if request.ID != 0 {
+		version = &models.StoreVersion{}
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(version, request.ID).Error; err != nil {
+			SafeRollback(tx)
+			if gorm.IsRecordNotFoundError(err) {
+				return nil, errors.New("草稿不存在")
+			}
+			return nil, err
+		}
+		if !version.Editable() {
+			SafeRollback(tx)
+			return nil, fmt.Errorf("版本状态为%s，不能修改", version.Status)
+		}
+	}
+
+	var store models.Store
+	if err := tx.First(&store, version.StoreID).Error; err != nil {
+		SafeRollback(tx)
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("商品不存在")
+		}
+		return nil, err
+	}
+	target := store
+	if err := applyStoreFields(&target, values); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := validateStoreBasics(tx, &target); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := validateStoreUpdate(&target, nil); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	version.Fields = string(fields)
+	version.Note = request.Note
+	version.Error = ""
+	if err := tx.Save(version).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	version.FieldValues = values
+	version.ChangeList = diffStoreColumns(&store, &target)
+	return version, nil
+}
+
+// GetVersion 查询版本详情
+func (s *storeVersionService) GetVersion(versionID uint) (*models.StoreVersion, error) {
+	var version models.StoreVersion
+	if err := config.Database.First(&version, versionID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("版本不存在")
+		}
+		return nil, err
+	}
+	if err := version.Decode(true); err != nil {
+		return nil, err
+	}
+	if !version.Editable() {
+		return &version, nil
+	}
+
+	// 未发布的草稿按当前商品预览差异
+	var store models.Store
+	if err := config.Database.First(&store, version.StoreID).Error; err != nil {
+		return nil, err
+	}
+	target := store
+	if err := applyStoreFields(&target, version.FieldValues); err != nil {
+		return nil, err
+	}
+	version.ChangeList = diffStoreColumns(&store, &target)
+	return &version, nil
+}
+
+// Schedule 设置或取消草稿的定时发布
+func (s *storeVersionService) Schedule(versionIDs []uint, publishAt *time.Time, operatorID uint) ([]*models.StoreVersion, error) {
+	if publishAt != nil && !publishAt.After(time.Now()) {
+		return nil, errors.New("publish_at必须晚于当前时间")
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	versions, err := lockStoreVersions(tx, versionIDs)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	status, scheduledBy := models.StoreVersionScheduled, operatorID
+	if publishAt == nil {
+		status, scheduledBy = models.StoreVersionDraft, 0
+	}
+	for _, version := range versions {
+		updates := map[string]interface{}{
+			"status":       status,
+			"publish_at":   publishAt,
+			"scheduled_by": scheduledBy,
+			"error":        "",
+		}
+		if publishAt == nil {
+			updates["publish_at"] = gorm.Expr("NULL")
+		}
+		if err := tx.Model(version).UpdateColumns(updates).Error; err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+		version.Status = status
+		version.PublishAt = publishAt
+		version.ScheduledBy = scheduledBy
+		version.Error = ""
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// Publish 立即发布草稿
+func (s *storeVersionService) Publish(versionIDs []uint, operatorID uint) ([]*models.StoreVersion, error) {
+	return s.publish(versionIDs, operatorID, nil)
+}
+
+// publish 在一个事务中发布草稿。scheduledAt不为空时由定时任务调用，只发布仍处于该时间定时发布状态的草稿
+func (s *storeVersionService) publish(versionIDs []uint, operatorID uint, scheduledAt *time.Time) ([]*models.StoreVersion, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var versions []*models.StoreVersion
+	var err error
+	if scheduledAt == nil {
+		versions, err = lockStoreVersions(tx, versionIDs)
+	} else {
+		// 定时任务查询后草稿可能已被修改、取消或发布，只发布仍在该时间定时发布的草稿
+		err = tx.Set("gorm:query_option", "FOR UPDATE").
+			Where("id IN (?) AND status = ? AND publish_at = ?", versionIDs, models.StoreVersionScheduled, *scheduledAt).
+			Order("id").Find(&versions).Error
+	}
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if len(versions) == 0 {
+		SafeRollback(tx)
+		return versions, nil
+	}
+
+	if err := publishStoreVersions(tx, versions, operatorID, time.Now()); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	invalidateStoreCatalog()
+	return versions, nil
+}
+
+// Discard 废弃草稿
+func (s *storeVersionService) Discard(versionID uint) (*models.StoreVersion, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	versions, err := lockStoreVersions(tx, []uint{versionID})
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	version := versions[0]
+	version.Status = models.StoreVersionDiscarded
+	if err := tx.Model(version).UpdateColumns(map[string]interface{}{
+		"status":     version.Status,
+		"publish_at": gorm.Expr("NULL"),
+	}).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	version.PublishAt = nil
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// Rollback 以目标版本发布后的字段值创建一个回滚版本并立即发布。
+// 库存由购买和补货持续变化，回滚时保留当前库存
+func (s *storeVersionService) Rollback(storeID uint, number uint, note string, operatorID uint) (*models.StoreVersion, error) {
+	if utf8.RuneCountInString(note) > storeVersionNoteMaxLength {
+		return nil, fmt.Errorf("note不能超过%d个字", storeVersionNoteMaxLength)
+	}
+
+	var target models.StoreVersion
+	if err := config.Database.Where("store_id = ? AND number = ? AND status = ?", storeID, number, models.StoreVersionPublished).
+		First(&target).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, fmt.Errorf("商品%d没有已发布的版本%d", storeID, number)
+		}
+		return nil, err
+	}
+	var values map[string]string
+	if err := json.Unmarshal([]byte(target.Snapshot), &values); err != nil {
+		return nil, err
+	}
+	delete(values, "stock")
+	fields, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	version := &models.StoreVersion{
+		StoreID:    storeID,
+		Status:     models.StoreVersionDraft,
+		Source:     models.StoreVersionSourceRollback,
+		Note:       note,
+		Fields:     string(fields),
+		RollbackTo: number,
+		CreatedBy:  operatorID,
+	}
+	if err := tx.Create(version).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if err := publishStoreVersions(tx, []*models.StoreVersion{version}, operatorID, time.Now()); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	invalidateStoreCatalog()
+	return version, nil
+}
+
+// RunScheduled 按定时发布时间分批发布到期的草稿，同一时间的草稿在一个事务中发布。
+// 一批发布失败时这批草稿退回草稿状态并记录失败原因，不影响其他批次
+func (s *storeVersionService) RunScheduled() ([]*models.StoreVersion, error) {
+	now := time.Now()
+
+	var times []time.Time
+	if err := config.Database.Model(&models.StoreVersion{}).
+		Where("status = ? AND publish_at <= ?", models.StoreVersionScheduled, now).
+		Order("publish_at").
+		Pluck("DISTINCT publish_at", &times).Error; err != nil {
+		return nil, err
+	}
+
+	published := make([]*models.StoreVersion, 0)
+	for _, publishAt := range times {
+		var ids []uint
+		if err := config.Database.Model(&models.StoreVersion{}).
+			Where("status = ? AND publish_at = ?", models.StoreVersionScheduled, publishAt).
+			Pluck("id", &ids).Error; err != nil {
+			return published, err
+		}
+
+		at := publishAt
+		versions, err := s.publish(ids, 0, &at)
+		if err != nil {
+			log.Printf("定时发布%s的%d个草稿失败: %v", publishAt.Format(time.RFC3339), len(ids), err)
+			message := []rune(err.Error())
+			if len(message) > 500 {
+				message = message[:500]
+			}
+			if err := config.Database.Model(&models.StoreVersion{}).
+				Where("id IN (?) AND status = ? AND publish_at = ?", ids, models.StoreVersionScheduled, publishAt).
+				UpdateColumns(map[string]interface{}{
+					"status":     models.StoreVersionDraft,
+					"publish_at": gorm.Expr("NULL"),
+					"error":      string(message),
+				}).Error; err != nil {
+				return published, err
+			}
+			continue
+		}
+		published = append(published, versions...)
+	}
+	return published, nil
+}
+
+// ListVersions 分页查询商品的版本，按创建时间倒序
+func (s *storeVersionService) ListVersions(storeID uint, status models.StoreVersionStatus, page, pageSize int) ([]*models.StoreVersion, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	db := config.Database.Model(&models.StoreVersion{}).Where("store_id = ?", storeID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var versions []*models.StoreVersion
+	if err := db.Order("id desc").Offset(offset).Limit(pageSize).Find(&versions).Error; err != nil {
+		return nil, 0, err
+	}
+	for _, version := range versions {
+		if err := version.Decode(false); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return versions, total, nil
+}
+
+// lockStoreVersions 锁定待操作的草稿，所有版本都必须存在且处于草稿或定时发布状态
+func lockStoreVersions(tx *gorm.DB, versionIDs []uint) ([]*models.StoreVersion, error) {
+	if len(versionIDs) == 0 {
+		return nil, errors.New("version_ids不能为空")
+	}
+
+	var versions []*models.StoreVersion
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("id IN (?)", versionIDs).Order("id").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	found := make(map[uint]*models.StoreVersion, len(versions))
+	for _, version := range versions {
+		found[version.ID] = version
+	}
+	for _, id := range versionIDs {
+		version, ok := found[id]
+		if !ok {
+			return nil, fmt.Errorf("版本%d不存在", id)
+		}
+		if !version.Editable() {
+			return nil, fmt.Errorf("版本%d状态为%s，不能操作", id, version.Status)
+		}
+	}
+	return versions, nil
+}
+
+// publishStoreVersions 发布已锁定的草稿：按商品ID顺序锁定商品并应用草稿的字段，
+// 所有草稿应用完后再统一校验，前置商品链按本批发布后的数据检查，最后写入商品并记录版本号、差异和完整字段值
+func publishStoreVersions(tx *gorm.DB, versions []*models.StoreVersion, operatorID uint, now time.Time) error {
+	sort.Slice(versions, func(i, j int) bool { return versions[i].StoreID < versions[j].StoreID })
+
+	type publishPlan struct {
+		version *models.StoreVersion
+		before  *models.Store
+		target  *models.Store
+	}
+	plans := make([]*publishPlan, len(versions))
+	pending := make(map[uint]*models.Store, len(versions))
+	for i, version := range versions {
+		if _, ok := pending[version.StoreID]; ok {
+			return fmt.Errorf("同一批次中商品%d有多个草稿", version.StoreID)
+		}
+		if err := version.Decode(false); err != nil {
+			return err
+		}
+
+		var store models.Store
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&store, version.StoreID).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return fmt.Errorf("版本%d的商品%d不存在", version.ID, version.StoreID)
+			}
+			return err
+		}
+		before := store
+		target := store
+		if err := applyStoreFields(&target, version.FieldValues); err != nil {
+			return fmt.Errorf("版本%d：%v", version.ID, err)
+		}
+		plans[i] = &publishPlan{version: version, before: &before, target: &target}
+		pending[target.ID] = &target
+	}
+
+	lookup := func(id uint) (*models.Store, error) {
+		if store, ok := pending[id]; ok {
+			return store, nil
+		}
+		return findStore(id)
+	}
+	for _, plan := range plans {
+		if err := validateStoreBasics(tx, plan.target); err != nil {
+			return fmt.Errorf("版本%d：%v", plan.version.ID, err)
+		}
+		if err := validateStoreUpdate(plan.target, lookup); err != nil {
+			return fmt.Errorf("版本%d：%v", plan.version.ID, err)
+		}
+	}
+
+	for _, plan := range plans {
+		plan.target.Version++
+		if err := tx.Omit(append([]string{"sales_count"}, models.RatingColumns...)...).Save(plan.target).Error; err != nil {
+			return err
+		}
+		if err := notifyWishlist(tx, plan.before, plan.target, now); err != nil {
+			return err
+		}
+
+		number, err := nextStoreVersionNumber(tx, plan.before, now)
+		if err != nil {
+			return err
+		}
+		version := plan.version
+		publishedBy := operatorID
+		if publishedBy == 0 {
+			publishedBy = version.ScheduledBy
+		}
+		if err := fillPublishedVersion(version, plan.before, plan.target); err != nil {
+			return err
+		}
+		version.Number = number
+		version.Status = models.StoreVersionPublished
+		version.PublishedBy = publishedBy
+		version.PublishedAt = &now
+		version.Error = ""
+		if err := tx.Model(version).UpdateColumns(map[string]interface{}{
+			"number":       version.Number,
+			"status":       version.Status,
+			"snapshot":     version.Snapshot,
+			"changes":      version.Changes,
+			"published_by": version.PublishedBy,
+			"published_at": now,
+			"error":        "",
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordStoreVersion 为直接修改（修改商品接口、批量导入）记录一个已发布的版本，没有差异时不记录。
+// 调用方需要在同一事务中锁定商品
+func recordStoreVersion(tx *gorm.DB, before, after *models.Store, source models.StoreVersionSource, operatorID uint, now time.Time) (*models.StoreVersion, error) {
+	changes := diffStoreColumns(before, after)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	number, err := nextStoreVersionNumber(tx, before, now)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(changes))
+	for _, change := range changes {
+		values[change.Field] = change.New
+	}
+	fields, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &models.StoreVersion{
+		StoreID:     after.ID,
+		Number:      number,
+		Status:      models.StoreVersionPublished,
+		Source:      source,
+		Fields:      string(fields),
+		CreatedBy:   operatorID,
+		PublishedBy: operatorID,
+		PublishedAt: &now,
+	}
+	if err := fillPublishedVersion(version, before, after); err != nil {
+		return nil, err
+	}
+	if err := tx.Create(version).Error; err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// nextStoreVersionNumber 返回商品下一个版本号。商品还没有版本时先以修改前的字段值记录版本1，保证可以回滚到最初的状态
+func nextStoreVersionNumber(tx *gorm.DB, before *models.Store, now time.Time) (uint, error) {
+	var latest struct {
+		Number uint
+	}
+	if err := tx.Model(&models.StoreVersion{}).
+		Select("COALESCE(MAX(number), 0) AS number").
+		Where("store_id = ? AND status = ?", before.ID, models.StoreVersionPublished).
+		Scan(&latest).Error; err != nil {
+		return 0, err
+	}
+	if latest.Number > 0 {
+		return latest.Number + 1, nil
+	}
+
+	snapshot, err := json.Marshal(storeSnapshot(before))
+	if err != nil {
+		return 0, err
+	}
+	initial := &models.StoreVersion{
+		StoreID:     before.ID,
+		Number:      1,
+		Status:      models.StoreVersionPublished,
+		Source:      models.StoreVersionSourceInitial,
+		Snapshot:    string(snapshot),
+		PublishedAt: &now,
+	}
+	if err := tx.Create(initial).Error; err != nil {
+		return 0, err
+	}
+	return 2, nil
+}
+
+// fillPublishedVersion 记录发布时的差异和发布后的完整字段值
+func fillPublishedVersion(version *models.StoreVersion, before, after *models.Store) error {
+	version.ChangeList = diffStoreColumns(before, after)
+	changes, err := json.Marshal(version.ChangeList)
+	if err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(storeSnapshot(after))
+	if err != nil {
+		return err
+	}
+	version.Changes = string(changes)
+	version.Snapshot = string(snapshot)
+	return nil
+}
+
+// storeSnapshot 商品所有可修改列的值
+func storeSnapshot(store *models.Store) map[string]string {
+	values := make(map[string]string)
+	for _, column := range storeColumns {
+		if column.mode == storeColumnEditable {
+			values[column.name] = column.get(store)
+		}
+	}
+	return values
+}
+
+// draftFieldValues 校验草稿修改的字段并转换为字符串，只能修改商品接口可以修改且不是库存的列
+func draftFieldValues(fields map[string]interface{}) (map[string]string, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("fields不能为空")
+	}
+	values := make(map[string]string, len(fields))
+	for name, value := range fields {
+		column := findStoreColumn(name)
+		if column == nil {
+			return nil, fmt.Errorf("未知的字段：%s", name)
+		}
+		if !draftableColumn(column) {
+			return nil, fmt.Errorf("字段%s不能通过草稿修改", name)
+		}
+		text, ok := storeFieldValue(value)
+		if !ok {
+			return nil, fmt.Errorf("字段%s的值必须是字符串或数字", name)
+		}
+		values[name] = text
+	}
+	return values, nil
+}
+
+// draftableColumn 判断列是否可以通过草稿修改。
+// 库存由购买和补货持续变化，草稿保存的值发布时已过时，因此不允许修改
+func draftableColumn(column *storeColumn) bool {
+	return column.mode == storeColumnEditable && column.name != "stock"
+}
+
+// applyStoreFields 将草稿字段值应用到商品，数字列的空值按0处理
+func applyStoreFields(store *models.Store, values map[string]string) error {
+	for _, column := range storeColumns {
+		value, ok := values[column.name]
+		if !ok || !draftableColumn(column) {
+			continue
+		}
+		if value == "" && column.kind == storeColumnNumber {
+			value = "0"
+		}
+		if err := column.set(store, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
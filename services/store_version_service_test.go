@@ -0,0 +1,72 @@
+package services
+
+import (
+	"encoding/json"
+	"goDDD1/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDraftFieldValues 测试草稿字段的校验和转换
+func TestDraftFieldValues(t *testing.T) {
+	values, err := draftFieldValues(map[string]interface{}{"price": float64(250), "name": " Sword ", "sale_end_at": nil})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"price": "250", "name": "Sword", "sale_end_at": ""}, values)
+
+	_, err = draftFieldValues(nil)
+	assert.Error(t, err)
+	_, err = draftFieldValues(map[string]interface{}{"color": "red"})
+	assert.Error(t, err)
+	_, err = draftFieldValues(map[string]interface{}{"tag": "weapon"})
+	assert.Error(t, err)
+	_, err = draftFieldValues(map[string]interface{}{"sales_count": 1})
+	assert.Error(t, err)
+	_, err = draftFieldValues(map[string]interface{}{"stock": 10})
+	assert.Error(t, err)
+	_, err = draftFieldValues(map[string]interface{}{"status": true})
+	assert.Error(t, err)
+}
+
+// TestApplyStoreFields 测试草稿字段应用到商品及差异和完整字段值
+func TestApplyStoreFields(t *testing.T) {
+	before := &models.Store{Name: "Sword", Price: 100, Stock: 5, SalePrice: 80, Tag: "weapon"}
+	after := *before
+	assert.NoError(t, applyStoreFields(&after, map[string]string{"price": "120", "sale_price": "", "tag": "armor", "stock": "99"}))
+	assert.Equal(t, int64(120), after.Price)
+	assert.Equal(t, int64(5), after.Stock)
+	assert.Equal(t, int64(0), after.SalePrice)
+	assert.Equal(t, models.Tag("weapon"), after.Tag)
+
+	changes := diffStoreColumns(before, &after)
+	assert.Equal(t, []*models.StoreFieldChange{
+		{Field: "price", Old: "100", New: "120"},
+		{Field: "sale_price", Old: "80", New: "0"},
+	}, changes)
+
+	snapshot := storeSnapshot(&after)
+	assert.Equal(t, "120", snapshot["price"])
+	assert.NotContains(t, snapshot, "tag")
+	assert.NotContains(t, snapshot, "sales_count")
+
+	assert.Error(t, applyStoreFields(&after, map[string]string{"price": "abc"}))
+}
+
+// TestStoreVersionDecode 测试版本JSON字段的解析
+func TestStoreVersionDecode(t *testing.T) {
+	changes, _ := json.Marshal([]*models.StoreFieldChange{{Field: "price", Old: "100", New: "120"}})
+	version := &models.StoreVersion{
+		Status:   models.StoreVersionPublished,
+		Fields:   `{"price":"120"}`,
+		Snapshot: `{"price":"120","name":"Sword"}`,
+		Changes:  string(changes),
+	}
+	assert.NoError(t, version.Decode(false))
+	assert.Equal(t, map[string]string{"price": "120"}, version.FieldValues)
+	assert.Len(t, version.ChangeList, 1)
+	assert.Nil(t, version.SnapshotValues)
+	assert.False(t, version.Editable())
+
+	assert.NoError(t, version.Decode(true))
+	assert.Equal(t, "Sword", version.SnapshotValues["name"])
+}
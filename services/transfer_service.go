@@ -0,0 +1,379 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"goDDD1/utils"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TransferService 玩家转账服务接口
+type TransferService interface {
+	Transfer(fromUserID uint, toUserID uint, walletType models.WalletType, amount int64, remark string) (*models.UserTransfer, error)
+	ReverseTransfer(transferID uint, operatorID uint, reason string) (*models.UserTransfer, error)
+	GetTransferByID(id uint) (*models.UserTransfer, error)
+	GetUserTransfers(userID uint, page, pageSize int) ([]*models.UserTransfer, int64, error)
+}
+
+// transferService 玩家转账服务实现
+type transferService struct {
+	config *config.TransferConfig
+}
+
+// NewTransferService 创建转账服务实例
+func NewTransferService() TransferService {
+	return &transferService{
+		config: config.GetTransferConfig(),
+	}
+}
+
+// Transfer 从转出方钱包向接收方转账，手续费由转出方额外支付并计入系统账户
+func (s *transferService) Transfer(fromUserID uint, toUserID uint, walletType models.WalletType, amount int64, remark string) (*models.UserTransfer, error) {
+	//1、基础校验
+	if amount <= 0 {
+		return nil, errors.New("转账金额必须大于0")
+	}
+	if fromUserID == toUserID {
+		return nil, errors.New("不能向自己转账")
+	}
+	if !s.config.IsTradable(string(walletType)) {
+		return nil, fmt.Errorf("%s不支持转账", walletType)
+	}
+	if s.config.MaxPerTransfer > 0 && amount > s.config.MaxPerTransfer {
+		return nil, fmt.Errorf("单笔转账不能超过%d", s.config.MaxPerTransfer)
+	}
+	fee := amount * s.config.FeePercent / 100
+
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	//2、校验转出方资格（等级、注册时长）
+	var fromUser models.User
+	if err := tx.Where("uid = ? AND is_deleted = ?", fromUserID, "0").First(&fromUser).Error; err != nil {
+		SafeRollback(tx)
+		return nil, errors.New("转出方用户不存在")
+	}
+	if fromUser.Level < s.config.MinLevel {
+		SafeRollback(tx)
+		return nil, fmt.Errorf("等级达到%d级后才能转账", s.config.MinLevel)
+	}
+	if time.Since(fromUser.CreatedAt) < time.Duration(s.config.MinAccountDays)*24*time.Hour {
+		SafeRollback(tx)
+		return nil, fmt.Errorf("注册满%d天后才能转账", s.config.MinAccountDays)
+	}
+
+	//3、校验接收方
+	var toUser models.User
+	if err := tx.Where("uid = ? AND is_deleted = ?", toUserID, "0").First(&toUser).Error; err != nil {
+		SafeRollback(tx)
+		return nil, errors.New("接收方用户不存在")
+	}
+
+	//4、按UID顺序锁定双方和收取手续费的系统账户钱包，避免互相转账时死锁
+	userIDs := []uint{fromUserID, toUserID}
+	if fee > 0 {
+		if err := s.ensureFeeWallet(tx, walletType); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+		userIDs = append(userIDs, s.config.SystemUID)
+	}
+	wallets, err := lockWallets(tx, walletType, userIDs...)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	fromWallet, toWallet := wallets[fromUserID], wallets[toUserID]
+	if fromWallet.Num < amount+fee {
+		SafeRollback(tx)
+		return nil, ErrInsufficientFunds
+	}
+
+	//5、校验每日限额与次数，转出方钱包已加锁，同一用户的并发转账在此串行
+	if err := s.checkDailyLimit(tx, fromUserID, walletType, amount); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//6、创建转账记录
+	transfer := &models.UserTransfer{
+		FromUserID: fromUserID,
+		ToUserID:   toUserID,
+		Type:       walletType,
+		Amount:     amount,
+		Fee:        fee,
+		Remark:     remark,
+		Status:     models.TransferStatusSuccess,
+	}
+	if fee > 0 {
+		transfer.FeeUserID = s.config.SystemUID
+	}
+	if err := tx.Create(transfer).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//7、变更余额并记录双方流水，接收方的余额保留转出货币桶的过期时间
+	if err := moveLockedBalance(tx, fromWallet, toWallet, amount, models.FlowRefTransfer, transfer.ID,
+		fmt.Sprintf("转账给用户%d", toUserID), fmt.Sprintf("收到用户%d的转账", fromUserID)); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	//8、手续费计入系统账户
+	if fee > 0 {
+		if err := s.moveFee(tx, fromWallet, wallets[s.config.SystemUID], fee, transfer); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	clearWalletCache(fromUserID, toUserID, s.config.SystemUID)
+	return transfer, nil
+}
+
+// ReverseTransfer 管理员撤销转账：从接收方扣回金额，退还转出方金额和手续费
+func (s *transferService) ReverseTransfer(transferID uint, operatorID uint, reason string) (*models.UserTransfer, error) {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var transfer models.UserTransfer
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&transfer, transferID).Error; err != nil {
+		SafeRollback(tx)
+		return nil, errors.New("转账记录不存在")
+	}
+	if transfer.Status != models.TransferStatusSuccess {
+		SafeRollback(tx)
+		return nil, errors.New("该转账已被撤销")
+	}
+
+	userIDs := []uint{transfer.FromUserID, transfer.ToUserID}
+	if transfer.Fee > 0 {
+		userIDs = append(userIDs, transfer.FeeUserID)
+	}
+	wallets, err := lockWallets(tx, transfer.Type, userIDs...)
+	if err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	toWallet := wallets[transfer.ToUserID]
+	if toWallet.Num < transfer.Amount {
+		SafeRollback(tx)
+		return nil, fmt.Errorf("接收方%w，无法撤销", ErrInsufficientFunds)
+	}
+
+	// 扣回的余额保留接收方货币桶的过期时间退还转出方
+	description := fmt.Sprintf("转账撤销，转账ID：%d，原因：%s", transfer.ID, reason)
+	if err := moveLockedBalance(tx, toWallet, wallets[transfer.FromUserID], transfer.Amount, models.FlowRefTransferReverse, transfer.ID,
+		description, description); err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+	if transfer.Fee > 0 {
+		feeWallet := wallets[transfer.FeeUserID]
+		if err := changeLockedWallet(tx, feeWallet, -transfer.Fee, transfer.FromUserID, models.FlowRefTransferReverse, transfer.ID, description); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+		if err := changeLockedWallet(tx, wallets[transfer.FromUserID], transfer.Fee, transfer.FeeUserID, models.FlowRefTransferReverse, transfer.ID, description); err != nil {
+			SafeRollback(tx)
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	transfer.Status = models.TransferStatusReversed
+	transfer.ReversedBy = operatorID
+	transfer.ReverseReason = reason
+	transfer.ReversedAt = &now
+	if err := tx.Save(&transfer).Error; err != nil {
+		SafeRollback(tx)
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	clearWalletCache(userIDs...)
+	return &transfer, nil
+}
+
+// GetTransferByID 根据ID获取转账记录
+func (s *transferService) GetTransferByID(id uint) (*models.UserTransfer, error) {
+	var transfer models.UserTransfer
+	if err := config.Database.First(&transfer, id).Error; err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// GetUserTransfers 分页获取用户转出和收到的转账记录
+func (s *transferService) GetUserTransfers(userID uint, page, pageSize int) ([]*models.UserTransfer, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	} else if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := (page - 1) * pageSize
+
+	var transfers []*models.UserTransfer
+	var total int64
+
+	query := config.Database.Model(&models.UserTransfer{}).Where("from_user_id = ? OR to_user_id = ?", userID, userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Offset(offset).Limit(pageSize).Order("id desc").Find(&transfers).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return transfers, total, nil
+}
+
+// checkDailyLimit 校验转出方当日累计转出金额和次数
+func (s *transferService) checkDailyLimit(tx *gorm.DB, userID uint, walletType models.WalletType, amount int64) error {
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var stat struct {
+		Total int64
+		Count int
+	}
+	if err := tx.Model(&models.UserTransfer{}).
+		Select("COALESCE(SUM(amount), 0) AS total, COUNT(*) AS count").
+		Where("from_user_id = ? AND type = ? AND status = ? AND created_at >= ?", userID, walletType, models.TransferStatusSuccess, startOfDay).
+		Scan(&stat).Error; err != nil {
+		return err
+	}
+
+	if s.config.DailyCount > 0 && stat.Count >= s.config.DailyCount {
+		return fmt.Errorf("每日最多转账%d次", s.config.DailyCount)
+	}
+	if s.config.DailyLimit > 0 && stat.Total+amount > s.config.DailyLimit {
+		return fmt.Errorf("今日转账额度剩余%d", s.config.DailyLimit-stat.Total)
+	}
+	return nil
+}
+
+// ensureFeeWallet 系统账户没有该类型钱包时创建，之后与双方钱包一起加锁
+func (s *transferService) ensureFeeWallet(tx *gorm.DB, walletType models.WalletType) error {
+	var feeWallet models.UserWallet
+	return tx.Where("user_id = ? AND type = ?", s.config.SystemUID, walletType).
+		Attrs(models.UserWallet{Num: 0}).
+		FirstOrCreate(&feeWallet, models.UserWallet{UserID: s.config.SystemUID, Type: walletType}).Error
+}
+
+// moveFee 将手续费从转出方钱包转入已加锁的系统账户钱包
+func (s *transferService) moveFee(tx *gorm.DB, fromWallet *models.UserWallet, feeWallet *models.UserWallet, fee int64, transfer *models.UserTransfer) error {
+	description := fmt.Sprintf("转账手续费，转账ID：%d", transfer.ID)
+	if err := changeLockedWallet(tx, fromWallet, -fee, s.config.SystemUID, models.FlowRefTransferFee, transfer.ID, description); err != nil {
+		return err
+	}
+	return changeLockedWallet(tx, feeWallet, fee, transfer.FromUserID, models.FlowRefTransferFee, transfer.ID, description)
+}
+
+// lockWallets 按UID升序对多个用户的同类型钱包加行锁
+func lockWallets(tx *gorm.DB, walletType models.WalletType, userIDs ...uint) (map[uint]*models.UserWallet, error) {
+	var wallets []*models.UserWallet
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("user_id IN (?) AND type = ?", userIDs, walletType).
+		Order("user_id asc").
+		Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]*models.UserWallet, len(wallets))
+	for _, wallet := range wallets {
+		result[wallet.UserID] = wallet
+	}
+	for _, userID := range userIDs {
+		if _, ok := result[userID]; !ok {
+			return nil, fmt.Errorf("用户%d的%s钱包不存在", userID, walletType)
+		}
+	}
+	return result, nil
+}
+
+// moveLockedBalance 在两个已加锁的钱包之间转移余额并记录双方流水，
+// 转入方按转出方消耗的货币桶生成过期时间相同的新桶，避免即将过期的余额转账后变为永久
+func moveLockedBalance(tx *gorm.DB, from *models.UserWallet, to *models.UserWallet, amount int64, refType string, refID uint, fromDescription string, toDescription string) error {
+	portions, err := debitWalletBuckets(tx, from, amount)
+	if err != nil {
+		return err
+	}
+	if err := creditWalletBuckets(tx, to, portions, toDescription); err != nil {
+		return err
+	}
+
+	if err := tx.Create(&models.UserCurrencyFlow{
+		UserID:        from.UserID,
+		CostType:      string(from.Type),
+		Description:   fromDescription,
+		Price:         -amount,
+		CounterpartID: to.UserID,
+		RefType:       refType,
+		RefID:         refID,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.UserCurrencyFlow{
+		UserID:        to.UserID,
+		CostType:      string(to.Type),
+		Description:   toDescription,
+		Price:         amount,
+		CounterpartID: from.UserID,
+		RefType:       refType,
+		RefID:         refID,
+	}).Error
+}
+
+// changeLockedWallet 变更已加锁钱包的余额并在同一事务中记录货币流水
+func changeLockedWallet(tx *gorm.DB, wallet *models.UserWallet, amount int64, counterpartID uint, refType string, refID uint, description string) error {
+	if err := changeWalletBalance(tx, wallet, amount, nil, description); err != nil {
+		return err
+	}
+
+	return tx.Create(&models.UserCurrencyFlow{
+		UserID:        wallet.UserID,
+		CostType:      string(wallet.Type),
+		Description:   description,
+		Price:         amount,
+		CounterpartID: counterpartID,
+		RefType:       refType,
+		RefID:         refID,
+	}).Error
+}
+
+// clearWalletCache 删除用户钱包缓存
+func clearWalletCache(userIDs ...uint) {
+	for _, userID := range userIDs {
+		cacheKey := fmt.Sprintf(models.CacheKeyUserBackpack, userID)
+		if err := utils.DelHashField(cacheKey, "wallets"); err == nil {
+			log.Printf("successful delete cacheKey: %s wallets", cacheKey)
+		}
+	}
+}
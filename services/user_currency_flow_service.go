@@ -40,11 +40,15 @@ func (s *UserCurrencyFlowService) GetUserCurrencyFlow(userID string) (map[string
 	flows := make([]map[string]interface{}, 0)
 	for _, flow := range userCurrencyFlows {
 		flowMap := map[string]interface{}{
-			"user_id":   flow.UserID,
-			"store_id":  flow.StoreID,
-			"cost_type": flow.CostType,
-			"price":     flow.Price,
-			"ctime":     flow.Ctime,
+			"user_id":        flow.UserID,
+			"store_id":       flow.StoreID,
+			"cost_type":      flow.CostType,
+			"price":          flow.Price,
+			"description":    flow.Description,
+			"counterpart_id": flow.CounterpartID,
+			"ref_type":       flow.RefType,
+			"ref_id":         flow.RefID,
+			"ctime":          flow.Ctime,
 		}
 		flows = append(flows, flowMap)
 	}
@@ -5,6 +5,7 @@ import (
 	"goDDD1/config"
 	"goDDD1/models"
 	"log"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
@@ -20,6 +21,8 @@ type UserService interface {
 	GetAllUsersByIsDeleted(isDeleted string) ([]*models.User, error)
 	GetAllUsers() ([]*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
+	// 设置用户VIP等级和到期时间，level为0表示取消VIP
+	SetVip(uid uint, level uint, expireAt *time.Time) (*models.User, error)
 }
 
 // userService 用户服务实现
@@ -136,6 +139,26 @@ func (s *userService) UpdateUser(user *models.User) error {
 	return config.Database.Save(user).Error
 }
 
+// SetVip 设置用户VIP等级和到期时间，只更新VIP相关字段，不影响并发修改的等级和经验
+func (s *userService) SetVip(uid uint, level uint, expireAt *time.Time) (*models.User, error) {
+	if level == 0 {
+		expireAt = nil
+	}
+
+	user, err := s.GetUserByUID(uid)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Database.Model(user).UpdateColumns(map[string]interface{}{
+		"vip_level":     level,
+		"vip_expire_at": expireAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+	user.VipLevel, user.VipExpireAt = level, expireAt
+	return user, nil
+}
+
 // DeleteUser 删除用户
 func (s *userService) DeleteUser(id uint) error {
 	return config.Database.Delete(&models.User{}, id).Error
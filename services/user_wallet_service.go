@@ -21,6 +21,8 @@ type UserWalletService interface {
 	UpdateWalletBalance(userID uint, walletType models.WalletType, amount int64) error
 	UpdateWalletBalance2(userID uint, walletType models.WalletType, amount int64) error
 	UpdateWalletBalanceWithTx(tx *gorm.DB, userID uint, walletType models.WalletType, amount int64, description string) error
+	GrantWalletBalanceWithTx(tx *gorm.DB, userID uint, walletType models.WalletType, amount int64, expiresAt *time.Time, description string) error
+	ExpireWalletBuckets() (int, error)
 	GetUserWallets(userID uint) ([]models.UserWallet, error)
 }
 
@@ -54,7 +56,7 @@ func (s *userWalletService) InitializeWalletWithTx(tx *gorm.DB, userID uint) err
 	// 创建coin钱包，初始化1000个coin
 	coinWallet := models.UserWallet{
 		UserID: userID,
-		Num:    1000,
+		Num:    models.InitialCoinGrant,
 		Type:   models.Coin,
 	}
 	if err := tx.Create(&coinWallet).Error; err != nil {
@@ -64,7 +66,7 @@ func (s *userWalletService) InitializeWalletWithTx(tx *gorm.DB, userID uint) err
 	// 创建diamond钱包，初始化200个diamond
 	diamondWallet := models.UserWallet{
 		UserID: userID,
-		Num:    200,
+		Num:    models.InitialDiamondGrant,
 		Type:   models.Diamond,
 	}
 	return tx.Create(&diamondWallet).Error
@@ -90,8 +92,15 @@ func (s *userWalletService) GetWalletByUserIDAndTypeWithTx(tx *gorm.DB, userID u
 	return &wallet, nil
 }
 
-// UpdateWalletBalance 更新钱包余额
+// UpdateWalletBalance 更新钱包余额，版本冲突时自动重试
 func (s *userWalletService) UpdateWalletBalance(userID uint, walletType models.WalletType, amount int64) error {
+	return withOptimisticRetry(func() error {
+		return s.updateWalletBalance(userID, walletType, amount)
+	})
+}
+
+// updateWalletBalance 在独立事务中更新一次钱包余额
+func (s *userWalletService) updateWalletBalance(userID uint, walletType models.WalletType, amount int64) error {
 	tx := config.Database.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -99,19 +108,32 @@ func (s *userWalletService) UpdateWalletBalance(userID uint, walletType models.W
 		}
 	}()
 
-	// 更新余额
-	if err := tx.Where("user_id = ? AND type = ?", userID, walletType).Update("num", gorm.Expr("num + ?", amount)).Error; err != nil {
+	// 基于版本号更新余额，余额不允许为负数
+	var wallet models.UserWallet
+	if err := tx.Where("user_id = ? AND type = ?", userID, walletType).First(&wallet).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := changeWalletBalance(tx, &wallet, amount, nil, "钱包余额更新"); err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// 添加奖励流水记录
-	rewardType := models.RewardTypeCoin
-	if walletType == models.Diamond {
-		rewardType = models.RewardTypeDiamond
+	// 添加货币流水，保证每次余额变动都有对应流水可供对账
+	flow := &models.UserCurrencyFlow{
+		UserID:      userID,
+		CostType:    string(walletType),
+		Price:       amount,
+		Description: "钱包余额更新",
+		RefType:     models.FlowRefAdminAdjust,
+	}
+	if err := tx.Create(flow).Error; err != nil {
+		tx.Rollback()
+		return err
 	}
 
-	err := s.rewardFlowService.CreateRewardFlow(tx, userID, rewardType, 0, amount, "钱包余额更新")
+	// 添加关联该货币流水的奖励流水记录
+	err := s.rewardFlowService.CreateCurrencyRewardFlow(tx, flow, 0)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -127,8 +149,15 @@ func (s *userWalletService) UpdateWalletBalance(userID uint, walletType models.W
 	return tx.Commit().Error
 }
 
-// UpdateWalletBalance2 更新钱包余额
+// UpdateWalletBalance2 更新钱包余额，版本冲突时自动重试
 func (s *userWalletService) UpdateWalletBalance2(userID uint, walletType models.WalletType, amount int64) error {
+	return withOptimisticRetry(func() error {
+		return s.updateWalletBalance2(userID, walletType, amount)
+	})
+}
+
+// updateWalletBalance2 在独立事务中更新一次钱包余额
+func (s *userWalletService) updateWalletBalance2(userID uint, walletType models.WalletType, amount int64) error {
 	tx := config.Database.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -136,29 +165,32 @@ func (s *userWalletService) UpdateWalletBalance2(userID uint, walletType models.
 		}
 	}()
 
-	// 更新余额
-	if err := tx.Where("user_id = ? AND type = ?", userID, walletType).Update("num", gorm.Expr("num + ?", amount)).Error; err != nil {
+	// 基于版本号更新余额，余额不允许为负数
+	var wallet models.UserWallet
+	if err := tx.Where("user_id = ? AND type = ?", userID, walletType).First(&wallet).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
-
-	// 添加流水代码
-	if err := s.UserCurrencyFlow.CreateUserCurrencyFlow(&models.UserCurrencyFlow{
-		UserID:   userID,
-		CostType: string(walletType),
-		Price:    amount,
-	}); err != nil {
+	if err := changeWalletBalance(tx, &wallet, amount, nil, "钱包余额更新"); err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// 添加奖励流水记录
-	rewardType := models.RewardTypeCoin
-	if walletType == models.Diamond {
-		rewardType = models.RewardTypeDiamond
+	// 添加流水代码（与余额变更处于同一事务）
+	flow := &models.UserCurrencyFlow{
+		UserID:      userID,
+		CostType:    string(walletType),
+		Price:       amount,
+		Description: "钱包余额更新",
+		RefType:     models.FlowRefAdminAdjust,
+	}
+	if err := tx.Create(flow).Error; err != nil {
+		tx.Rollback()
+		return err
 	}
 
-	err := s.rewardFlowService.CreateRewardFlow(tx, userID, rewardType, 0, amount, "钱包余额更新")
+	// 添加关联该货币流水的奖励流水记录
+	err := s.rewardFlowService.CreateCurrencyRewardFlow(tx, flow, 0)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -167,36 +199,37 @@ func (s *userWalletService) UpdateWalletBalance2(userID uint, walletType models.
 	return tx.Commit().Error
 }
 
-// UpdateWalletBalanceWithTx 使用事务更新钱包余额
+// UpdateWalletBalanceWithTx 使用事务更新钱包余额，增加的余额永不过期
+// 版本冲突时返回ErrConcurrentModification，由调用方决定是否重试整个事务
 func (s *userWalletService) UpdateWalletBalanceWithTx(tx *gorm.DB, userID uint, walletType models.WalletType, amount int64, description string) error {
-	var wallet models.UserWallet
-
-	tx = tx.Set("gorm:query_option", "FOR UPDATE")
+	return s.GrantWalletBalanceWithTx(tx, userID, walletType, amount, nil, description)
+}
 
+// GrantWalletBalanceWithTx 使用事务更新钱包余额，expiresAt不为空时增加的余额将在该时间过期
+func (s *userWalletService) GrantWalletBalanceWithTx(tx *gorm.DB, userID uint, walletType models.WalletType, amount int64, expiresAt *time.Time, description string) error {
+	var wallet models.UserWallet
 	if err := tx.Where("user_id = ? AND type = ?", userID, walletType).
 		First(&wallet).Error; err != nil {
 		return err
 	}
 
-	// 更新余额
-	wallet.Num += amount
+	// 基于版本号更新余额并维护货币桶
+	if err := changeWalletBalance(tx, &wallet, amount, expiresAt, description); err != nil {
+		return err
+	}
 
-	// 添加流水代码
-	if err := s.UserCurrencyFlow.CreateUserCurrencyFlow(&models.UserCurrencyFlow{
+	// 添加流水代码（与余额变更处于同一事务）
+	flow := &models.UserCurrencyFlow{
 		UserID:      userID,
 		CostType:    string(walletType),
 		Price:       amount,
 		Description: description,
-	}); err != nil {
-		return err
 	}
-
-	// 添加奖励流水记录
-	rewardType := models.RewardTypeCoin
-	if walletType == models.Diamond {
-		rewardType = models.RewardTypeDiamond
+	if err := tx.Create(flow).Error; err != nil {
+		return err
 	}
 
+	// 添加关联该货币流水的奖励流水记录
 	var itemID uint
 	if walletType == models.Diamond {
 		itemID = 0
@@ -205,13 +238,7 @@ func (s *userWalletService) UpdateWalletBalanceWithTx(tx *gorm.DB, userID uint,
 		itemID = 1
 	}
 
-	err := s.rewardFlowService.CreateRewardFlow(tx, userID, rewardType, itemID, amount, description)
-	if err != nil {
-		return err
-	}
-
-	// 保存更新
-	return tx.Save(&wallet).Error
+	return s.rewardFlowService.CreateCurrencyRewardFlow(tx, flow, itemID)
 }
 
 // GetUserWallets 获取用户所有钱包
@@ -228,6 +255,15 @@ func (s *userWalletService) GetUserWallets(userID uint) ([]models.UserWallet, er
 		return nil, result.Error
 	}
 
+	// 附加即将过期的余额
+	expiries, err := getWalletExpiries(config.Database, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range wallets {
+		wallets[i].Expiries = expiries[wallets[i].Type]
+	}
+
 	if len(wallets) > 0 {
 		utils.SetHashField(cacheKey, "wallets", wallets, time.Hour)
 	}
@@ -235,3 +271,99 @@ func (s *userWalletService) GetUserWallets(userID uint) ([]models.UserWallet, er
 	return wallets, nil
 
 }
+
+// ExpireWalletBuckets 扣除所有已过期货币桶的剩余余额，返回处理的货币桶数量
+func (s *userWalletService) ExpireWalletBuckets() (int, error) {
+	count := 0
+	var lastID uint
+	for {
+		var buckets []*models.WalletBucket
+		if err := config.Database.
+			Where("id > ? AND status = ? AND remaining > 0 AND expires_at <= ?", lastID, models.BucketStatusActive, time.Now()).
+			Order("id asc").Limit(100).
+			Find(&buckets).Error; err != nil {
+			return count, err
+		}
+		if len(buckets) == 0 {
+			break
+		}
+		lastID = buckets[len(buckets)-1].ID
+
+		for _, bucket := range buckets {
+			bucketID := bucket.ID
+			if err := withOptimisticRetry(func() error {
+				return s.expireBucket(bucketID)
+			}); err != nil {
+				log.Printf("货币桶%d过期处理失败: %v", bucketID, err)
+				continue
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// expireBucket 在独立事务中扣除单个过期货币桶的剩余余额，并记录过期流水
+func (s *userWalletService) expireBucket(bucketID uint) error {
+	tx := config.Database.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			SafeRollback(tx)
+		}
+	}()
+
+	var bucket models.WalletBucket
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&bucket, bucketID).Error; err != nil {
+		SafeRollback(tx)
+		return err
+	}
+	if bucket.Status != models.BucketStatusActive || bucket.Remaining <= 0 {
+		SafeRollback(tx)
+		return nil
+	}
+
+	var wallet models.UserWallet
+	if err := tx.Where("user_id = ? AND type = ?", bucket.UserID, bucket.Type).First(&wallet).Error; err != nil {
+		SafeRollback(tx)
+		return err
+	}
+
+	// 钱包余额与货币桶不一致时最多扣至0，差异交由对账处理
+	amount := bucket.Remaining
+	if amount > wallet.Num {
+		amount = wallet.Num
+	}
+	if amount > 0 {
+		if err := updateWalletWithVersion(tx, &wallet, -amount); err != nil {
+			SafeRollback(tx)
+			return err
+		}
+		if err := tx.Create(&models.UserCurrencyFlow{
+			UserID:      bucket.UserID,
+			CostType:    string(bucket.Type),
+			Price:       -amount,
+			Description: fmt.Sprintf("货币过期，来源：%s", bucket.Source),
+			RefType:     models.FlowRefExpired,
+			RefID:       bucket.ID,
+		}).Error; err != nil {
+			SafeRollback(tx)
+			return err
+		}
+	}
+
+	if err := tx.Model(&bucket).Updates(map[string]interface{}{
+		"remaining": 0,
+		"status":    models.BucketStatusExpired,
+	}).Error; err != nil {
+		SafeRollback(tx)
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	clearWalletCache(bucket.UserID)
+	return nil
+}
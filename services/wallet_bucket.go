@@ -0,0 +1,244 @@
+package services
+
+import (
+	"goDDD1/models"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// legacyBucketSource 历史余额补建货币桶时使用的来源描述
+const legacyBucketSource = "历史余额"
+
+// changeWalletBalance 变更钱包余额并同步维护货币桶：
+// 增加余额时生成一个新桶，expiresAt为nil表示永不过期；
+// 扣减余额时按过期时间从早到晚消耗货币桶，永不过期的桶最后消耗。
+func changeWalletBalance(tx *gorm.DB, wallet *models.UserWallet, amount int64, expiresAt *time.Time, source string) error {
+	if amount == 0 {
+		return nil
+	}
+
+	if amount > 0 {
+		if err := updateWalletWithVersion(tx, wallet, amount); err != nil {
+			return err
+		}
+		return tx.Create(&models.WalletBucket{
+			UserID:    wallet.UserID,
+			Type:      wallet.Type,
+			Amount:    amount,
+			Remaining: amount,
+			Source:    source,
+			Status:    models.BucketStatusActive,
+			GrantedAt: time.Now(),
+			ExpiresAt: expiresAt,
+		}).Error
+	}
+
+	_, err := debitWalletBuckets(tx, wallet, -amount)
+	return err
+}
+
+// bucketPortion 从货币桶中扣减出的一部分余额及其过期时间
+type bucketPortion struct {
+	Amount    int64
+	ExpiresAt *time.Time
+}
+
+// debitWalletBuckets 扣减钱包余额，返回按过期时间从早到晚消耗的各部分余额
+func debitWalletBuckets(tx *gorm.DB, wallet *models.UserWallet, amount int64) ([]bucketPortion, error) {
+	// 扣减前先为没有对应货币桶的历史余额补建永久桶
+	if err := syncLegacyBucket(tx, wallet); err != nil {
+		return nil, err
+	}
+	if err := updateWalletWithVersion(tx, wallet, -amount); err != nil {
+		return nil, err
+	}
+	return consumeBuckets(tx, wallet, amount)
+}
+
+// debitOrderPayment 扣减订单的实付金额，并记录消耗的货币桶，退款时按记录恢复过期时间
+func debitOrderPayment(tx *gorm.DB, wallet *models.UserWallet, orderID uint, amount int64) error {
+	if amount == 0 {
+		return nil
+	}
+	portions, err := debitWalletBuckets(tx, wallet, amount)
+	if err != nil {
+		return err
+	}
+	for _, portion := range portions {
+		if err := tx.Create(&models.OrderBucketUsage{
+			OrderID:   orderID,
+			CostType:  string(wallet.Type),
+			Amount:    portion.Amount,
+			ExpiresAt: portion.ExpiresAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreOrderPayment 退还订单的部分实付金额，按消耗顺序的相反顺序恢复货币桶的过期时间，
+// 已过期的部分退还后由过期任务处理；没有消耗记录的早期订单按永不过期退还
+func restoreOrderPayment(tx *gorm.DB, wallet *models.UserWallet, orderID uint, amount int64, source string) error {
+	var usages []*models.OrderBucketUsage
+	if orderID != 0 {
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").
+			Where("order_id = ? AND cost_type = ? AND restored < amount", orderID, wallet.Type).
+			Order("id desc").
+			Find(&usages).Error; err != nil {
+			return err
+		}
+	}
+
+	portions := make([]bucketPortion, 0, len(usages)+1)
+	remaining := amount
+	for _, usage := range usages {
+		if remaining <= 0 {
+			break
+		}
+		restored := usage.Amount - usage.Restored
+		if restored > remaining {
+			restored = remaining
+		}
+		if err := tx.Model(usage).Update("restored", gorm.Expr("restored + ?", restored)).Error; err != nil {
+			return err
+		}
+		portions = append(portions, bucketPortion{Amount: restored, ExpiresAt: usage.ExpiresAt})
+		remaining -= restored
+	}
+	if remaining > 0 {
+		portions = append(portions, bucketPortion{Amount: remaining})
+	}
+	return creditWalletBuckets(tx, wallet, portions, source)
+}
+
+// creditWalletBuckets 增加钱包余额，每部分余额生成一个保留原过期时间的新桶
+func creditWalletBuckets(tx *gorm.DB, wallet *models.UserWallet, portions []bucketPortion, source string) error {
+	var total int64
+	for _, portion := range portions {
+		total += portion.Amount
+	}
+	if total == 0 {
+		return nil
+	}
+	if err := updateWalletWithVersion(tx, wallet, total); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, portion := range portions {
+		if err := tx.Create(&models.WalletBucket{
+			UserID:    wallet.UserID,
+			Type:      wallet.Type,
+			Amount:    portion.Amount,
+			Remaining: portion.Amount,
+			Source:    source,
+			Status:    models.BucketStatusActive,
+			GrantedAt: now,
+			ExpiresAt: portion.ExpiresAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncLegacyBucket 钱包余额大于货币桶剩余总额时，将差额补建为一个永不过期的桶
+func syncLegacyBucket(tx *gorm.DB, wallet *models.UserWallet) error {
+	var result struct {
+		Total int64
+	}
+	if err := tx.Model(&models.WalletBucket{}).
+		Select("COALESCE(SUM(remaining), 0) AS total").
+		Where("user_id = ? AND type = ? AND status = ?", wallet.UserID, wallet.Type, models.BucketStatusActive).
+		Scan(&result).Error; err != nil {
+		return err
+	}
+
+	missing := wallet.Num - result.Total
+	if missing <= 0 {
+		return nil
+	}
+
+	return tx.Create(&models.WalletBucket{
+		UserID:    wallet.UserID,
+		Type:      wallet.Type,
+		Amount:    missing,
+		Remaining: missing,
+		Source:    legacyBucketSource,
+		Status:    models.BucketStatusActive,
+		GrantedAt: wallet.CreatedAt,
+	}).Error
+}
+
+// consumeBuckets 按过期时间从早到晚消耗货币桶中的余额，过期时间相同的部分合并返回
+func consumeBuckets(tx *gorm.DB, wallet *models.UserWallet, amount int64) ([]bucketPortion, error) {
+	var buckets []*models.WalletBucket
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("user_id = ? AND type = ? AND status = ? AND remaining > 0", wallet.UserID, wallet.Type, models.BucketStatusActive).
+		Order("expires_at IS NULL, expires_at asc, id asc").
+		Find(&buckets).Error; err != nil {
+		return nil, err
+	}
+
+	var portions []bucketPortion
+	for _, bucket := range buckets {
+		if amount <= 0 {
+			break
+		}
+
+		used := bucket.Remaining
+		if used > amount {
+			used = amount
+		}
+		bucket.Remaining -= used
+		amount -= used
+		if last := len(portions) - 1; last >= 0 && sameExpiry(portions[last].ExpiresAt, bucket.ExpiresAt) {
+			portions[last].Amount += used
+		} else {
+			portions = append(portions, bucketPortion{Amount: used, ExpiresAt: bucket.ExpiresAt})
+		}
+
+		updates := map[string]interface{}{"remaining": bucket.Remaining}
+		if bucket.Remaining == 0 {
+			updates["status"] = models.BucketStatusExhausted
+		}
+		if err := tx.Model(bucket).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// 货币桶不足以覆盖时剩余部分视为永不过期
+	if amount > 0 {
+		portions = append(portions, bucketPortion{Amount: amount})
+	}
+	return portions, nil
+}
+
+// sameExpiry 判断两个过期时间是否相同，nil表示永不过期
+func sameExpiry(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// getWalletExpiries 查询用户各钱包即将过期的余额
+func getWalletExpiries(db *gorm.DB, userID uint) (map[models.WalletType][]models.WalletExpiry, error) {
+	var buckets []*models.WalletBucket
+	if err := db.Where("user_id = ? AND status = ? AND remaining > 0 AND expires_at > ?", userID, models.BucketStatusActive, time.Now()).
+		Order("expires_at asc").
+		Find(&buckets).Error; err != nil {
+		return nil, err
+	}
+
+	expiries := make(map[models.WalletType][]models.WalletExpiry)
+	for _, bucket := range buckets {
+		expiries[bucket.Type] = append(expiries[bucket.Type], models.WalletExpiry{
+			Amount:    bucket.Remaining,
+			ExpiresAt: *bucket.ExpiresAt,
+		})
+	}
+	return expiries, nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goDDD1/config"
+	"goDDD1/models"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// wishlistMaxItems 每个玩家最多收藏的商品数量
+const wishlistMaxItems = 200
+
+// WishlistService 收藏服务接口
+type WishlistService interface {
+	AddItem(userID uint, storeID uint) ([]*models.WishlistEntry, error)
+	RemoveItem(userID uint, storeID uint) ([]*models.WishlistEntry, error)
+	// 获取收藏列表，价格和库存以当前商品数据为准
+	GetWishlist(userID uint) ([]*models.WishlistEntry, error)
+}
+
+type wishlistService struct{}
+
+// NewWishlistService 创建收藏服务实例
+func NewWishlistService() WishlistService {
+	return &wishlistService{}
+}
+
+// AddItem 收藏商品，已收藏时不重复添加
+func (s *wishlistService) AddItem(userID uint, storeID uint) ([]*models.WishlistEntry, error) {
+	var store models.Store
+	if err := config.Database.Where("id = ? AND status = 1", storeID).First(&store).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, errors.New("商品不存在或已下架")
+		}
+		return nil, err
+	}
+
+	var count int
+	if err := config.Database.Model(&models.WishlistItem{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count >= wishlistMaxItems {
+		return nil, fmt.Errorf("最多收藏%d个商品", wishlistMaxItems)
+	}
+
+	item := models.WishlistItem{UserID: userID, StoreID: storeID, PriceAtAdd: store.CurrentPrice(time.Now())}
+	if err := config.Database.Create(&item).Error; err != nil && !isDuplicateKeyError(err) {
+		return nil, err
+	}
+	return s.GetWishlist(userID)
+}
+
+// RemoveItem 取消收藏
+func (s *wishlistService) RemoveItem(userID uint, storeID uint) ([]*models.WishlistEntry, error) {
+	if err := config.Database.Where("user_id = ? AND store_id = ?", userID, storeID).Delete(models.WishlistItem{}).Error; err != nil {
+		return nil, err
+	}
+	return s.GetWishlist(userID)
+}
+
+// GetWishlist 获取收藏列表，按收藏时间倒序
+func (s *wishlistService) GetWishlist(userID uint) ([]*models.WishlistEntry, error) {
+	var items []*models.WishlistItem
+	if err := config.Database.Where("user_id = ?", userID).Order("id desc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	entries := make([]*models.WishlistEntry, 0, len(items))
+	if len(items) == 0 {
+		return entries, nil
+	}
+
+	storeIDs := make([]uint, len(items))
+	for i, item := range items {
+		storeIDs[i] = item.StoreID
+	}
+	var stores []*models.Store
+	if err := config.Database.Where("id IN (?)", storeIDs).Find(&stores).Error; err != nil {
+		return nil, err
+	}
+	storeMap := make(map[uint]*models.Store, len(stores))
+	for _, store := range stores {
+		storeMap[store.ID] = store
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		// 已删除的商品不再展示
+		store, ok := storeMap[item.StoreID]
+		if !ok {
+			continue
+		}
+		entries = append(entries, &models.WishlistEntry{
+			StoreID:      store.ID,
+			Name:         store.Name,
+			CostType:     store.CostType,
+			Price:        store.Price,
+			CurrentPrice: store.CurrentPrice(now),
+			PriceAtAdd:   item.PriceAtAdd,
+			Stock:        store.Stock,
+			Available:    store.Status == 1 && store.IsAvailable(now),
+			AddedAt:      item.CreatedAt,
+		})
+	}
+	return entries, nil
+}
+
+// notifyWishlist 商品更新时比较更新前后的售价和库存，为收藏了该商品的玩家生成降价或补货通知
+func notifyWishlist(tx *gorm.DB, before *models.Store, after *models.Store, now time.Time) error {
+	if oldPrice, newPrice := before.CurrentPrice(now), after.CurrentPrice(now); newPrice < oldPrice {
+		content := fmt.Sprintf("您收藏的商品%s降价了，当前售价%d %s，原售价%d %s", after.Name, newPrice, after.CostType, oldPrice, after.CostType)
+		if err := createWishlistNotifications(tx, after.ID, models.NotificationPriceDrop, "收藏商品降价", content, now); err != nil {
+			return err
+		}
+	}
+	if before.Stock <= 0 && after.Stock > 0 {
+		content := fmt.Sprintf("您收藏的商品%s已补货，当前库存%d", after.Name, after.Stock)
+		if err := createWishlistNotifications(tx, after.ID, models.NotificationBackInStock, "收藏商品补货", content, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createWishlistNotifications 为收藏了商品的所有玩家批量写入通知
+func createWishlistNotifications(tx *gorm.DB, storeID uint, notificationType models.NotificationType, title string, content string, now time.Time) error {
+	return tx.Exec("INSERT INTO notifications (user_id, type, title, content, store_id, is_read, created_at) "+
+		"SELECT user_id, ?, ?, ?, store_id, false, ? FROM wishlist_items WHERE store_id = ?",
+		notificationType, title, content, now, storeID).Error
+}
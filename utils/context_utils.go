@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCurrentUID 从上下文中获取JWT中间件写入的当前用户UID
+func GetCurrentUID(ctx *gin.Context) (uint, error) {
+	value, exists := ctx.Get("uid")
+	if !exists {
+		return 0, errors.New("用户未登录")
+	}
+
+	uid, ok := value.(uint)
+	if !ok || uid == 0 {
+		return 0, errors.New("无效的用户信息")
+	}
+
+	return uid, nil
+}
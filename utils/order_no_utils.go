@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// GenerateOrderNo 生成订单号：前缀 + 时间戳(精确到秒) + 6位随机数
+func GenerateOrderNo(prefix string) string {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		n = big.NewInt(time.Now().UnixNano() % 1000000)
+	}
+	return fmt.Sprintf("%s%s%06d", prefix, time.Now().Format("20060102150405"), n.Int64())
+}
+
+// couponCodeChars 兑换码字符集，去掉了容易混淆的0、O、1、I
+const couponCodeChars = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// GenerateCouponCode 生成指定长度的随机兑换码
+func GenerateCouponCode(length int) string {
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(couponCodeChars)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			n = big.NewInt(time.Now().UnixNano() % int64(len(couponCodeChars)))
+		}
+		code[i] = couponCodeChars[n.Int64()]
+	}
+	return string(code)
+}
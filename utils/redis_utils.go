@@ -3,8 +3,11 @@ package utils
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"goDDD1/config"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
 // SetCache 设置缓存
@@ -123,3 +126,158 @@ func DelHashField(key string, field string) error {
 	rdb := config.GetRedisClient()
 	return rdb.HDel(ctx, key, field).Err()
 }
+
+// TryLock 尝试获取一个带过期时间的分布式锁，获取成功返回true
+func TryLock(key string, expiration time.Duration) (bool, error) {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+	return rdb.SetNX(ctx, key, 1, expiration).Result()
+}
+
+// Unlock 释放分布式锁
+func Unlock(key string) error {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+	return rdb.Del(ctx, key).Err()
+}
+
+// incrByIfExistsScript 仅在key存在时累加，避免在计数器未初始化时写入不完整的值
+var incrByIfExistsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return redis.call('INCRBY', KEYS[1], ARGV[1])
+end
+return nil
+`)
+
+// IncrByIfExists 当key存在时累加计数，key不存在时不做任何操作
+func IncrByIfExists(key string, value int64) error {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+	err := incrByIfExistsScript.Run(ctx, rdb, []string{key}, value).Err()
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
+
+// incrByWithCapScript 计数器累加后不超过上限时才累加，返回{是否累加, 累加后或当前的值}；key不存在时返回nil
+var incrByWithCapScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return nil
+end
+current = tonumber(current)
+if current + tonumber(ARGV[1]) > tonumber(ARGV[2]) then
+	return {0, current}
+end
+return {1, redis.call('INCRBY', KEYS[1], ARGV[1])}
+`)
+
+// IncrByWithCap 原子地检查并累加计数器，累加后超过上限时不累加并返回false和当前值；key不存在时返回redis.Nil
+func IncrByWithCap(key string, value int64, limit int64) (bool, int64, error) {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+	result, err := incrByWithCapScript.Run(ctx, rdb, []string{key}, value, limit).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("计数器%s返回值格式错误", key)
+	}
+	applied, _ := values[0].(int64)
+	current, _ := values[1].(int64)
+	return applied == 1, current, nil
+}
+
+// GetCounter 获取计数器的值，key不存在时返回redis.Nil
+func GetCounter(key string) (int64, error) {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+	return rdb.Get(ctx, key).Int64()
+}
+
+// InitCounter 在key不存在时初始化计数器
+func InitCounter(key string, value int64, expiration time.Duration) error {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+	return rdb.SetNX(ctx, key, value, expiration).Err()
+}
+
+// GetHashAll 获取 Redis 哈希表中的全部字段，值保持原始字符串
+func GetHashAll(key string) (map[string]string, error) {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+	return rdb.HGetAll(ctx, key).Result()
+}
+
+// IncrHashField 累加哈希字段的整数值，并刷新整个哈希 key 的过期时间
+func IncrHashField(key string, field string, value int64, expiration time.Duration) (int64, error) {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+
+	result, err := rdb.HIncrBy(ctx, key, field, value).Result()
+	if err != nil {
+		return 0, err
+	}
+	if expiration > 0 {
+		if err := rdb.Expire(ctx, key, expiration).Err(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// PushQueue 将数据序列化为JSON后放入队列
+func PushQueue(key string, value interface{}) error {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return rdb.LPush(ctx, key, jsonValue).Err()
+}
+
+// PopQueueTo 阻塞地从队列中取出一条数据，同时原子地放入处理中队列，超时未取到时返回false。
+// 处理完成后需要用返回的原始数据调用AckQueue，进程崩溃时数据仍保留在处理中队列
+func PopQueueTo(key string, processingKey string, timeout time.Duration, dest interface{}) (string, bool, error) {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+
+	result, err := rdb.BRPopLPush(ctx, key, processingKey, timeout).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return result, true, json.Unmarshal([]byte(result), dest)
+}
+
+// AckQueue 从处理中队列移除已处理完成的数据
+func AckQueue(processingKey string, raw string) error {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+
+	return rdb.LRem(ctx, processingKey, 1, raw).Err()
+}
+
+// RestoreQueue 将处理中队列里未确认的数据全部放回队列，返回放回的数量
+func RestoreQueue(processingKey string, key string) (int, error) {
+	ctx := context.Background()
+	rdb := config.GetRedisClient()
+
+	count := 0
+	for {
+		err := rdb.RPopLPush(ctx, processingKey, key).Err()
+		if err == redis.Nil {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+}
@@ -8,9 +8,19 @@ import (
 
 // 响应码常量
 const (
-	CodeSuccess     = "20000" // 成功
-	CodeClientError = "40000" // 客户端错误
-	CodeServerError = "50000" // 服务器错误
+	CodeSuccess                = "20000" // 成功
+	CodeClientError            = "40000" // 客户端错误
+	CodeInsufficientFunds      = "40001" // 余额不足
+	CodeInsufficientStock      = "40002" // 库存不足
+	CodeItemsConsumed          = "40003" // 物品已被使用
+	CodeSpendLimitExceeded     = "40004" // 超出消费限额
+	CodePurchaseLimitReached   = "40005" // 达到购买数量上限
+	CodeCouponNotApplicable    = "40006" // 优惠券不可用
+	CodeLevelRequired          = "40007" // 等级不足
+	CodeVipRequired            = "40008" // VIP等级不足
+	CodePrerequisiteRequired   = "40009" // 未拥有前置商品
+	CodeConcurrentModification = "40900" // 并发修改冲突
+	CodeServerError            = "50000" // 服务器错误
 )
 
 // Response 统一响应结构体
@@ -72,6 +82,18 @@ func (r *ResponseUtil) ResponseServerError(ctx *gin.Context, err error) {
 	})
 }
 
+// ResponseCodeError 指定错误码的错误响应
+// code: 错误码
+// message: 错误消息
+func (r *ResponseUtil) ResponseCodeError(ctx *gin.Context, code string, message string) {
+	ctx.JSON(http.StatusOK, Response{
+		Code: code,
+		Data: gin.H{
+			"message": message,
+		},
+	})
+}
+
 // 全局响应工具实例
 var ResponseUtilInstance = NewResponseUtil()
 
@@ -87,3 +109,7 @@ func ResClientError(ctx *gin.Context, message string) {
 func ResServerError(ctx *gin.Context, err error) {
 	ResponseUtilInstance.ResponseServerError(ctx, err)
 }
+
+func ResCodeError(ctx *gin.Context, code string, message string) {
+	ResponseUtilInstance.ResponseCodeError(ctx, code, message)
+}